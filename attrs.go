@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+func attrsCommand(args []string) error {
+	if len(args) > 0 && args[0] == "get" {
+		return attrsGetCommand(args[1:])
+	}
+	if len(args) > 0 && args[0] == "set" {
+		return attrsSetCommand(args[1:])
+	}
+
+	return fmt.Errorf("Usage: %s attrs get|set [flags] <queue> ...", os.Args[0])
+}
+
+func attrsGetCommand(args []string) error {
+	fs := flag.NewFlagSet("attrs get", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s attrs get [flags] <queue> [Name...]\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Without names every attribute is shown.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) < 1 {
+		return usageError(fs, "Need a queue.")
+	}
+
+	s, err := cf.queue(args[0])
+	if err != nil {
+		return err
+	}
+	s.ReadOnly = true
+
+	qar, err := s.GetQueueAttributes(args[1:]...)
+	if err != nil {
+		return err
+	}
+
+	if *cf.json {
+		return printJSON(qar.Attributes.Raw)
+	}
+
+	names := make([]string, 0, len(qar.Attributes.Raw))
+	for name := range qar.Attributes.Raw {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", name, qar.Attributes.Raw[name])
+	}
+
+	return nil
+}
+
+func attrsSetCommand(args []string) error {
+	fs := flag.NewFlagSet("attrs set", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	diff := fs.Bool("diff", false, "Show current and proposed values before applying")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s attrs set [flags] <queue> Name=Value...\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "A value of @file is read from file, e.g. RedrivePolicy=@policy.json.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) < 2 {
+		return usageError(fs, "Need a queue and at least one attribute.")
+	}
+
+	attrs, err := parseAttributeArgs(args[1:])
+	if err != nil {
+		return err
+	}
+	if err = sqs.ValidateQueueAttributes(attrs); err != nil {
+		return err
+	}
+
+	s, err := cf.queue(args[0])
+	if err != nil {
+		return err
+	}
+
+	if *diff {
+		names := make([]string, 0, len(attrs))
+		for name := range attrs {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		qar, err := s.GetQueueAttributes(names...)
+		if err != nil {
+			return err
+		}
+		printAttributeDiff(names, qar.Attributes.Raw, attrs)
+	}
+
+	if _, err = s.SetQueueAttributes(attrs); err != nil {
+		return err
+	}
+	fmt.Printf("Updated %d attribute(s) of %s.\n", len(attrs), s.QueueName)
+
+	return nil
+}
+
+// parseAttributeArgs turns Name=Value arguments into an attribute map,
+// reading values of the form @file from the named file.
+func parseAttributeArgs(args []string) (map[string]string, error) {
+	attrs := make(map[string]string, len(args))
+
+	for _, arg := range args {
+		i := strings.Index(arg, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("Invalid attribute %q, expected Name=Value.", arg)
+		}
+		name, value := arg[:i], arg[i+1:]
+
+		if strings.HasPrefix(value, "@") {
+			data, err := os.ReadFile(value[1:])
+			if err != nil {
+				return nil, err
+			}
+			value = strings.TrimSpace(string(data))
+		}
+
+		if _, ok := attrs[name]; ok {
+			return nil, fmt.Errorf("Attribute %s given more than once.", name)
+		}
+		attrs[name] = value
+	}
+
+	return attrs, nil
+}
+
+func printAttributeDiff(names []string, current, proposed map[string]string) {
+	for _, name := range names {
+		old, ok := current[name]
+		switch {
+		case !ok:
+			fmt.Printf("+ %s: %s\n", name, proposed[name])
+		case old == proposed[name]:
+			fmt.Printf("  %s: %s (unchanged)\n", name, old)
+		default:
+			fmt.Printf("- %s: %s\n+ %s: %s\n", name, old, name, proposed[name])
+		}
+	}
+}
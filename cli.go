@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+// commands are the subcommands, selected by the first argument.
+var commands = map[string]func(args []string) error{
+	"attrs":        attrsCommand,
+	"create-queue": createQueueCommand,
+	"delete":       deleteCommand,
+	"delete-queue": deleteQueueCommand,
+	"list":         listCommand,
+	"peek":         peekCommand,
+	"purge":        purgeCommand,
+	"receive":      receiveCommand,
+	"send":         sendCommand,
+	"stats":        statsCommand,
+}
+
+// clientFlags are the credential, location and output flags shared by
+// every subcommand.
+type clientFlags struct {
+	accessKey *string
+	secret    *string
+	region    *string
+	uuid      *string
+	profile   *string
+	json      *bool
+}
+
+func addClientFlags(fs *flag.FlagSet) *clientFlags {
+	return &clientFlags{
+		accessKey: fs.String("accesskey", "", "AWS Access Key, or $AWS_ACCESS_KEY_ID"),
+		secret:    fs.String("secret", "", "AWS Secret Key, or $AWS_SECRET_ACCESS_KEY"),
+		region:    fs.String("region", "", "AWS Region ID, or $AWS_REGION"),
+		uuid:      fs.String("uuid", "", "AWS Unique ID; queue names are looked up without it"),
+		profile:   fs.String("profile", "", "Profile in the shared AWS credentials files, or $AWS_PROFILE"),
+		json:      fs.Bool("json", false, "Print results as JSON"),
+	}
+}
+
+// client returns a client configured from the flags, falling back to the
+// environment and then to the profile for settings the flags leave out.
+// needRegion is false for commands that only address queues by URL.
+func (cf *clientFlags) client(needRegion bool) (*sqs.Client, error) {
+	accessKey := firstSet(*cf.accessKey, os.Getenv("AWS_ACCESS_KEY_ID"))
+	secret := firstSet(*cf.secret, os.Getenv("AWS_SECRET_ACCESS_KEY"))
+	region := firstSet(*cf.region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"))
+
+	if accessKey == "" || secret == "" || region == "" {
+		p, err := loadProfile(firstSet(*cf.profile, os.Getenv("AWS_PROFILE"), "default"))
+		if err != nil {
+			return nil, err
+		}
+
+		// Keys only come as a pair, so a profile never completes a key
+		// given elsewhere.
+		if accessKey == "" && secret == "" {
+			accessKey, secret = p.accessKey, p.secret
+		}
+		region = firstSet(region, p.region)
+	}
+
+	errs := make(Errors, 0)
+	if accessKey == "" {
+		errs = append(errs, errors.New("AWS Access Key needs to be set."))
+	}
+	if secret == "" {
+		errs = append(errs, errors.New("AWS Secret Key needs to be set."))
+	}
+	if needRegion && region == "" {
+		errs = append(errs, errors.New("AWS Region ID needs to be set."))
+	}
+
+	if errs.hasErrors() {
+		errs.printErrors(os.Stderr)
+		return nil, errors.New("Missing credentials or settings.")
+	}
+
+	return sqs.NewClient(region, *cf.uuid, accessKey, secret), nil
+}
+
+// queue returns a client for the queue given by name or by URL. A name
+// needs a region; a URL carries it.
+func (cf *clientFlags) queue(nameOrURL string) (*sqs.Queue, error) {
+	c, err := cf.client(!isQueueURL(nameOrURL))
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Queue(nameOrURL)
+}
+
+func isQueueURL(s string) bool {
+	return strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")
+}
+
+func firstSet(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+// printJSON writes v to standard output as indented JSON.
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(v)
+}
+
+// parseArgs parses args with fs, allowing flags to follow positional
+// arguments as in "create-queue orders -fifo", and returns the positional
+// arguments.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// usageError reports a malformed command line after printing the
+// command's usage.
+func usageError(fs *flag.FlagSet, format string, args ...interface{}) error {
+	fs.Usage()
+	return fmt.Errorf(format, args...)
+}
+
+func runCommand(name string, args []string) {
+	if err := commands[name](args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags] [arguments]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  "+name)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun %s <command> -h for the flags of a command.\n", os.Args[0])
+}
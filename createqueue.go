@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+func createQueueCommand(args []string) error {
+	fs := flag.NewFlagSet("create-queue", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	fifo := fs.Bool("fifo", false, "Create a FIFO queue; .fifo is appended to the names")
+	visibility := fs.Duration("visibility", 0, "Visibility timeout, e.g. 60s")
+	dlqName := fs.String("dlq", "", "Dead-letter queue to create, or reuse, and redrive to")
+	maxReceive := fs.Int("max-receive", 5, "Receives before a message moves to the dead-letter queue")
+	kms := fs.String("kms", "", "KMS key id or alias for server-side encryption")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s create-queue [flags] <name>\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) != 1 {
+		return usageError(fs, "Need exactly one queue name.")
+	}
+	if *visibility%time.Second != 0 {
+		return fmt.Errorf("Visibility timeout must be a whole number of seconds, got %s.", *visibility)
+	}
+
+	opts := &sqs.CreateQueueOptions{
+		FifoQueue:      *fifo,
+		KmsMasterKeyId: *kms,
+		Attributes:     make(map[string]string),
+	}
+	if *visibility > 0 {
+		opts.Attributes[sqs.AttrVisibilityTimeout] = strconv.Itoa(int(*visibility / time.Second))
+	}
+	c, err := cf.client(true)
+	if err != nil {
+		return err
+	}
+	// An empty convention only adds the .fifo suffix where it is missing.
+	c.Defaults.Naming = &sqs.QueueNaming{}
+
+	var result struct {
+		QueueUrl           string
+		DeadLetterQueueUrl string `json:",omitempty"`
+	}
+
+	if *dlqName != "" {
+		dlq := c.Defaults
+		dlq.QueueName = *dlqName
+
+		qur, err := dlq.EnsureQueue(&sqs.CreateQueueOptions{FifoQueue: *fifo, KmsMasterKeyId: *kms})
+		if err != nil {
+			return err
+		}
+		result.DeadLetterQueueUrl = qur.QueueURL
+
+		s, err := c.Queue(qur.QueueURL)
+		if err != nil {
+			return err
+		}
+		arn, err := s.QueueARN()
+		if err != nil {
+			return err
+		}
+		opts.Attributes[sqs.AttrRedrivePolicy] = sqs.RedrivePolicy{
+			DeadLetterTargetArn: arn,
+			MaxReceiveCount:     *maxReceive,
+		}.String()
+	}
+
+	s, err := c.CreateQueue(args[0], opts)
+	if err != nil {
+		return err
+	}
+	result.QueueUrl = s.ExplicitQueueURL
+
+	if *cf.json {
+		return printJSON(result)
+	}
+
+	if result.DeadLetterQueueUrl != "" {
+		fmt.Println("Dead-letter queue:", result.DeadLetterQueueUrl)
+	}
+	fmt.Println("Queue:", result.QueueUrl)
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// profile holds the settings of a named profile in the shared AWS
+// credentials and config files.
+type profile struct {
+	accessKey string
+	secret    string
+	region    string
+}
+
+// loadProfile reads the named profile from ~/.aws/credentials and
+// ~/.aws/config, or from the files named by AWS_SHARED_CREDENTIALS_FILE
+// and AWS_CONFIG_FILE. Missing files and profiles leave the settings
+// empty.
+func loadProfile(name string) (*profile, error) {
+	p := new(profile)
+
+	home, _ := os.UserHomeDir()
+
+	credsPath := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credsPath == "" {
+		credsPath = filepath.Join(home, ".aws", "credentials")
+	}
+	creds, err := readINISection(credsPath, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// The config file prefixes every profile but the default one.
+	section := name
+	if name != "default" {
+		section = "profile " + name
+	}
+
+	configPath := os.Getenv("AWS_CONFIG_FILE")
+	if configPath == "" {
+		configPath = filepath.Join(home, ".aws", "config")
+	}
+	config, err := readINISection(configPath, section)
+	if err != nil {
+		return nil, err
+	}
+
+	p.accessKey = creds["aws_access_key_id"]
+	p.secret = creds["aws_secret_access_key"]
+	p.region = config["region"]
+
+	return p, nil
+}
+
+// readINISection returns the keys of one section of an INI file, or none
+// if the file does not exist.
+func readINISection(path, section string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	inSection := false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			inSection = strings.TrimSpace(line[1:len(line)-1]) == section
+			continue
+		}
+
+		if i := strings.Index(line, "="); inSection && i > 0 {
+			values[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+
+	return values, scanner.Err()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+func deleteCommand(args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	handle := fs.String("handle", "", "Receipt handle of the message to delete")
+	handlesFile := fs.String("handles-file", "", "File with one receipt handle per line")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s delete [flags] <queue>\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Blank lines and lines starting with # in the handles file are skipped.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) != 1 {
+		return usageError(fs, "Need exactly one queue.")
+	}
+	if (*handle == "") == (*handlesFile == "") {
+		return usageError(fs, "Need either -handle or -handles-file.")
+	}
+
+	s, err := cf.queue(args[0])
+	if err != nil {
+		return err
+	}
+
+	if *handle != "" {
+		if _, err = s.DeleteSQSMessage(*handle); err != nil {
+			return err
+		}
+		fmt.Println("Deleted 1 message.")
+		return nil
+	}
+
+	handles, err := readHandles(*handlesFile)
+	if err != nil {
+		return err
+	}
+
+	err = s.DeleteAll(context.Background(), handles)
+
+	var be sqs.BatchError
+	if err != nil && !errors.As(err, &be) {
+		return err
+	}
+	for _, f := range be {
+		i, _ := strconv.Atoi(f.Id)
+		fmt.Fprintf(os.Stderr, "Failed to delete %s: %s (%s)\n", handles[i], f.Message, f.Code)
+	}
+
+	fmt.Printf("Deleted %d of %d messages.\n", len(handles)-len(be), len(handles))
+	if len(be) > 0 {
+		return fmt.Errorf("%d deletes failed.", len(be))
+	}
+
+	return nil
+}
+
+func readHandles(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var handles []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		handles = append(handles, line)
+	}
+
+	return handles, scanner.Err()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func deleteQueueCommand(args []string) error {
+	fs := flag.NewFlagSet("delete-queue", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s delete-queue [flags] <queue>\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "The queue and every message in it are deleted.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) != 1 {
+		return usageError(fs, "Need exactly one queue.")
+	}
+
+	s, err := cf.queue(args[0])
+	if err != nil {
+		return err
+	}
+
+	if _, err = s.DeleteQueue(); err != nil {
+		return err
+	}
+
+	if *cf.json {
+		return printJSON(struct{ Deleted string }{s.QueueName})
+	}
+	fmt.Println("Deleted queue", s.QueueName)
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func listCommand(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s list [flags] [prefix]\n\n", os.Args[0])
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) > 1 {
+		return usageError(fs, "Need at most one prefix.")
+	}
+
+	prefix := ""
+	if len(args) == 1 {
+		prefix = args[0]
+	}
+
+	c, err := cf.client(true)
+	if err != nil {
+		return err
+	}
+
+	urls, err := c.Defaults.ListAllQueues(prefix)
+	if err != nil {
+		return err
+	}
+
+	if *cf.json {
+		if urls == nil {
+			urls = []string{}
+		}
+		return printJSON(urls)
+	}
+
+	for _, u := range urls {
+		fmt.Println(u)
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+func peekCommand(args []string) error {
+	fs := flag.NewFlagSet("peek", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	n := fs.Int("n", 10, "Number of messages to show, at most 10")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s peek [flags] <queue>\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Messages stay visible to consumers; their receive counts do go up.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) != 1 {
+		return usageError(fs, "Need exactly one queue.")
+	}
+	if *n < 1 || *n > 10 {
+		return usageError(fs, "-n must be between 1 and 10, got %d.", *n)
+	}
+
+	s, err := cf.queue(args[0])
+	if err != nil {
+		return err
+	}
+	s.ReadOnly = true
+
+	msgs, err := s.Peek(*n)
+	if err != nil {
+		return err
+	}
+
+	return printMessages(msgs, *cf.json)
+}
+
+// messageJSON is the JSON form of a received message.
+type messageJSON struct {
+	MessageId         string
+	ReceiptHandle     string
+	SentTimestamp     time.Time
+	ReceiveCount      int
+	MessageAttributes map[string]string `json:",omitempty"`
+	Body              string
+}
+
+// printMessages prints received messages, as a JSON array if asJSON is
+// set.
+func printMessages(msgs []*sqs.RecvMessageResponse, asJSON bool) error {
+	if asJSON {
+		out := make([]messageJSON, len(msgs))
+		for i, m := range msgs {
+			out[i] = messageJSON{
+				MessageId:     m.MessageId,
+				ReceiptHandle: m.ReceiptHandle,
+				SentTimestamp: m.SentTimestamp(),
+				ReceiveCount:  m.ReceiveCount(),
+				Body:          m.MessageBody,
+			}
+			if len(m.MessageAttributes) > 0 {
+				out[i].MessageAttributes = make(map[string]string, len(m.MessageAttributes))
+				for _, attr := range m.MessageAttributes {
+					out[i].MessageAttributes[attr.Name] = attr.StringValue
+				}
+			}
+		}
+		return printJSON(out)
+	}
+
+	if len(msgs) == 0 {
+		fmt.Println("No messages available.")
+		return nil
+	}
+
+	for i, m := range msgs {
+		if i > 0 {
+			fmt.Println()
+		}
+		printMessage(m)
+	}
+
+	return nil
+}
+
+func printMessage(m *sqs.RecvMessageResponse) {
+	sent := m.SentTimestamp()
+
+	fmt.Println("Message", m.MessageId)
+	fmt.Printf("  Sent:       %s (%s ago)\n", sent.Format(time.RFC3339), time.Since(sent).Round(time.Second))
+	fmt.Printf("  Receives:   %d\n", m.ReceiveCount())
+	fmt.Printf("  Handle:     %s\n", m.ReceiptHandle)
+
+	if len(m.MessageAttributes) > 0 {
+		attrs := make([]string, 0, len(m.MessageAttributes))
+		for _, attr := range m.MessageAttributes {
+			attrs = append(attrs, fmt.Sprintf("    %s: %s", attr.Name, attr.StringValue))
+		}
+		sort.Strings(attrs)
+
+		fmt.Println("  Attributes:")
+		fmt.Println(strings.Join(attrs, "\n"))
+	}
+
+	fmt.Println("  Body:")
+	for _, line := range strings.Split(m.MessageBody, "\n") {
+		fmt.Println("    " + line)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func purgeCommand(args []string) error {
+	fs := flag.NewFlagSet("purge", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	wait := fs.Bool("wait", false, "Wait for the queue to report no messages, up to a minute")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s purge [flags] <queue>\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Every message in the queue is deleted; SQS allows one purge a minute.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) != 1 {
+		return usageError(fs, "Need exactly one queue.")
+	}
+
+	s, err := cf.queue(args[0])
+	if err != nil {
+		return err
+	}
+
+	if *wait {
+		_, err = s.PurgeQueueAndWait(context.Background())
+	} else {
+		_, err = s.PurgeQueue()
+	}
+	if err != nil {
+		return err
+	}
+
+	if *cf.json {
+		return printJSON(struct{ Purged string }{s.QueueName})
+	}
+	fmt.Println("Purged queue", s.QueueName)
+
+	return nil
+}
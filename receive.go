@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func receiveCommand(args []string) error {
+	fs := flag.NewFlagSet("receive", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	n := fs.Int("n", 1, "Number of messages to receive, at most 10")
+	wait := fs.Int("wait", 0, "Seconds to wait for messages to arrive, at most 20")
+	del := fs.Bool("delete", false, "Delete the messages once printed")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s receive [flags] <queue>\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Received messages stay hidden for the queue's visibility timeout unless deleted.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) != 1 {
+		return usageError(fs, "Need exactly one queue.")
+	}
+	if *n < 1 || *n > 10 {
+		return usageError(fs, "-n must be between 1 and 10, got %d.", *n)
+	}
+	if *wait < 0 || *wait > 20 {
+		return usageError(fs, "-wait must be between 0 and 20, got %d.", *wait)
+	}
+
+	s, err := cf.queue(args[0])
+	if err != nil {
+		return err
+	}
+
+	msgs, err := s.ReceiveSQSMessagesContext(context.Background(), *n, *wait)
+	if err != nil {
+		return err
+	}
+
+	if err = printMessages(msgs, *cf.json); err != nil {
+		return err
+	}
+
+	if !*del || len(msgs) == 0 {
+		return nil
+	}
+
+	handles := make([]string, len(msgs))
+	for i, m := range msgs {
+		handles[i] = m.ReceiptHandle
+	}
+	if err = s.DeleteAll(context.Background(), handles); err != nil {
+		return err
+	}
+	if !*cf.json {
+		fmt.Printf("\nDeleted %d message(s).\n", len(msgs))
+	}
+
+	return nil
+}
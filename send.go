@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+// attributeFlags collects repeated Name=Value flags.
+type attributeFlags map[string]string
+
+func (af attributeFlags) String() string {
+	return ""
+}
+
+func (af attributeFlags) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("Invalid attribute %q, expected Name=Value.", value)
+	}
+	af[value[:i]] = value[i+1:]
+
+	return nil
+}
+
+func sendCommand(args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	file := fs.String("file", "", "Read the body from this file; - reads standard input")
+	delay := fs.Int("delay", 0, "Delay delivery by this many seconds, up to 900")
+	group := fs.String("group", "", "Message group id, required on FIFO queues")
+	dedupId := fs.String("dedup-id", "", "Message deduplication id for FIFO queues")
+	attrs := make(attributeFlags)
+	fs.Var(attrs, "attr", "Message attribute as Name=Value; may be repeated")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s send [flags] <queue> [body]\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Without a body or -file the body is read from standard input.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) < 1 || len(args) > 2 {
+		return usageError(fs, "Need a queue and at most one body.")
+	}
+	if len(args) == 2 && *file != "" {
+		return usageError(fs, "Need either a body or -file, not both.")
+	}
+
+	var (
+		body []byte
+		err  error
+	)
+	switch {
+	case len(args) == 2:
+		body = []byte(args[1])
+	case *file != "" && *file != "-":
+		body, err = os.ReadFile(*file)
+	default:
+		body, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	s, err := cf.queue(args[0])
+	if err != nil {
+		return err
+	}
+
+	opts := &sqs.SendOptions{
+		DelaySeconds:           *delay,
+		MessageAttributes:      attrs,
+		MessageGroupId:         *group,
+		MessageDeduplicationId: *dedupId,
+	}
+
+	smr, err := s.SendSQSMessageContext(context.Background(), body, opts)
+	if err != nil {
+		return err
+	}
+
+	if *cf.json {
+		return printJSON(struct{ MessageId string }{smr.MessageId})
+	}
+	fmt.Println("Sent message", smr.MessageId)
+
+	return nil
+}
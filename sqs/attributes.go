@@ -0,0 +1,277 @@
+package sqs
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const (
+	AttrAll                                   = "All"
+	AttrApproximateNumberOfMessages           = "ApproximateNumberOfMessages"
+	AttrApproximateNumberOfMessagesNotVisible = "ApproximateNumberOfMessagesNotVisible"
+	AttrApproximateNumberOfMessagesDelayed    = "ApproximateNumberOfMessagesDelayed"
+	AttrCreatedTimestamp                      = "CreatedTimestamp"
+	AttrLastModifiedTimestamp                 = "LastModifiedTimestamp"
+	AttrDelaySeconds                          = "DelaySeconds"
+	AttrMaximumMessageSize                    = "MaximumMessageSize"
+	AttrMessageRetentionPeriod                = "MessageRetentionPeriod"
+	AttrPolicy                                = "Policy"
+	AttrQueueArn                              = "QueueArn"
+	AttrReceiveMessageWaitTimeSeconds         = "ReceiveMessageWaitTimeSeconds"
+	AttrRedrivePolicy                         = "RedrivePolicy"
+	AttrVisibilityTimeout                     = "VisibilityTimeout"
+	AttrFifoQueue                             = "FifoQueue"
+	AttrContentBasedDeduplication             = "ContentBasedDeduplication"
+	AttrKmsMasterKeyId                        = "KmsMasterKeyId"
+	AttrKmsDataKeyReusePeriodSeconds          = "KmsDataKeyReusePeriodSeconds"
+	AttrSqsManagedSseEnabled                  = "SqsManagedSseEnabled"
+)
+
+// QueueAttributes is the typed form of the attributes returned by
+// GetQueueAttributes. Durations are expressed in seconds, as SQS does.
+type QueueAttributes struct {
+	ApproximateNumberOfMessages           int
+	ApproximateNumberOfMessagesNotVisible int
+	ApproximateNumberOfMessagesDelayed    int
+	CreatedTimestamp                      time.Time
+	LastModifiedTimestamp                 time.Time
+	DelaySeconds                          int
+	MaximumMessageSize                    int
+	MessageRetentionPeriod                int
+	Policy                                string
+	QueueArn                              string
+	ReceiveMessageWaitTimeSeconds         int
+	RedrivePolicy                         *RedrivePolicy
+	VisibilityTimeout                     int
+	FifoQueue                             bool
+	ContentBasedDeduplication             bool
+	KmsMasterKeyId                        string
+	KmsDataKeyReusePeriodSeconds          int
+	SqsManagedSseEnabled                  bool
+
+	// Raw holds every attribute as returned by SQS, including any that
+	// have no typed field above.
+	Raw map[string]string
+}
+
+type QueueAttributesResponse struct {
+	Attributes QueueAttributes
+	BasicResponse
+}
+
+type Attribute struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+type getQueueAttributesResponse struct {
+	Attributes []Attribute `xml:"GetQueueAttributesResult>Attribute"`
+	BasicResponse
+}
+
+// ParseQueueAttributes converts raw attribute values, as SQS returns them,
+// to QueueAttributes.
+func ParseQueueAttributes(raw map[string]string) (QueueAttributes, error) {
+	qa := QueueAttributes{Raw: raw}
+
+	ints := map[string]*int{
+		AttrApproximateNumberOfMessages:           &qa.ApproximateNumberOfMessages,
+		AttrApproximateNumberOfMessagesNotVisible: &qa.ApproximateNumberOfMessagesNotVisible,
+		AttrApproximateNumberOfMessagesDelayed:    &qa.ApproximateNumberOfMessagesDelayed,
+		AttrDelaySeconds:                          &qa.DelaySeconds,
+		AttrMaximumMessageSize:                    &qa.MaximumMessageSize,
+		AttrMessageRetentionPeriod:                &qa.MessageRetentionPeriod,
+		AttrReceiveMessageWaitTimeSeconds:         &qa.ReceiveMessageWaitTimeSeconds,
+		AttrVisibilityTimeout:                     &qa.VisibilityTimeout,
+		AttrKmsDataKeyReusePeriodSeconds:          &qa.KmsDataKeyReusePeriodSeconds,
+	}
+	bools := map[string]*bool{
+		AttrFifoQueue:                 &qa.FifoQueue,
+		AttrContentBasedDeduplication: &qa.ContentBasedDeduplication,
+		AttrSqsManagedSseEnabled:      &qa.SqsManagedSseEnabled,
+	}
+	times := map[string]*time.Time{
+		AttrCreatedTimestamp:      &qa.CreatedTimestamp,
+		AttrLastModifiedTimestamp: &qa.LastModifiedTimestamp,
+	}
+
+	for name, value := range raw {
+		var err error
+
+		switch name {
+		case AttrPolicy:
+			qa.Policy = value
+		case AttrQueueArn:
+			qa.QueueArn = value
+		case AttrKmsMasterKeyId:
+			qa.KmsMasterKeyId = value
+		case AttrRedrivePolicy:
+			qa.RedrivePolicy = new(RedrivePolicy)
+			err = json.Unmarshal([]byte(value), qa.RedrivePolicy)
+		}
+
+		if p, ok := ints[name]; ok {
+			*p, err = strconv.Atoi(value)
+		}
+		if p, ok := bools[name]; ok {
+			*p, err = strconv.ParseBool(value)
+		}
+		if p, ok := times[name]; ok {
+			var sec int64
+			sec, err = strconv.ParseInt(value, 10, 64)
+			*p = time.Unix(sec, 0)
+		}
+
+		if err != nil {
+			return qa, fmt.Errorf("Invalid value %q for attribute %s: %s", value, name, err)
+		}
+	}
+
+	return qa, nil
+}
+
+// GetQueueAttributes fetches the named attributes of the queue, or all of
+// them when no names are given.
+func (s *SQSRequest) GetQueueAttributes(names ...string) (*QueueAttributesResponse, error) {
+	if len(names) == 0 {
+		names = []string{AttrAll}
+	}
+
+	params := map[string]string{
+		"Action": "GetQueueAttributes",
+	}
+
+	for i, name := range names {
+		params[fmt.Sprintf("AttributeName.%d", i+1)] = name
+	}
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	gqr := new(getQueueAttributesResponse)
+	if err = xml.NewDecoder(reader).Decode(gqr); err != nil {
+		return nil, err
+	}
+
+	raw := make(map[string]string, len(gqr.Attributes))
+	for _, attr := range gqr.Attributes {
+		raw[attr.Name] = attr.Value
+	}
+
+	qa, err := ParseQueueAttributes(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueueAttributesResponse{qa, gqr.BasicResponse}, nil
+}
+
+// SetQueueAttributes updates the given attributes of the queue. Attributes
+// not present in the map are left unchanged.
+func (s *SQSRequest) SetQueueAttributes(attributes map[string]string) (*BasicResponse, error) {
+	if err := ValidateQueueAttributes(attributes); err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"Action": "SetQueueAttributes",
+	}
+
+	count := 1
+	for name, value := range attributes {
+		params[fmt.Sprintf("Attribute.%d.Name", count)] = name
+		params[fmt.Sprintf("Attribute.%d.Value", count)] = value
+		count++
+	}
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	bmr := new(BasicResponse)
+	if err = xml.NewDecoder(reader).Decode(bmr); err != nil {
+		return nil, err
+	}
+
+	s.emitQueueEvent(QueueAttributesChanged, s.QueueName, s.generateSQSQueueURI(), attributes)
+
+	return bmr, nil
+}
+
+// attributeRanges are the values SQS accepts for its numeric queue
+// attributes.
+var attributeRanges = map[string][2]int{
+	AttrDelaySeconds:                  {0, maxDelaySeconds},
+	AttrMaximumMessageSize:            {1024, 262144},
+	AttrMessageRetentionPeriod:        {60, 1209600},
+	AttrReceiveMessageWaitTimeSeconds: {0, 20},
+	AttrVisibilityTimeout:             {0, maxVisibilityTimeout},
+	AttrKmsDataKeyReusePeriodSeconds:  {60, 86400},
+}
+
+// readOnlyAttributes are reported by GetQueueAttributes but cannot be set.
+var readOnlyAttributes = map[string]bool{
+	AttrApproximateNumberOfMessages:           true,
+	AttrApproximateNumberOfMessagesNotVisible: true,
+	AttrApproximateNumberOfMessagesDelayed:    true,
+	AttrCreatedTimestamp:                      true,
+	AttrLastModifiedTimestamp:                 true,
+	AttrQueueArn:                              true,
+}
+
+// ValidateQueueAttributes checks attribute values before they are sent to
+// CreateQueue or SetQueueAttributes, so that mistakes are reported with
+// the offending attribute rather than as a bare InvalidAttributeValue.
+// An empty Policy or RedrivePolicy removes the policy. Attributes unknown
+// to this package are passed through unchecked.
+func ValidateQueueAttributes(attributes map[string]string) error {
+	if attributes[AttrKmsMasterKeyId] != "" && attributes[AttrSqsManagedSseEnabled] == "true" {
+		return fmt.Errorf("Attributes %s and %s cannot both be set.", AttrKmsMasterKeyId, AttrSqsManagedSseEnabled)
+	}
+
+	for name, value := range attributes {
+		if readOnlyAttributes[name] {
+			return fmt.Errorf("Attribute %s is read-only.", name)
+		}
+
+		if r, ok := attributeRanges[name]; ok {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < r[0] || n > r[1] {
+				return fmt.Errorf("Attribute %s must be between %d and %d, got %q.", name, r[0], r[1], value)
+			}
+		}
+
+		if value == "" && (name == AttrPolicy || name == AttrRedrivePolicy) {
+			continue
+		}
+
+		switch name {
+		case AttrFifoQueue, AttrContentBasedDeduplication, AttrSqsManagedSseEnabled:
+			if _, err := strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("Attribute %s must be true or false, got %q.", name, value)
+			}
+		case AttrPolicy:
+			if !json.Valid([]byte(value)) {
+				return fmt.Errorf("Attribute %s is not a valid JSON document.", name)
+			}
+		case AttrRedrivePolicy:
+			var rp RedrivePolicy
+			if err := json.Unmarshal([]byte(value), &rp); err != nil {
+				return fmt.Errorf("Attribute %s is invalid: %s", name, err)
+			}
+			if err := rp.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package sqs
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes the processing of a single message.
+type AuditRecord struct {
+	Time         time.Time     `json:"time"`
+	MessageId    string        `json:"messageId"`
+	ReceiveCount int           `json:"receiveCount"`
+	Outcome      string        `json:"outcome"`
+	Error        string        `json:"error,omitempty"`
+	Duration     time.Duration `json:"duration"`
+	WorkerId     string        `json:"workerId,omitempty"`
+}
+
+// AuditSink receives an AuditRecord for every processed message.
+type AuditSink interface {
+	Record(rec AuditRecord) error
+}
+
+// WriterAuditSink appends records to an io.Writer as newline-delimited
+// JSON. It is safe for concurrent use.
+type WriterAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterAuditSink(w io.Writer) *WriterAuditSink {
+	return &WriterAuditSink{w: w}
+}
+
+// OpenAuditLog opens (or creates) an append-only audit log file.
+func OpenAuditLog(path string) (*WriterAuditSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWriterAuditSink(f), nil
+}
+
+func (was *WriterAuditSink) Record(rec AuditRecord) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	was.mu.Lock()
+	defer was.mu.Unlock()
+
+	_, err = was.w.Write(append(b, '\n'))
+	return err
+}
+
+// Close closes the underlying writer if it is an io.Closer.
+func (was *WriterAuditSink) Close() error {
+	if c, ok := was.w.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+// FindAuditRecords reads an audit log written by a WriterAuditSink and
+// returns every record for the given message id, oldest first.
+func FindAuditRecords(r io.Reader, messageId string) ([]AuditRecord, error) {
+	var recs []AuditRecord
+
+	dec := json.NewDecoder(r)
+	for {
+		var rec AuditRecord
+		if err := dec.Decode(&rec); err == io.EOF {
+			break
+		} else if err != nil {
+			return recs, err
+		}
+
+		if rec.MessageId == messageId {
+			recs = append(recs, rec)
+		}
+	}
+
+	return recs, nil
+}
@@ -10,18 +10,11 @@ import (
 )
 
 func GenerateSignature(sqsURI, method, secret string, uv url.Values) string {
-	u, err := url.Parse(sqsURI)
+	sigPayload, err := StringToSign(sqsURI, method, uv)
 	if err != nil {
 		return ""
 	}
 
-	sigPayload := strings.Join([]string{
-		method,
-		u.Host,
-		u.Path,
-		uv.Encode(),
-	}, "\n")
-
 	h := hmac.New(sha256.New, []byte(secret))
 	fmt.Fprint(h, sigPayload)
 
@@ -31,3 +24,32 @@ func GenerateSignature(sqsURI, method, secret string, uv url.Values) string {
 
 	return string(sig)
 }
+
+// StringToSign returns the canonical string that GenerateSignature signs
+// for the given request.
+func StringToSign(sqsURI, method string, uv url.Values) (string, error) {
+	u, err := url.Parse(sqsURI)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.Join([]string{
+		method,
+		u.Host,
+		u.Path,
+		uv.Encode(),
+	}, "\n"), nil
+}
+
+// SignatureError is returned in place of an *ErrorResponse when
+// SQSRequest.DiagnoseSignatures is set and SQS rejects a request with
+// SignatureDoesNotMatch. It carries the string the client signed so it
+// can be compared with the one the server expected.
+type SignatureError struct {
+	StringToSign string
+	Response     *ErrorResponse
+}
+
+func (se *SignatureError) Error() string {
+	return fmt.Sprintf("%s\nString to sign:\n%s", se.Response, se.StringToSign)
+}
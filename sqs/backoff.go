@@ -0,0 +1,45 @@
+package sqs
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Backoff describes an exponentially growing delay, capped at Max.
+type Backoff struct {
+	Initial    time.Duration
+	Max        time.Duration
+	Multiplier float64 // defaults to 2
+}
+
+// Delay returns the delay before the n-th consecutive attempt, counting
+// from 1.
+func (b *Backoff) Delay(n int) time.Duration {
+	if n < 1 || b.Initial <= 0 {
+		return 0
+	}
+
+	mult := b.Multiplier
+	if mult < 1 {
+		mult = 2
+	}
+
+	d := float64(b.Initial) * math.Pow(mult, float64(n-1))
+	if b.Max > 0 && d > float64(b.Max) {
+		return b.Max
+	}
+
+	return time.Duration(d)
+}
+
+// sleep waits for d, or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
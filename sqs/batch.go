@@ -0,0 +1,103 @@
+package sqs
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+)
+
+// maxBatchBytes is the largest combined payload SQS accepts in a single
+// SendMessageBatch request.
+const maxBatchBytes = 256 * 1024
+
+type BatchEntry struct {
+	Id   string
+	Body []byte
+	SendOptions
+}
+
+type SendMessageBatchResultEntry struct {
+	Id            string `xml:"Id"`
+	MessageId     string `xml:"MessageId"`
+	MessageMD5    string `xml:"MD5OfMessageBody"`
+	AttributesMD5 string `xml:"MD5OfMessageAttributes"`
+}
+
+type SendMessageBatchResponse struct {
+	Successful []SendMessageBatchResultEntry `xml:"SendMessageBatchResult>SendMessageBatchResultEntry"`
+	Failed     []BatchResultError            `xml:"SendMessageBatchResult>BatchResultErrorEntry"`
+	BasicResponse
+}
+
+// SendSQSMessageBatch sends up to ten messages in a single request. Entry
+// ids must be unique within the batch; they are echoed back in the
+// response to tell which entries succeeded. Entries whose MD5 digests do
+// not match are reported as failed, with ChecksumMismatchCode.
+func (s *SQSRequest) SendSQSMessageBatch(entries []BatchEntry) (*SendMessageBatchResponse, error) {
+	if len(entries) == 0 || len(entries) > maxBatchEntries {
+		return nil, fmt.Errorf("A batch needs between 1 and %d entries, got %d.", maxBatchEntries, len(entries))
+	}
+
+	params := map[string]string{
+		"Action": "SendMessageBatch",
+	}
+
+	sent := make(map[string]int, len(entries))
+	bodies := make([]string, len(entries))
+	attrs := make([][]MessageAttribute, len(entries))
+	for i, entry := range entries {
+		if err := entry.validate(); err != nil {
+			return nil, err
+		}
+
+		body, opts, err := s.encodeBody(entry.Body, &entry.SendOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		body, opts, err = s.offload(context.Background(), body, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		prefix := fmt.Sprintf("SendMessageBatchRequestEntry.%d.", i+1)
+		params[prefix+"Id"] = entry.Id
+		params[prefix+"MessageBody"] = body
+		bodies[i] = body
+		attrs[i] = sendAttributes(opts)
+		sent[entry.Id] = i
+		opts.setParams(params, prefix)
+	}
+
+	s.observeBatch("SendMessageBatch", len(entries))
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	smr := new(SendMessageBatchResponse)
+	if err = xml.NewDecoder(reader).Decode(smr); err != nil {
+		return nil, err
+	}
+
+	successful := smr.Successful[:0]
+	for _, result := range smr.Successful {
+		i, ok := sent[result.Id]
+		if !ok {
+			successful = append(successful, result)
+			continue
+		}
+
+		err := s.verifyMessage(result.MessageId, bodies[i], result.MessageMD5, attrs[i], result.AttributesMD5)
+		if err != nil {
+			smr.Failed = append(smr.Failed, BatchResultError{result.Id, ChecksumMismatchCode, err.Error(), false})
+			continue
+		}
+		successful = append(successful, result)
+	}
+	smr.Successful = successful
+
+	return smr, nil
+}
@@ -0,0 +1,124 @@
+package sqs
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"unicode/utf8"
+)
+
+// BodyCodec converts message payloads to and from the text SQS stores as
+// the message body. Both ends of a queue must use the same codec.
+//
+// Clients used to query-escape bodies, which corrupts messages sent by
+// other producers. They now default to RawBody. To migrate a queue whose
+// messages were sent query-escaped, set BodyCodec to QueryEscapedBody on
+// its consumers until those messages are drained, and on its producers
+// until every consumer has been switched over; then drop the setting
+// everywhere, or set Base64Body on both ends for binary payloads.
+type BodyCodec interface {
+	EncodeBody(payload []byte) (string, error)
+	DecodeBody(body string) ([]byte, error)
+}
+
+var (
+	// RawBody sends payloads unchanged. They must be UTF-8 text made of
+	// characters SQS accepts, which excludes most control characters;
+	// others fail with ErrInvalidBody. It is the default.
+	RawBody BodyCodec = rawBody{}
+
+	// Base64Body sends payloads base64 encoded and is safe for any binary
+	// data, at the cost of a third more bytes on the wire.
+	Base64Body BodyCodec = base64Body{}
+
+	// JSONBody sends payloads that are JSON documents unchanged and
+	// rejects anything else.
+	JSONBody BodyCodec = jsonBody{}
+
+	// QueryEscapedBody URL query-escapes payloads, as clients used to by
+	// default. It is only kept so that existing queues stay readable;
+	// other producers' messages containing '%' or '+' do not survive it.
+	QueryEscapedBody BodyCodec = queryEscapedBody{}
+)
+
+var ErrInvalidBody = errors.New("Message body contains characters SQS does not accept; use Base64Body for binary payloads.")
+
+type rawBody struct{}
+
+func (rawBody) EncodeBody(payload []byte) (string, error) {
+	if !validBody(payload) {
+		return "", ErrInvalidBody
+	}
+
+	return string(payload), nil
+}
+
+func (rawBody) DecodeBody(body string) ([]byte, error) {
+	return []byte(body), nil
+}
+
+// validBody reports whether payload only holds the characters SQS allows
+// in a message body: tab, newline, carriage return and the Unicode range
+// from space upwards, minus surrogates and U+FFFE and U+FFFF.
+func validBody(payload []byte) bool {
+	for len(payload) > 0 {
+		r, size := utf8.DecodeRune(payload)
+		if r == utf8.RuneError && size <= 1 {
+			return false
+		}
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' || r == 0xFFFE || r == 0xFFFF {
+			return false
+		}
+		payload = payload[size:]
+	}
+
+	return true
+}
+
+type base64Body struct{}
+
+func (base64Body) EncodeBody(payload []byte) (string, error) {
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
+
+func (base64Body) DecodeBody(body string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(body)
+}
+
+type jsonBody struct{}
+
+func (jsonBody) EncodeBody(payload []byte) (string, error) {
+	if !json.Valid(payload) {
+		return "", errors.New("Message body is not a valid JSON document.")
+	}
+
+	return string(payload), nil
+}
+
+func (jsonBody) DecodeBody(body string) ([]byte, error) {
+	if !json.Valid([]byte(body)) {
+		return nil, errors.New("Message body is not a valid JSON document.")
+	}
+
+	return []byte(body), nil
+}
+
+type queryEscapedBody struct{}
+
+func (queryEscapedBody) EncodeBody(payload []byte) (string, error) {
+	return url.QueryEscape(string(payload)), nil
+}
+
+func (queryEscapedBody) DecodeBody(body string) ([]byte, error) {
+	payload, err := url.QueryUnescape(body)
+	return []byte(payload), err
+}
+
+func (s *SQSRequest) bodyCodec() BodyCodec {
+	if s.BodyCodec == nil {
+		return RawBody
+	}
+
+	return s.BodyCodec
+}
@@ -0,0 +1,136 @@
+package sqs
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// allBytes holds every byte value, which only binary-safe codecs survive.
+func allBytes() []byte {
+	b := make([]byte, 256)
+	for i := range b {
+		b[i] = byte(i)
+	}
+
+	return b
+}
+
+func TestBodyCodecRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		codec   BodyCodec
+		payload []byte
+	}{
+		{"raw text", RawBody, []byte("50% off + free shipping\n\tnow")},
+		{"raw unicode", RawBody, []byte("café \U0001F600")},
+		{"raw empty", RawBody, []byte{}},
+		{"base64 binary", Base64Body, allBytes()},
+		{"base64 empty", Base64Body, []byte{}},
+		{"json", JSONBody, []byte(`{"a":[1,"%+"]}`)},
+		{"query-escaped text", QueryEscapedBody, []byte("50% off + free shipping")},
+		{"query-escaped binary", QueryEscapedBody, allBytes()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := tt.codec.EncodeBody(tt.payload)
+			if err != nil {
+				t.Fatalf("EncodeBody: %v", err)
+			}
+			if tt.codec != QueryEscapedBody && tt.codec != RawBody && !validBody([]byte(body)) {
+				t.Errorf("EncodeBody produced a body SQS would reject: %q", body)
+			}
+
+			got, err := tt.codec.DecodeBody(body)
+			if err != nil {
+				t.Fatalf("DecodeBody: %v", err)
+			}
+			if !bytes.Equal(got, tt.payload) {
+				t.Errorf("round trip = %q, want %q", got, tt.payload)
+			}
+		})
+	}
+}
+
+func TestRawBodyRejectsBinary(t *testing.T) {
+	for _, payload := range [][]byte{
+		{0x00},
+		{'a', 0x07, 'b'},
+		{0xff, 0xfe},
+		[]byte("￿"),
+	} {
+		if _, err := RawBody.EncodeBody(payload); !errors.Is(err, ErrInvalidBody) {
+			t.Errorf("EncodeBody(%q) error = %v, want ErrInvalidBody", payload, err)
+		}
+	}
+}
+
+func TestDefaultBodyCodec(t *testing.T) {
+	s := &SQSRequest{}
+	if s.bodyCodec() != RawBody {
+		t.Fatalf("default codec = %T, want RawBody", s.bodyCodec())
+	}
+
+	// Bodies from other producers come through the default codec as is.
+	got, err := s.decodeBody("100%+done", nil)
+	if err != nil || string(got) != "100%+done" {
+		t.Errorf("decodeBody = %q, %v, want the body unchanged", got, err)
+	}
+}
+
+func TestEncodeBodyRoundTrip(t *testing.T) {
+	binary := bytes.Repeat(allBytes(), 8)
+	noise := make([]byte, 2048)
+	rand.New(rand.NewSource(1)).Read(noise)
+	text := bytes.Repeat([]byte("compressible text "), 100)
+
+	tests := []struct {
+		name       string
+		s          *SQSRequest
+		payload    []byte
+		compressed bool
+		err        error
+	}{
+		{"base64", &SQSRequest{BodyCodec: Base64Body}, binary, false, nil},
+		{"base64 compressed", &SQSRequest{BodyCodec: Base64Body, CompressAbove: 100}, text, true, nil},
+		{"raw compressed text", &SQSRequest{CompressAbove: 100}, text, true, nil},
+		{"raw below threshold", &SQSRequest{CompressAbove: 10000}, text, false, nil},
+		{"raw compressed binary", &SQSRequest{CompressAbove: 100}, binary, true, nil},
+		// Binary the codec refuses is compressed even if that does not
+		// make it smaller.
+		{"raw incompressible binary", &SQSRequest{CompressAbove: 100}, noise, true, nil},
+		{"raw uncompressed binary", &SQSRequest{}, binary, false, ErrInvalidBody},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, opts, err := tt.s.encodeBody(tt.payload, nil)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("encodeBody error = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+
+			var attrs []MessageAttribute
+			if opts != nil {
+				for name, value := range opts.MessageAttributes {
+					attrs = append(attrs, MessageAttribute{name, "String", value})
+				}
+			}
+			if compressed := len(attrs) == 1 && attrs[0].Name == ContentEncodingAttribute; compressed != tt.compressed {
+				t.Errorf("compressed = %v, want %v", compressed, tt.compressed)
+			}
+
+			got, err := tt.s.decodeBody(body, attrs)
+			if err != nil {
+				t.Fatalf("decodeBody: %v", err)
+			}
+			if !bytes.Equal(got, tt.payload) {
+				t.Errorf("round trip changed the payload")
+			}
+		})
+	}
+}
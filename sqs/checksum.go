@@ -0,0 +1,104 @@
+package sqs
+
+import (
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ChecksumMismatchCode is the code of the BatchResultError reported for a
+// batch entry whose MD5 digest did not match.
+const ChecksumMismatchCode = "ChecksumMismatch"
+
+// ChecksumError reports that the MD5 digest SQS returned for a message
+// does not match the one computed locally, meaning the body or attributes
+// were corrupted on the way.
+type ChecksumError struct {
+	MessageId string
+	Field     string // "body" or "attributes"
+	Expected  string
+	Actual    string
+}
+
+func (ce *ChecksumError) Error() string {
+	return fmt.Sprintf("MD5 of message %s %s does not match: computed %s, SQS returned %s.", ce.MessageId, ce.Field, ce.Expected, ce.Actual)
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// messageAttributesMD5 computes the digest SQS returns as
+// MD5OfMessageAttributes: the attributes sorted by name, each encoded as
+// length-prefixed name, data type and value, with a transport type byte
+// before the value.
+func messageAttributesMD5(attrs []MessageAttribute) string {
+	sorted := make([]MessageAttribute, len(attrs))
+	copy(sorted, attrs)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	h := md5.New()
+	field := func(s string) {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(s)))
+		h.Write(size[:])
+		h.Write([]byte(s))
+	}
+
+	for _, attr := range sorted {
+		field(attr.Name)
+		field(attr.DataType)
+		h.Write([]byte{1}) // String transport type
+		field(attr.StringValue)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sendAttributes(opts *SendOptions) []MessageAttribute {
+	if opts == nil {
+		return nil
+	}
+
+	attrs := make([]MessageAttribute, 0, len(opts.MessageAttributes))
+	for name, value := range opts.MessageAttributes {
+		attrs = append(attrs, MessageAttribute{name, "String", value})
+	}
+
+	return attrs
+}
+
+// verifyMessage checks the digests SQS returned for a message against its
+// body, as sent over the wire, and its attributes. Digests SQS did not
+// return are not checked, nor are attributes with binary values, which
+// are not decoded.
+func (s *SQSRequest) verifyMessage(messageId, body, bodyMD5 string, attrs []MessageAttribute, attrsMD5 string) error {
+	if s.SkipChecksums {
+		return nil
+	}
+
+	if bodyMD5 != "" {
+		if sum := md5Hex(body); !strings.EqualFold(sum, bodyMD5) {
+			return &ChecksumError{messageId, "body", sum, bodyMD5}
+		}
+	}
+
+	if attrsMD5 == "" || len(attrs) == 0 {
+		return nil
+	}
+	for _, attr := range attrs {
+		if strings.HasPrefix(attr.DataType, "Binary") {
+			return nil
+		}
+	}
+
+	if sum := messageAttributesMD5(attrs); !strings.EqualFold(sum, attrsMD5) {
+		return &ChecksumError{messageId, "attributes", sum, attrsMD5}
+	}
+
+	return nil
+}
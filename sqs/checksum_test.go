@@ -0,0 +1,80 @@
+package sqs
+
+import "testing"
+
+func TestMessageAttributesMD5(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs []MessageAttribute
+		want  string
+	}{
+		{"string", []MessageAttribute{{"tenant", "String", "acme"}}, "c52f727da6769fcbc66f3f8555ce234a"},
+		{"number", []MessageAttribute{{"count", "Number", "42"}}, "2ee5fa915753ff72599b2514463a2897"},
+		{"utf-8 value", []MessageAttribute{{"name", "String", "héllo"}}, "2a74c928eb7620ad8dc6bac54338bed1"},
+		{
+			// Encoded sorted by name, whatever the order given.
+			"sorted names",
+			[]MessageAttribute{{"tenant", "String", "acme"}, {"price", "Number.float", "9.99"}, {"count", "Number", "42"}},
+			"602e7ad54477a2e6c5303ea9234e466e",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := messageAttributesMD5(tt.attrs); got != tt.want {
+				t.Errorf("messageAttributesMD5 = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyMessage(t *testing.T) {
+	tenant := []MessageAttribute{{"tenant", "String", "acme"}}
+	binary := []MessageAttribute{{"tenant", "String", "acme"}, {"blob", "Binary", "aGVsbG8="}}
+
+	tests := []struct {
+		name     string
+		body     string
+		bodyMD5  string
+		attrs    []MessageAttribute
+		attrsMD5 string
+		skip     bool
+
+		field string // of the mismatch, if any
+	}{
+		{name: "body", body: "hello", bodyMD5: "5d41402abc4b2a76b9719d911017c592"},
+		{name: "empty body", body: "", bodyMD5: "d41d8cd98f00b204e9800998ecf8427e"},
+		{name: "utf-8 body", body: "héllo ✓", bodyMD5: "21b1ae5bc147bb564254200a4731e337"},
+		{name: "upper case digest", body: "hello", bodyMD5: "5D41402ABC4B2A76B9719D911017C592"},
+		{name: "body mismatch", body: "hellO", bodyMD5: "5d41402abc4b2a76b9719d911017c592", field: "body"},
+		{name: "no body digest", body: "hello"},
+		{name: "attributes", body: "hello", attrs: tenant, attrsMD5: "c52f727da6769fcbc66f3f8555ce234a"},
+		{name: "attributes mismatch", body: "hello", attrs: tenant, attrsMD5: "2ee5fa915753ff72599b2514463a2897", field: "attributes"},
+		{name: "no attributes digest", body: "hello", attrs: tenant},
+		// Binary values are not decoded, so their digest cannot be
+		// computed.
+		{name: "binary attribute", body: "hello", attrs: binary, attrsMD5: "00000000000000000000000000000000"},
+		{name: "skip checksums", body: "hellO", bodyMD5: "5d41402abc4b2a76b9719d911017c592", attrs: tenant, attrsMD5: "0", skip: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SQSRequest{SkipChecksums: tt.skip}
+			err := s.verifyMessage("m1", tt.body, tt.bodyMD5, tt.attrs, tt.attrsMD5)
+
+			if tt.field == "" {
+				if err != nil {
+					t.Errorf("verifyMessage = %v", err)
+				}
+				return
+			}
+			ce, ok := err.(*ChecksumError)
+			if !ok {
+				t.Fatalf("verifyMessage = %v, want a *ChecksumError", err)
+			}
+			if ce.Field != tt.field || ce.MessageId != "m1" {
+				t.Errorf("mismatch of %s in %s, want %s in m1", ce.Field, ce.MessageId, tt.field)
+			}
+		})
+	}
+}
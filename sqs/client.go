@@ -0,0 +1,451 @@
+package sqs
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// SQSClient is the set of queue and message operations of an SQSRequest.
+// Code that depends on it rather than on *SQSRequest, as Consumer,
+// Producer and Relay do, can be exercised against the in-memory fake in
+// the sqstest package. Settings that only an *SQSRequest has, such as its
+// Tracer, BodyCodec or Propagation policy, apply when it is one.
+type SQSClient interface {
+	SendSQSMessage(message []byte) (*SendMessageResponse, error)
+	SendSQSMessageWithOptions(message []byte, opts *SendOptions) (*SendMessageResponse, error)
+	SendSQSMessageContext(ctx context.Context, message []byte, opts *SendOptions) (*SendMessageResponse, error)
+	SendSQSMessageBatch(entries []BatchEntry) (*SendMessageBatchResponse, error)
+
+	ReceiveSQSMessage() (*RecvMessageResponse, error)
+	ReceiveSQSMessages(max, waitSeconds int) ([]*RecvMessageResponse, error)
+	ReceiveSQSMessagesContext(ctx context.Context, max, waitSeconds int) ([]*RecvMessageResponse, error)
+	Peek(max int) ([]*RecvMessageResponse, error)
+
+	DeleteSQSMessage(handle string) (*BasicResponse, error)
+	DeleteSQSMessageBatch(handles []string) (*DeleteMessageBatchResponse, error)
+	DeleteAll(ctx context.Context, handles []string) error
+	ChangeMessageVisibility(handle string, timeout int) (*BasicResponse, error)
+	ReleaseAll(ctx context.Context, msgs []*RecvMessageResponse) error
+
+	QueueURL() (*QueueURLResponse, error)
+	QueueURLForOwner(ownerAccountId string) (*QueueURLResponse, error)
+	CreateQueue(queueName string, options map[string]string) (*QueueURLResponse, error)
+	CreateQueueWithOptions(queueName string, opts *CreateQueueOptions) (*QueueURLResponse, error)
+	DeleteQueue() (*BasicResponse, error)
+	PurgeQueue() (*BasicResponse, error)
+	ListQueues(prefix string) (*QueueListResponse, error)
+	ListQueuesPage(prefix, nextToken string, maxResults int) (*QueueListResponse, error)
+
+	GetQueueAttributes(names ...string) (*QueueAttributesResponse, error)
+	SetQueueAttributes(attributes map[string]string) (*BasicResponse, error)
+
+	TagQueue(tags map[string]string) (*BasicResponse, error)
+	UntagQueue(keys ...string) (*BasicResponse, error)
+	ListQueueTags() (*QueueTagsResponse, error)
+
+	AddPermission(label string, accountIds, actions []string) (*BasicResponse, error)
+	RemovePermission(label string) (*BasicResponse, error)
+
+	ListDeadLetterSourceQueues() (*DeadLetterSourceQueuesResponse, error)
+	ListDeadLetterSourceQueuesPage(nextToken string, maxResults int) (*DeadLetterSourceQueuesResponse, error)
+	StartMessageMoveTask(dest SQSClient, maxPerSecond int) (*StartMessageMoveTaskResponse, error)
+	CancelMessageMoveTask(taskHandle string) (*CancelMessageMoveTaskResponse, error)
+	ListMessageMoveTasks(maxResults int) (*ListMessageMoveTasksResponse, error)
+}
+
+var _ SQSClient = (*SQSRequest)(nil)
+
+// requester is implemented by *SQSRequest and by the types that embed
+// it, such as Queue.
+type requester interface {
+	request() *SQSRequest
+}
+
+func (s *SQSRequest) request() *SQSRequest {
+	return s
+}
+
+// settings returns the *SQSRequest behind c, whose settings apply to the
+// messages sent and received through c, or the zero SQSRequest if c is
+// another implementation.
+func settings(c SQSClient) *SQSRequest {
+	if r, ok := c.(requester); ok {
+		return r.request()
+	}
+
+	return &SQSRequest{}
+}
+
+// queueName returns the name of the queue c operates on, if it can tell.
+func queueName(c SQSClient) string {
+	switch q := c.(type) {
+	case requester:
+		return q.request().QueueName
+	case interface{ Name() string }:
+		return q.Name()
+	}
+
+	return ""
+}
+
+// queueURI returns the URL of the queue c operates on, or "" if it cannot
+// be resolved.
+func queueURI(c SQSClient) string {
+	if r, ok := c.(requester); ok {
+		return r.request().generateSQSQueueURI()
+	}
+
+	qur, err := c.QueueURL()
+	if err != nil {
+		return ""
+	}
+
+	return qur.QueueURL
+}
+
+// queueARN returns the ARN of the queue c operates on.
+func queueARN(c SQSClient) (string, error) {
+	qar, err := c.GetQueueAttributes(AttrQueueArn)
+	if err != nil {
+		return "", err
+	}
+
+	return qar.Attributes.QueueArn, nil
+}
+
+// Client holds the configuration shared by the queues of an account, so
+// that one set of credentials, HTTP client, limiter and so on serves any
+// number of queues. Its methods take the queue they act on, by name or by
+// URL; Queue returns a handle on a single queue instead. Handles are cheap
+// to make and share everything Defaults points to.
+type Client struct {
+	// Defaults configures every queue handle. Its RegionId, UUID (the
+	// account id) and credentials locate and sign requests; its
+	// QueueName and ExplicitQueueURL are ignored.
+	Defaults SQSRequest
+
+	mu   sync.Mutex
+	urls map[string]string // resolved queue URLs, by name
+}
+
+// Queue is a handle on one queue of a Client. It has every method of
+// SQSRequest, and can be passed wherever an SQSClient is expected.
+type Queue struct {
+	*SQSRequest
+}
+
+func NewClient(region, accountId, accessKey, secret string) *Client {
+	return &Client{
+		Defaults: SQSRequest{
+			RegionId:     region,
+			UUID:         accountId,
+			AWSAccessKey: accessKey,
+			AWSSecret:    secret,
+		},
+	}
+}
+
+func (c *Client) account() *SQSRequest {
+	s := c.Defaults
+	s.QueueName = ""
+	s.ExplicitQueueURL = ""
+
+	return &s
+}
+
+// Queue returns a handle on the queue given by name or by URL. When the
+// client has no account id, names are resolved with GetQueueUrl, once.
+func (c *Client) Queue(nameOrURL string) (*Queue, error) {
+	s := c.account()
+	if strings.HasPrefix(nameOrURL, "https://") || strings.HasPrefix(nameOrURL, "http://") {
+		return queueHandle(s.WithQueueURL(nameOrURL))
+	}
+
+	s.QueueName = nameOrURL
+	if s.UUID != "" {
+		return &Queue{s}, nil
+	}
+
+	c.mu.Lock()
+	queueURL, ok := c.urls[nameOrURL]
+	c.mu.Unlock()
+
+	if !ok {
+		qur, err := s.QueueURL()
+		if err != nil {
+			return nil, err
+		}
+		queueURL = qur.QueueURL
+
+		c.mu.Lock()
+		if c.urls == nil {
+			c.urls = make(map[string]string)
+		}
+		c.urls[nameOrURL] = queueURL
+		c.mu.Unlock()
+	}
+
+	return queueHandle(s.WithQueueURL(queueURL))
+}
+
+func queueHandle(s *SQSRequest, err error) (*Queue, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	return &Queue{s}, nil
+}
+
+// forget drops the resolved URL of a queue that was deleted.
+func (c *Client) forget(queue string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for name, queueURL := range c.urls {
+		if name == queue || queueURL == queue {
+			delete(c.urls, name)
+		}
+	}
+}
+
+// CreateQueue creates a queue and returns a handle on it.
+func (c *Client) CreateQueue(name string, opts *CreateQueueOptions) (*Queue, error) {
+	qur, err := c.account().CreateQueueWithOptions(name, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return queueHandle(c.account().WithQueueURL(qur.QueueURL))
+}
+
+// QueueURL returns the URL of the queue given by name or by URL.
+func (c *Client) QueueURL(queue string) (string, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return "", err
+	}
+
+	return q.generateSQSQueueURI(), nil
+}
+
+// DeleteQueue deletes the queue given by name or by URL.
+func (c *Client) DeleteQueue(queue string) (*BasicResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	br, err := q.DeleteQueue()
+	if err != nil {
+		return nil, err
+	}
+	c.forget(queue)
+
+	return br, nil
+}
+
+// PurgeQueue deletes every message of the queue given by name or by URL.
+func (c *Client) PurgeQueue(queue string) (*BasicResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.PurgeQueue()
+}
+
+// ListQueues returns the queues whose names start with prefix, up to
+// 1000 of them.
+func (c *Client) ListQueues(prefix string) (*QueueListResponse, error) {
+	return c.account().ListQueues(prefix)
+}
+
+// ListQueuesPage returns a single page of the queues whose names start
+// with prefix. See SQSRequest.ListQueuesPage.
+func (c *Client) ListQueuesPage(prefix, nextToken string, maxResults int) (*QueueListResponse, error) {
+	return c.account().ListQueuesPage(prefix, nextToken, maxResults)
+}
+
+// SendMessage sends a message to the queue given by name or by URL.
+func (c *Client) SendMessage(ctx context.Context, queue string, message []byte, opts *SendOptions) (*SendMessageResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.SendSQSMessageContext(ctx, message, opts)
+}
+
+// SendMessageBatch sends up to ten messages to the queue given by name or
+// by URL.
+func (c *Client) SendMessageBatch(queue string, entries []BatchEntry) (*SendMessageBatchResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.SendSQSMessageBatch(entries)
+}
+
+// ReceiveMessages receives up to max messages from the queue given by name
+// or by URL, waiting up to waitSeconds for at least one to arrive.
+func (c *Client) ReceiveMessages(ctx context.Context, queue string, max, waitSeconds int) ([]*RecvMessageResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.ReceiveSQSMessagesContext(ctx, max, waitSeconds)
+}
+
+// DeleteMessage deletes a message from the queue given by name or by URL.
+func (c *Client) DeleteMessage(queue, handle string) (*BasicResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.DeleteSQSMessage(handle)
+}
+
+// DeleteMessageBatch deletes up to ten messages from the queue given by
+// name or by URL.
+func (c *Client) DeleteMessageBatch(queue string, handles []string) (*DeleteMessageBatchResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.DeleteSQSMessageBatch(handles)
+}
+
+// ChangeMessageVisibility sets the visibility timeout of an in-flight
+// message of the queue given by name or by URL.
+func (c *Client) ChangeMessageVisibility(queue, handle string, timeout int) (*BasicResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.ChangeMessageVisibility(handle, timeout)
+}
+
+// GetQueueAttributes returns attributes of the queue given by name or by
+// URL; all of them if no names are given.
+func (c *Client) GetQueueAttributes(queue string, names ...string) (*QueueAttributesResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.GetQueueAttributes(names...)
+}
+
+// SetQueueAttributes sets attributes of the queue given by name or by URL.
+func (c *Client) SetQueueAttributes(queue string, attributes map[string]string) (*BasicResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.SetQueueAttributes(attributes)
+}
+
+// TagQueue adds or replaces tags of the queue given by name or by URL.
+func (c *Client) TagQueue(queue string, tags map[string]string) (*BasicResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.TagQueue(tags)
+}
+
+// UntagQueue removes tags of the queue given by name or by URL.
+func (c *Client) UntagQueue(queue string, keys ...string) (*BasicResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.UntagQueue(keys...)
+}
+
+// ListQueueTags returns the tags of the queue given by name or by URL.
+func (c *Client) ListQueueTags(queue string) (*QueueTagsResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.ListQueueTags()
+}
+
+// AddPermission grants accounts actions on the queue given by name or by
+// URL. See SQSRequest.AddPermission.
+func (c *Client) AddPermission(queue, label string, accountIds, actions []string) (*BasicResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.AddPermission(label, accountIds, actions)
+}
+
+// RemovePermission revokes the permissions added under label on the queue
+// given by name or by URL.
+func (c *Client) RemovePermission(queue, label string) (*BasicResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.RemovePermission(label)
+}
+
+// ListDeadLetterSourceQueues returns the queues whose redrive policy
+// targets the queue given by name or by URL, up to 1000 of them.
+func (c *Client) ListDeadLetterSourceQueues(queue string) (*DeadLetterSourceQueuesResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.ListDeadLetterSourceQueues()
+}
+
+// StartMessageMoveTask redrives the messages of the dead-letter queue
+// given by name or by URL to dest, or back to the queues they came from if
+// dest is empty.
+func (c *Client) StartMessageMoveTask(queue, dest string, maxPerSecond int) (*StartMessageMoveTaskResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	var d SQSClient
+	if dest != "" {
+		if d, err = c.Queue(dest); err != nil {
+			return nil, err
+		}
+	}
+
+	return q.StartMessageMoveTask(d, maxPerSecond)
+}
+
+// CancelMessageMoveTask stops a running move task.
+func (c *Client) CancelMessageMoveTask(taskHandle string) (*CancelMessageMoveTaskResponse, error) {
+	return c.account().CancelMessageMoveTask(taskHandle)
+}
+
+// ListMessageMoveTasks returns the most recent move tasks of the
+// dead-letter queue given by name or by URL.
+func (c *Client) ListMessageMoveTasks(queue string, maxResults int) (*ListMessageMoveTasksResponse, error) {
+	q, err := c.Queue(queue)
+	if err != nil {
+		return nil, err
+	}
+
+	return q.ListMessageMoveTasks(maxResults)
+}
@@ -0,0 +1,96 @@
+package sqs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestClientTargetsQueuePerCall(t *testing.T) {
+	var calls []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		calls = append(calls, r.PostForm.Get("Action")+" "+r.URL.Path)
+
+		switch r.PostForm.Get("Action") {
+		case "GetQueueAttributes":
+			w.Write([]byte(`<GetQueueAttributesResponse><GetQueueAttributesResult><Attribute><Name>ApproximateNumberOfMessages</Name><Value>3</Value></Attribute></GetQueueAttributesResult></GetQueueAttributesResponse>`))
+		case "ListQueueTags":
+			w.Write([]byte(`<ListQueueTagsResponse><ListQueueTagsResult><Tag><Key>team</Key><Value>billing</Value></Tag></ListQueueTagsResult></ListQueueTagsResponse>`))
+		default:
+			w.Write([]byte(`<Response></Response>`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient("us-east-1", "123456789012", "key", "secret")
+	c.Defaults.Endpoint = srv.URL
+	c.Defaults.Protocol = ProtocolQuery
+
+	if _, err := c.PurgeQueue("orders"); err != nil {
+		t.Fatal(err)
+	}
+	qar, err := c.GetQueueAttributes("invoices", AttrApproximateNumberOfMessages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qar.Attributes.ApproximateNumberOfMessages != 3 {
+		t.Errorf("ApproximateNumberOfMessages = %d", qar.Attributes.ApproximateNumberOfMessages)
+	}
+	qtr, err := c.ListQueueTags(srv.URL + "/123456789012/refunds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qtr.Tags["team"] != "billing" {
+		t.Errorf("tags = %v", qtr.Tags)
+	}
+	if _, err = c.DeleteQueue("orders"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"PurgeQueue /123456789012/orders/",
+		"GetQueueAttributes /123456789012/invoices/",
+		"ListQueueTags /123456789012/refunds",
+		"DeleteQueue /123456789012/orders/",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("requests = %q, want %q", calls, want)
+	}
+}
+
+func TestClientQueueHandle(t *testing.T) {
+	c := NewClient("eu-west-1", "123456789012", "key", "secret")
+	c.Defaults.WaitSeconds = 5
+
+	q, err := c.Queue("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A Queue is an SQSClient that keeps the client's settings.
+	var sc SQSClient = q
+	if s := settings(sc); s.WaitSeconds != 5 || s.RegionId != "eu-west-1" {
+		t.Errorf("settings of a Queue = %+v", s)
+	}
+	if got := queueName(sc); got != "orders" {
+		t.Errorf("queueName = %q", got)
+	}
+
+	url, err := c.QueueURL("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://sqs.eu-west-1.amazonaws.com/123456789012/orders/" {
+		t.Errorf("QueueURL = %q", url)
+	}
+
+	other, err := c.Queue("invoices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.SQSRequest == q.SQSRequest || q.QueueName != "orders" {
+		t.Error("queue handles share their SQSRequest")
+	}
+}
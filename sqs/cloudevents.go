@@ -0,0 +1,117 @@
+package sqs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const CloudEventsSpecVersion = "1.0"
+
+// CloudEvent is a CloudEvents 1.0 event in structured JSON mode: the whole
+// event, context attributes and data alike, travels as the message body.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	Id              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataSchema      string          `json:"dataschema,omitempty"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            *time.Time      `json:"time,omitempty"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// NewCloudEvent builds an event with a random id and the current time,
+// carrying data encoded as JSON.
+func NewCloudEvent(source, eventType string, data interface{}) (*CloudEvent, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	ce := &CloudEvent{
+		SpecVersion: CloudEventsSpecVersion,
+		Id:          hex.EncodeToString(id),
+		Source:      source,
+		Type:        eventType,
+		Time:        &now,
+	}
+
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		ce.Data = b
+		ce.DataContentType = "application/json"
+	}
+
+	return ce, nil
+}
+
+func (ce *CloudEvent) Validate() error {
+	switch {
+	case ce.SpecVersion != CloudEventsSpecVersion:
+		return errors.New("CloudEvent specversion must be " + CloudEventsSpecVersion + ".")
+	case ce.Id == "":
+		return errors.New("CloudEvent needs an id.")
+	case ce.Source == "":
+		return errors.New("CloudEvent needs a source.")
+	case ce.Type == "":
+		return errors.New("CloudEvent needs a type.")
+	}
+
+	return nil
+}
+
+// DecodeData unmarshals the JSON data of the event into v.
+func (ce *CloudEvent) DecodeData(v interface{}) error {
+	return json.Unmarshal(ce.Data, v)
+}
+
+func ParseCloudEvent(body string) (*CloudEvent, error) {
+	ce := new(CloudEvent)
+	if err := json.Unmarshal([]byte(body), ce); err != nil {
+		return nil, err
+	}
+
+	if err := ce.Validate(); err != nil {
+		return nil, err
+	}
+
+	return ce, nil
+}
+
+func (s *SQSRequest) SendCloudEvent(ce *CloudEvent) (*SendMessageResponse, error) {
+	if err := ce.Validate(); err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(ce)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.SendSQSMessage(b)
+}
+
+// ReceiveCloudEvent receives a message and parses its body as a
+// structured-mode CloudEvent. The message is returned as well so that it
+// can be deleted once processed.
+func (s *SQSRequest) ReceiveCloudEvent() (*CloudEvent, *RecvMessageResponse, error) {
+	rmr, err := s.ReceiveSQSMessage()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ce, err := ParseCloudEvent(rmr.MessageBody)
+	if err != nil {
+		return nil, rmr, err
+	}
+
+	return ce, rmr, nil
+}
@@ -0,0 +1,85 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type metricStatisticsResponse struct {
+	Datapoints []struct {
+		Timestamp time.Time `xml:"Timestamp"`
+		Maximum   float64   `xml:"Maximum"`
+	} `xml:"GetMetricStatisticsResult>Datapoints>member"`
+}
+
+// OldestMessageAge returns the age of the oldest message on the queue, as
+// last reported by the ApproximateAgeOfOldestMessage CloudWatch metric.
+// Unlike peeking, it leaves messages alone, but the metric is published
+// once a minute and lags by a few minutes. It is zero when
+// CloudWatch has no datapoint from the last 15 minutes, as for queues
+// that have been empty for hours. The client's credentials need
+// cloudwatch:GetMetricStatistics.
+func (s *SQSRequest) OldestMessageAge(ctx context.Context) (time.Duration, error) {
+	end := time.Now().UTC()
+
+	uv := url.Values{}
+	uv.Set("Action", "GetMetricStatistics")
+	uv.Set("Version", "2010-08-01")
+	uv.Set("Namespace", "AWS/SQS")
+	uv.Set("MetricName", "ApproximateAgeOfOldestMessage")
+	uv.Set("Dimensions.member.1.Name", "QueueName")
+	uv.Set("Dimensions.member.1.Value", s.QueueName)
+	uv.Set("StartTime", end.Add(-15*time.Minute).Format(time.RFC3339))
+	uv.Set("EndTime", end.Format(time.RFC3339))
+	uv.Set("Period", "60")
+	uv.Set("Statistics.member.1", "Maximum")
+	body := []byte(uv.Encode())
+
+	endpoint := fmt.Sprintf("https://monitoring.%s.amazonaws.com/", s.RegionId)
+	if s.Endpoint != "" {
+		endpoint = strings.TrimSuffix(s.Endpoint, "/") + "/"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	SignV4(req, sha256Hex(body), s.AWSAccessKey, s.AWSSecret, s.RegionId, "monitoring", time.Now())
+
+	resp, err := s.roundTrip(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		er := new(ErrorResponse)
+		if xml.NewDecoder(resp.Body).Decode(er) != nil || er.Code == "" {
+			return 0, errors.New(resp.Status)
+		}
+		return 0, er
+	}
+
+	msr := new(metricStatisticsResponse)
+	if err = xml.NewDecoder(resp.Body).Decode(msr); err != nil {
+		return 0, err
+	}
+
+	var latest time.Time
+	var age float64
+	for _, dp := range msr.Datapoints {
+		if dp.Timestamp.After(latest) {
+			latest, age = dp.Timestamp, dp.Maximum
+		}
+	}
+
+	return time.Duration(age * float64(time.Second)), nil
+}
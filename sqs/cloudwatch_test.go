@@ -0,0 +1,55 @@
+package sqs
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOldestMessageAge(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if got := r.PostForm.Get("MetricName"); got != "ApproximateAgeOfOldestMessage" {
+			t.Errorf("MetricName = %q", got)
+		}
+		if got := r.PostForm.Get("Dimensions.member.1.Value"); got != "orders" {
+			t.Errorf("queue dimension = %q, want orders", got)
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "/us-east-1/monitoring/") {
+			t.Errorf("Authorization = %q, want a monitoring scope", r.Header.Get("Authorization"))
+		}
+
+		w.Write([]byte(`<GetMetricStatisticsResponse><GetMetricStatisticsResult><Datapoints>
+			<member><Timestamp>2024-05-01T10:01:00Z</Timestamp><Maximum>90.0</Maximum></member>
+			<member><Timestamp>2024-05-01T10:02:00Z</Timestamp><Maximum>125.0</Maximum></member>
+			<member><Timestamp>2024-05-01T10:00:00Z</Timestamp><Maximum>300.0</Maximum></member>
+		</Datapoints></GetMetricStatisticsResult></GetMetricStatisticsResponse>`))
+	}))
+	defer srv.Close()
+
+	s := &SQSRequest{RegionId: "us-east-1", QueueName: "orders", Endpoint: srv.URL}
+	age, err := s.OldestMessageAge(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if age != 125*time.Second {
+		t.Errorf("age = %s, want the latest datapoint, 2m5s", age)
+	}
+}
+
+func TestOldestMessageAgeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code><Message>no</Message></Error></ErrorResponse>`))
+	}))
+	defer srv.Close()
+
+	s := &SQSRequest{RegionId: "us-east-1", QueueName: "orders", Endpoint: srv.URL}
+	_, err := s.OldestMessageAge(context.Background())
+	if er, ok := err.(*ErrorResponse); !ok || er.Code != "AccessDenied" {
+		t.Errorf("error = %v, want an AccessDenied *ErrorResponse", err)
+	}
+}
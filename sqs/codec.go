@@ -0,0 +1,211 @@
+package sqs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"sync"
+)
+
+// ContentTypeAttribute names the codec a message sent with SendTyped was
+// marshaled with.
+const ContentTypeAttribute = "content-type"
+
+// Codec marshals Go values into message payloads and back. ContentType
+// identifies the codec to receivers. Binary reports whether its payloads
+// may hold bytes that RawBody rejects.
+type Codec interface {
+	ContentType() string
+	Binary() bool
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSONCodec Codec = jsonCodec{}
+
+	// GobCodec produces binary payloads. Unless the client's BodyCodec is
+	// binary-safe, SendTyped base64 encodes them and says so in
+	// ContentTypeAttribute.
+	GobCodec Codec = gobCodec{}
+
+	// ProtoCodec handles values with Marshal() ([]byte, error) and
+	// Unmarshal([]byte) error methods, as generated protobuf messages
+	// have. Its payloads are binary too.
+	ProtoCodec Codec = protoCodec{}
+)
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		JSONCodec.ContentType():  JSONCodec,
+		GobCodec.ContentType():   GobCodec,
+		ProtoCodec.ContentType(): ProtoCodec,
+	}
+)
+
+// RegisterCodec makes a codec known to UnmarshalMessage by its content
+// type, replacing any codec registered for the same type.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+
+	codecs[c.ContentType()] = c
+}
+
+func lookupCodec(contentType string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	c, ok := codecs[contentType]
+	return c, ok
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Binary() bool {
+	return false
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) ContentType() string {
+	return "application/x-gob"
+}
+
+func (gobCodec) Binary() bool {
+	return true
+}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+type protoCodec struct{}
+
+func (protoCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (protoCodec) Binary() bool {
+	return true
+}
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(interface{ Marshal() ([]byte, error) })
+	if !ok {
+		return nil, fmt.Errorf("%T is not a protobuf message.", v)
+	}
+
+	return m.Marshal()
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(interface{ Unmarshal([]byte) error })
+	if !ok {
+		return fmt.Errorf("%T is not a protobuf message.", v)
+	}
+
+	return m.Unmarshal(data)
+}
+
+func (s *SQSRequest) codec() Codec {
+	if s.Codec == nil {
+		return JSONCodec
+	}
+
+	return s.Codec
+}
+
+// binarySafe reports whether bc can encode any payload.
+func binarySafe(bc BodyCodec) bool {
+	return bc == Base64Body || bc == QueryEscapedBody
+}
+
+// SendTyped marshals v with the client's Codec and sends it, recording
+// the codec's content type in ContentTypeAttribute. Payloads of a binary
+// codec that the client's BodyCodec cannot carry are base64 encoded, and
+// their content type gets an encoding=base64 parameter.
+func (s *SQSRequest) SendTyped(v interface{}) (*SendMessageResponse, error) {
+	return s.SendTypedWithOptions(v, nil)
+}
+
+func (s *SQSRequest) SendTypedWithOptions(v interface{}, opts *SendOptions) (*SendMessageResponse, error) {
+	c := s.codec()
+
+	payload, err := c.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := c.ContentType()
+	if c.Binary() && !binarySafe(s.bodyCodec()) {
+		payload = []byte(base64.StdEncoding.EncodeToString(payload))
+		contentType = mime.FormatMediaType(contentType, map[string]string{"encoding": "base64"})
+	}
+
+	return s.SendSQSMessageWithOptions(payload, opts.withAttribute(ContentTypeAttribute, contentType))
+}
+
+// ReceiveTyped receives a single message and unmarshals it into v. Like
+// ReceiveSQSMessage it returns ErrNoMessage if none is available. The
+// message is returned even if unmarshaling fails, so it can be deleted.
+func (s *SQSRequest) ReceiveTyped(v interface{}) (*RecvMessageResponse, error) {
+	m, err := s.ReceiveSQSMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	return m, s.UnmarshalMessage(m, v)
+}
+
+// UnmarshalMessage unmarshals a message into v with the codec named by
+// its ContentTypeAttribute, or with the client's Codec if it has none.
+func (s *SQSRequest) UnmarshalMessage(m *RecvMessageResponse, v interface{}) error {
+	c := s.codec()
+	data := []byte(m.MessageBody)
+
+	if contentType := m.MessageAttribute(ContentTypeAttribute); contentType != "" {
+		mediaType, params, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return fmt.Errorf("Invalid content type %q of message %s.", contentType, m.MessageId)
+		}
+
+		var ok bool
+		if c, ok = lookupCodec(mediaType); !ok {
+			return fmt.Errorf("No codec registered for content type %q of message %s.", contentType, m.MessageId)
+		}
+
+		if params["encoding"] == "base64" {
+			if data, err = base64.StdEncoding.DecodeString(m.MessageBody); err != nil {
+				return err
+			}
+		}
+	}
+
+	return c.Unmarshal(data, v)
+}
@@ -0,0 +1,154 @@
+package sqs
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// loopback answers JSON protocol SendMessage and ReceiveMessage requests,
+// handing out the last message sent.
+type loopback struct {
+	*httptest.Server
+
+	mu   sync.Mutex
+	sent map[string]interface{}
+}
+
+func newLoopback(t *testing.T) *loopback {
+	lb := &loopback{}
+	lb.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+
+		lb.mu.Lock()
+		defer lb.mu.Unlock()
+
+		switch strings.TrimPrefix(r.Header.Get("X-Amz-Target"), jsonTargetPrefix) {
+		case "SendMessage":
+			lb.sent = req
+			w.Write([]byte(`{"MessageId":"m1"}`))
+		case "ReceiveMessage":
+			resp := map[string]interface{}{"Messages": []interface{}{map[string]interface{}{
+				"MessageId":         "m1",
+				"ReceiptHandle":     "h1",
+				"Body":              lb.sent["MessageBody"],
+				"MessageAttributes": lb.sent["MessageAttributes"],
+			}}}
+			json.NewEncoder(w).Encode(resp)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(lb.Close)
+
+	return lb
+}
+
+func (lb *loopback) body() string {
+	lb.mu.Lock()
+	defer lb.mu.Unlock()
+
+	body, _ := lb.sent["MessageBody"].(string)
+	return body
+}
+
+// point stands in for a generated protobuf message; its encoding holds
+// bytes RawBody rejects.
+type point struct {
+	X, Y uint32
+}
+
+func (p *point) Marshal() ([]byte, error) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, p.X)
+	binary.BigEndian.PutUint32(b[4:], p.Y)
+	return b, nil
+}
+
+func (p *point) Unmarshal(b []byte) error {
+	if len(b) != 8 {
+		return errors.New("Invalid point.")
+	}
+	p.X, p.Y = binary.BigEndian.Uint32(b), binary.BigEndian.Uint32(b[4:])
+	return nil
+}
+
+func TestTypedRoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		codec       Codec
+		bodyCodec   BodyCodec
+		contentType string
+	}{
+		{"json", JSONCodec, nil, "application/json"},
+		{"gob", GobCodec, nil, "application/x-gob; encoding=base64"},
+		{"proto", ProtoCodec, nil, "application/x-protobuf; encoding=base64"},
+		{"json with base64 bodies", JSONCodec, Base64Body, "application/json"},
+		{"gob with base64 bodies", GobCodec, Base64Body, "application/x-gob"},
+		{"proto with base64 bodies", ProtoCodec, Base64Body, "application/x-protobuf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lb := newLoopback(t)
+			s := &SQSRequest{
+				RegionId:      "us-east-1",
+				UUID:          "123456789012",
+				QueueName:     "orders",
+				AWSAccessKey:  "key",
+				AWSSecret:     "secret",
+				Endpoint:      lb.URL,
+				SkipChecksums: true,
+				Codec:         tt.codec,
+				BodyCodec:     tt.bodyCodec,
+			}
+
+			sent := &point{X: 1, Y: 0x0a000000}
+			if _, err := s.SendTyped(sent); err != nil {
+				t.Fatalf("SendTyped: %v", err)
+			}
+			if !validBody([]byte(lb.body())) {
+				t.Errorf("sent a body SQS would reject: %q", lb.body())
+			}
+
+			got := new(point)
+			m, err := s.ReceiveTyped(got)
+			if err != nil {
+				t.Fatalf("ReceiveTyped: %v", err)
+			}
+			if !reflect.DeepEqual(got, sent) {
+				t.Errorf("received %+v, want %+v", got, sent)
+			}
+			if ct := m.MessageAttribute(ContentTypeAttribute); ct != tt.contentType {
+				t.Errorf("content type = %q, want %q", ct, tt.contentType)
+			}
+
+			// A receiver with another default codec goes by the content
+			// type.
+			r := &SQSRequest{BodyCodec: tt.bodyCodec}
+			again := new(point)
+			if err = r.UnmarshalMessage(m, again); err != nil || !reflect.DeepEqual(again, sent) {
+				t.Errorf("UnmarshalMessage = %+v, %v", again, err)
+			}
+		})
+	}
+}
+
+func TestUnmarshalMessageUnknownContentType(t *testing.T) {
+	m := &RecvMessageResponse{MessageId: "m1", MessageBody: "{}"}
+	m.MessageAttributes = []MessageAttribute{{Name: ContentTypeAttribute, StringValue: "application/x-yaml"}}
+
+	s := &SQSRequest{}
+	if err := s.UnmarshalMessage(m, new(point)); err == nil || !strings.Contains(err.Error(), "No codec registered") {
+		t.Errorf("UnmarshalMessage = %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package sqs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+)
+
+// ContentEncodingAttribute marks messages whose body was compressed on
+// send. Such bodies are base64 encoded, whatever the client's BodyCodec.
+const ContentEncodingAttribute = "content-encoding"
+
+const gzipEncoding = "gzip"
+
+// encodeBody turns a payload into a message body, compressing it when it
+// is larger than CompressAbove and compression makes it smaller. The
+// returned options carry ContentEncodingAttribute if, and only if, it was
+// compressed; stale reserved attributes, as copied from a received
+// message, are dropped.
+func (s *SQSRequest) encodeBody(payload []byte, opts *SendOptions) (string, *SendOptions, error) {
+	opts = withoutReservedAttributes(opts)
+
+	body, err := s.bodyCodec().EncodeBody(payload)
+	if s.CompressAbove <= 0 || len(payload) <= s.CompressAbove {
+		return body, opts, err
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, cerr := zw.Write(payload); cerr != nil {
+		return "", nil, cerr
+	}
+	if cerr := zw.Close(); cerr != nil {
+		return "", nil, cerr
+	}
+
+	compressed := base64.StdEncoding.EncodeToString(buf.Bytes())
+	if err == nil && len(compressed) >= len(body) {
+		return body, opts, nil
+	}
+
+	return compressed, opts.withAttribute(ContentEncodingAttribute, gzipEncoding), nil
+}
+
+// reservedAttributes describe how a message body is encoded and are set
+// by the client itself.
+var reservedAttributes = []string{ContentEncodingAttribute, ExtendedPayloadSizeAttribute}
+
+func withoutReservedAttributes(opts *SendOptions) *SendOptions {
+	if opts == nil {
+		return nil
+	}
+
+	reserved := false
+	for _, name := range reservedAttributes {
+		if _, ok := opts.MessageAttributes[name]; ok {
+			reserved = true
+		}
+	}
+	if !reserved {
+		return opts
+	}
+
+	o := *opts
+	o.MessageAttributes = make(map[string]string, len(opts.MessageAttributes))
+	for name, value := range opts.MessageAttributes {
+		o.MessageAttributes[name] = value
+	}
+	for _, name := range reservedAttributes {
+		delete(o.MessageAttributes, name)
+	}
+
+	return &o
+}
+
+// decodeBody reverses encodeBody, decompressing bodies marked with
+// ContentEncodingAttribute regardless of the client's settings.
+func (s *SQSRequest) decodeBody(body string, attrs []MessageAttribute) ([]byte, error) {
+	for _, attr := range attrs {
+		if attr.Name != ContentEncodingAttribute || attr.StringValue != gzipEncoding {
+			continue
+		}
+
+		data, err := base64.StdEncoding.DecodeString(body)
+		if err != nil {
+			return nil, err
+		}
+
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+
+		return io.ReadAll(zr)
+	}
+
+	return s.bodyCodec().DecodeBody(body)
+}
@@ -0,0 +1,105 @@
+package sqs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompressAbove(t *testing.T) {
+	text := func(n int) []byte { return bytes.Repeat([]byte("a"), n) }
+
+	tests := []struct {
+		name          string
+		compressAbove int
+		payload       []byte
+		compressed    bool
+	}{
+		{"disabled", 0, text(10000), false},
+		{"negative", -1, text(10000), false},
+		{"at the threshold", 100, text(100), false},
+		{"above the threshold", 100, text(101), true},
+		{"no smaller once compressed", 10, []byte("qwertyuiopasdfghjkl"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &SQSRequest{CompressAbove: tt.compressAbove}
+			body, opts, err := s.encodeBody(tt.payload, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var attrs []MessageAttribute
+			encoding := ""
+			if opts != nil {
+				encoding = opts.MessageAttributes[ContentEncodingAttribute]
+				attrs = append(attrs, MessageAttribute{ContentEncodingAttribute, "String", encoding})
+			}
+			if compressed := encoding == gzipEncoding; compressed != tt.compressed {
+				t.Fatalf("compressed = %v, want %v", compressed, tt.compressed)
+			}
+			if tt.compressed && len(body) >= len(tt.payload) {
+				t.Errorf("compressed body of %d bytes for a payload of %d", len(body), len(tt.payload))
+			}
+
+			got, err := s.decodeBody(body, attrs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.payload) {
+				t.Errorf("round trip = %q, want %q", got, tt.payload)
+			}
+		})
+	}
+}
+
+func TestCompressedBodyIgnoresReceiverCodec(t *testing.T) {
+	payload := []byte(strings.Repeat("50% off + free shipping ", 20))
+
+	sender := &SQSRequest{CompressAbove: 100}
+	body, opts, err := sender.encodeBody(payload, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := []MessageAttribute{{ContentEncodingAttribute, "String", opts.MessageAttributes[ContentEncodingAttribute]}}
+
+	for _, codec := range []BodyCodec{RawBody, Base64Body, QueryEscapedBody} {
+		receiver := &SQSRequest{BodyCodec: codec}
+		got, err := receiver.decodeBody(body, attrs)
+		if err != nil || !bytes.Equal(got, payload) {
+			t.Errorf("decoding with %T = %q, %v", codec, got, err)
+		}
+	}
+}
+
+func TestEncodeBodyDropsStaleEncoding(t *testing.T) {
+	s := &SQSRequest{}
+	opts := &SendOptions{MessageAttributes: map[string]string{
+		ContentEncodingAttribute:     gzipEncoding,
+		ExtendedPayloadSizeAttribute: "300000",
+		"tenant":                     "acme",
+	}}
+
+	body, got, err := s.encodeBody([]byte("hello"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != "hello" || len(got.MessageAttributes) != 1 || got.MessageAttributes["tenant"] != "acme" {
+		t.Errorf("encodeBody = %q, %v", body, got.MessageAttributes)
+	}
+	if len(opts.MessageAttributes) != 3 {
+		t.Errorf("encodeBody changed the caller's options: %v", opts.MessageAttributes)
+	}
+}
+
+func TestDecodeCorruptCompressedBody(t *testing.T) {
+	s := &SQSRequest{}
+	attrs := []MessageAttribute{{ContentEncodingAttribute, "String", gzipEncoding}}
+
+	for _, body := range []string{"not base64!", "aGVsbG8="} {
+		if _, err := s.decodeBody(body, attrs); err == nil {
+			t.Errorf("decodeBody(%q) succeeded", body)
+		}
+	}
+}
@@ -0,0 +1,485 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Handler processes a single message. Returning nil acks the message;
+// returning an error hands it back to the queue, or to the consumer's
+// dead-letter queue once it has been received DeadLetterAfter times. Use an
+// OutcomeHandler for finer control.
+type Handler func(ctx context.Context, m *RecvMessageResponse) error
+
+// Consumer long-polls a queue and dispatches every message it receives to
+// OutcomeHandler, or Handler if that is not set, running up to Concurrency
+// handlers at once.
+type Consumer struct {
+	Queue          SQSClient
+	Handler        Handler
+	OutcomeHandler OutcomeHandler
+
+	Concurrency int // defaults to 1
+	MaxMessages int // per receive, defaults to min(Concurrency, 10)
+	WaitSeconds int // long-poll wait, defaults to the queue's or 20
+
+	// Tuner, if set, picks the number of messages and the wait of each
+	// receive instead of MaxMessages and WaitSeconds.
+	Tuner *PollTuner
+
+	// IdleBackoff, if set, delays the next poll after each consecutive
+	// empty receive, growing up to IdleBackoff.Max. Polling returns to full
+	// speed as soon as a receive yields messages.
+	IdleBackoff *Backoff
+
+	// Budget, if set, lowers concurrency and eventually pauses polling
+	// while handlers fail more often than it allows.
+	Budget *ErrorBudget
+
+	// Heartbeat, if non-zero, keeps messages invisible while their handler
+	// runs by extending the visibility timeout at this interval. Should it
+	// give up, OnError is told once the handler returns.
+	Heartbeat time.Duration
+
+	// DeadLetter, if set, receives a copy of every message whose handler
+	// returns a DeadLetter outcome, after which the original is deleted.
+	// Messages whose Handler fails are released for redelivery, leaving
+	// them to the queue's redrive policy, unless DeadLetterAfter is
+	// positive: then they are dead-lettered on the receive that reaches
+	// it.
+	DeadLetter      SQSClient
+	DeadLetterAfter int
+
+	// Quarantine, if set, receives messages whose handler returns a
+	// Quarantine outcome.
+	Quarantine SQSClient
+
+	// Audit, if set, receives a record for every processed message,
+	// tagged with WorkerId.
+	Audit    AuditSink
+	WorkerId string
+
+	// Dedup, if set, skips messages whose DedupKeyAttribute was already
+	// handled: they are deleted without reaching the handler. See
+	// SendOnce.
+	Dedup *DedupFilter
+
+	// Watermarks, if set, is told the SentTimestamp of every message that
+	// is deleted after successful handling, and runs alongside Run.
+	Watermarks *WatermarkReporter
+
+	// OnError is called with errors that do not belong to a handler, such
+	// as failed receives or deletes. It may be called concurrently.
+	OnError func(err error)
+
+	once    sync.Once
+	stop    chan struct{}
+	stopped sync.Once
+	running sync.WaitGroup
+}
+
+const (
+	OutcomeDeleted      = "deleted"
+	OutcomeReleased     = "released"
+	OutcomeDeadLettered = "dead-lettered"
+	OutcomeFailed       = "failed"
+	OutcomeRescheduled  = "rescheduled"
+	OutcomeQuarantined  = "quarantined"
+	OutcomeDuplicate    = "duplicate"
+)
+
+func NewConsumer(queue SQSClient, handler Handler) *Consumer {
+	return &Consumer{
+		Queue:   queue,
+		Handler: handler,
+	}
+}
+
+func NewOutcomeConsumer(queue SQSClient, handler OutcomeHandler) *Consumer {
+	return &Consumer{
+		Queue:          queue,
+		OutcomeHandler: handler,
+	}
+}
+
+func (c *Consumer) init() {
+	c.stop = make(chan struct{})
+
+	if c.Concurrency < 1 {
+		c.Concurrency = 1
+	}
+	if c.MaxMessages < 1 {
+		c.MaxMessages = c.Concurrency
+	}
+	if c.MaxMessages > maxBatchEntries {
+		c.MaxMessages = maxBatchEntries
+	}
+	if c.WaitSeconds == 0 {
+		c.WaitSeconds = settings(c.Queue).WaitSeconds
+	}
+	if c.WaitSeconds == 0 {
+		c.WaitSeconds = 20
+	}
+}
+
+// Run polls the queue until ctx is cancelled or Stop is called, then waits
+// for in-flight handlers to finish. Messages that were received but not yet
+// handed to a handler are released back to the queue.
+func (c *Consumer) Run(ctx context.Context) error {
+	c.once.Do(c.init)
+
+	c.running.Add(1)
+	defer c.running.Done()
+
+	pollCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go func() {
+		select {
+		case <-c.stop:
+			cancel()
+		case <-pollCtx.Done():
+		}
+	}()
+
+	jobs := make(chan *RecvMessageResponse)
+	var workers sync.WaitGroup
+	for i := 0; i < c.Concurrency; i++ {
+		workers.Add(1)
+		go func(slot int) {
+			defer workers.Done()
+			for c.Budget.wait(pollCtx, slot, c.Concurrency) {
+				m, ok := <-jobs
+				if !ok {
+					return
+				}
+				c.process(ctx, m)
+			}
+		}(i)
+	}
+
+	var reporting sync.WaitGroup
+	reportCtx, stopReporting := context.WithCancel(context.Background())
+	defer stopReporting()
+
+	if c.Watermarks != nil {
+		reporting.Add(1)
+		go func() {
+			defer reporting.Done()
+			if err := c.Watermarks.Run(reportCtx); err != nil {
+				c.reportError(err)
+			}
+		}()
+	}
+
+	c.poll(pollCtx, jobs)
+
+	close(jobs)
+	workers.Wait()
+
+	stopReporting()
+	reporting.Wait()
+
+	return ctx.Err()
+}
+
+func (c *Consumer) poll(ctx context.Context, jobs chan<- *RecvMessageResponse) {
+	empty := 0
+	for c.Budget.wait(ctx, 0, c.Concurrency) {
+		max, wait := c.MaxMessages, c.WaitSeconds
+		if c.Tuner != nil {
+			max, wait = c.Tuner.Current()
+		}
+		if n := c.Budget.Concurrency(c.Concurrency); n > 0 && max > n {
+			max = n
+		}
+
+		msgs, err := c.Queue.ReceiveSQSMessagesContext(ctx, max, wait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			c.reportError(err)
+			sleep(ctx, time.Second)
+			continue
+		}
+
+		if c.Tuner != nil {
+			c.Tuner.Observe(len(msgs))
+		}
+
+		if len(msgs) == 0 {
+			empty++
+			if c.IdleBackoff != nil {
+				sleep(ctx, c.IdleBackoff.Delay(empty))
+			}
+			continue
+		}
+		empty = 0
+
+		for i, m := range msgs {
+			select {
+			case jobs <- m:
+			case <-ctx.Done():
+				c.release(msgs[i:])
+				return
+			}
+		}
+	}
+}
+
+func (c *Consumer) release(msgs []*RecvMessageResponse) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.Queue.ReleaseAll(ctx, msgs); err != nil {
+		c.reportError(err)
+	}
+}
+
+// Stop stops polling and blocks until Run has drained in-flight work.
+func (c *Consumer) Stop() {
+	c.once.Do(c.init)
+	c.stopped.Do(func() { close(c.stop) })
+	c.running.Wait()
+}
+
+func (c *Consumer) process(ctx context.Context, m *RecvMessageResponse) {
+	start := time.Now()
+
+	// Messages scheduled with SendSQSMessageAt may arrive early; they go
+	// back on the queue without reaching the handler. If that fails the
+	// message is left to reappear once its visibility timeout expires.
+	if _, ok := m.NotBefore(); ok {
+		rescheduled, err := rescheduleIfEarly(c.Queue, m)
+		if err != nil {
+			c.reportError(err)
+		}
+		if rescheduled || err != nil {
+			c.audit(start, m, OutcomeRescheduled, err)
+			return
+		}
+	}
+
+	key := m.MessageAttribute(DedupKeyAttribute)
+	if c.Dedup == nil {
+		key = ""
+	}
+	if key != "" {
+		// A store that cannot be reached lets the message through, as
+		// it would be without a filter.
+		state, err := c.Dedup.store().Begin(ctx, key)
+		if err != nil {
+			c.reportError(err)
+			key = ""
+		} else if state != DedupNew {
+			c.skipDuplicate(start, m, state)
+			return
+		}
+	}
+
+	var hb *Heartbeat
+	if c.Heartbeat > 0 {
+		hb = keepMessageVisible(ctx, c.Queue, m.ReceiptHandle, c.Heartbeat)
+	}
+
+	hctx, span := c.startSpan(ContextWithMessage(ctx, m), m)
+	o, herr := c.handle(hctx, m)
+
+	if hb != nil {
+		if err := hb.Stop(); err != nil {
+			c.reportError(err)
+		}
+	}
+
+	result, err := c.settle(m, o)
+	if err != nil {
+		c.reportError(err)
+	}
+	c.Budget.observe(o, herr, c.Concurrency)
+	if span != nil {
+		span.SetAttribute("messaging.sqs.outcome", result)
+		endSpan(span, herr)
+	}
+	if key != "" {
+		handled := result == OutcomeDeleted || result == OutcomeDeadLettered || result == OutcomeQuarantined
+		if err := c.Dedup.store().End(context.Background(), key, handled); err != nil {
+			c.reportError(err)
+		}
+	}
+	if result == OutcomeDeleted && c.Watermarks != nil {
+		c.Watermarks.Observe(queueURI(c.Queue), m.SentTimestamp())
+	}
+
+	if herr == nil && o.Reason != "" {
+		herr = errors.New(o.Reason)
+	}
+	c.audit(start, m, result, herr)
+}
+
+// startSpan starts the span of handling m, as a child of the trace the
+// producer of m sent it in.
+func (c *Consumer) startSpan(ctx context.Context, m *RecvMessageResponse) (context.Context, Span) {
+	t := settings(c.Queue).Tracer
+	if t == nil {
+		return ctx, nil
+	}
+
+	ctx, span := t.Start(t.Extract(ctx, m.MessageAttribute(TraceParentAttribute)), "SQS process")
+	span.SetAttribute("messaging.message.id", m.MessageId)
+	span.SetAttribute("messaging.destination.name", queueName(c.Queue))
+
+	return ctx, span
+}
+
+// skipDuplicate deletes a copy of a message that was already handled, or
+// puts it back for later while another copy is being handled, in case
+// that fails.
+func (c *Consumer) skipDuplicate(start time.Time, m *RecvMessageResponse, state DedupState) {
+	if state == DedupRunning {
+		result, err := c.settle(m, Retry(dedupRetryDelay))
+		if err != nil {
+			c.reportError(err)
+		}
+		c.audit(start, m, result, err)
+		return
+	}
+
+	_, err := c.Queue.DeleteSQSMessage(m.ReceiptHandle)
+	if err != nil {
+		c.reportError(err)
+	}
+	c.audit(start, m, OutcomeDuplicate, err)
+}
+
+func (c *Consumer) audit(start time.Time, m *RecvMessageResponse, result string, err error) {
+	if c.Audit == nil {
+		return
+	}
+
+	rec := AuditRecord{
+		Time:         start,
+		MessageId:    m.MessageId,
+		ReceiveCount: m.ReceiveCount(),
+		Outcome:      result,
+		Duration:     time.Since(start),
+		WorkerId:     c.WorkerId,
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+
+	if err = c.Audit.Record(rec); err != nil {
+		c.reportError(err)
+	}
+}
+
+// handle runs the handler and returns its outcome, along with the error
+// behind it if the handler failed or panicked.
+func (c *Consumer) handle(ctx context.Context, m *RecvMessageResponse) (o Outcome, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("Handler panicked on message %s: %v", m.MessageId, r)
+			o = c.failure(m, err)
+		}
+	}()
+
+	if c.OutcomeHandler != nil {
+		return c.OutcomeHandler(ctx, m), nil
+	}
+
+	if err = c.Handler(ctx, m); err != nil {
+		return c.failure(m, err), err
+	}
+
+	return Ack, nil
+}
+
+func (c *Consumer) failure(m *RecvMessageResponse, err error) Outcome {
+	if c.DeadLetter != nil && c.DeadLetterAfter > 0 && m.ReceiveCount() >= c.DeadLetterAfter {
+		return DeadLetter(err.Error())
+	}
+
+	return Retry(0)
+}
+
+// settle acts on the outcome of a handled message and reports what was
+// done with it.
+func (c *Consumer) settle(m *RecvMessageResponse, o Outcome) (string, error) {
+	switch {
+	case o.Kind == OutcomeAck:
+		if _, err := c.Queue.DeleteSQSMessage(m.ReceiptHandle); err != nil {
+			return OutcomeFailed, err
+		}
+		return OutcomeDeleted, nil
+
+	case o.Kind == OutcomeDeadLetter && c.DeadLetter != nil:
+		if err := c.forward(c.DeadLetter, m, o.Reason); err != nil {
+			return OutcomeFailed, err
+		}
+		return OutcomeDeadLettered, nil
+
+	case o.Kind == OutcomeQuarantine && c.Quarantine != nil:
+		if err := c.forward(c.Quarantine, m, o.Reason); err != nil {
+			return OutcomeFailed, err
+		}
+		return OutcomeQuarantined, nil
+
+	case o.Kind == OutcomeQuarantine:
+		if _, err := c.Queue.ChangeMessageVisibility(m.ReceiptHandle, maxVisibilityTimeout); err != nil {
+			return OutcomeFailed, err
+		}
+		return OutcomeQuarantined, nil
+	}
+
+	if _, err := c.Queue.ChangeMessageVisibility(m.ReceiptHandle, o.visibilitySeconds()); err != nil {
+		return OutcomeFailed, err
+	}
+
+	return OutcomeReleased, nil
+}
+
+// forward sends a copy of m, with its message attributes and the given
+// reason, to another queue and deletes the original. The reason is dropped
+// if m has no room for another attribute.
+func (c *Consumer) forward(dst SQSClient, m *RecvMessageResponse, reason string) error {
+	opts := forwardOptions(m, dst)
+	if reason != "" && len(opts.MessageAttributes) < maxMessageAttributes {
+		opts.MessageAttributes[DeadLetterReasonAttribute] = reason
+	}
+
+	if _, err := dst.SendSQSMessageWithOptions([]byte(m.MessageBody), opts); err != nil {
+		return err
+	}
+
+	_, err := c.Queue.DeleteSQSMessage(m.ReceiptHandle)
+	return err
+}
+
+// forwardOptions returns the options that send a copy of a received
+// message to dst: its attributes and, between FIFO queues, its group and
+// deduplication ids.
+func forwardOptions(m *RecvMessageResponse, dst SQSClient) *SendOptions {
+	attrs := make(map[string]string, len(m.MessageAttributes)+1)
+	for _, attr := range m.MessageAttributes {
+		attrs[attr.Name] = attr.StringValue
+	}
+
+	opts := &SendOptions{MessageAttributes: attrs}
+	if strings.HasSuffix(queueName(dst), ".fifo") {
+		opts.MessageGroupId = m.MessageGroupId()
+		opts.MessageDeduplicationId = m.Attribute("MessageDeduplicationId")
+	}
+
+	return opts
+}
+
+func (c *Consumer) reportError(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+	}
+}
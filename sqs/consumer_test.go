@@ -0,0 +1,284 @@
+package sqs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+	"github.com/neurodrone/aws-sqs/sqs/sqstest"
+)
+
+// auditLog collects the records of a Consumer.
+type auditLog struct {
+	mu      sync.Mutex
+	records []sqs.AuditRecord
+	first   chan struct{}
+}
+
+func newAuditLog() *auditLog {
+	return &auditLog{first: make(chan struct{})}
+}
+
+func (al *auditLog) Record(rec sqs.AuditRecord) error {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	al.records = append(al.records, rec)
+	if len(al.records) == 1 {
+		close(al.first)
+	}
+
+	return nil
+}
+
+func (al *auditLog) outcome(i int) string {
+	al.mu.Lock()
+	defer al.mu.Unlock()
+
+	if i >= len(al.records) {
+		return ""
+	}
+
+	return al.records[i].Outcome
+}
+
+func createQueue(t *testing.T, f *sqstest.Fake, name string) *sqstest.Queue {
+	t.Helper()
+
+	q := f.Queue(name)
+	if _, err := q.CreateQueue(name, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return q
+}
+
+// messages returns the number of messages on q, visible or not.
+func messages(t *testing.T, q *sqstest.Queue) int {
+	t.Helper()
+
+	qar, err := q.GetQueueAttributes(sqs.AttrAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a := qar.Attributes
+	return a.ApproximateNumberOfMessages + a.ApproximateNumberOfMessagesNotVisible + a.ApproximateNumberOfMessagesDelayed
+}
+
+// runConsumer runs c until it has settled a message.
+func runConsumer(t *testing.T, c *sqs.Consumer) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+	go func() { done <- c.Run(ctx) }()
+
+	select {
+	case <-c.Audit.(*auditLog).first:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message was handled")
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("Run = %v", err)
+	}
+}
+
+func TestConsumerSettle(t *testing.T) {
+	failure := errors.New("boom")
+
+	tests := []struct {
+		name            string
+		handler         sqs.Handler
+		outcome         sqs.Outcome
+		deadLetter      bool
+		deadLetterAfter int
+		received        int // receives before the consumer's
+		attributes      int // besides tenant
+
+		result  string
+		left    int    // on the queue
+		reason  string // on the dead-letter queue
+		dlqLeft int
+	}{
+		{name: "ack", outcome: sqs.Ack, result: sqs.OutcomeDeleted},
+		{name: "retry", outcome: sqs.Retry(time.Minute), result: sqs.OutcomeReleased, left: 1},
+		{name: "dead letter", outcome: sqs.DeadLetter("bad"), deadLetter: true, result: sqs.OutcomeDeadLettered, reason: "bad", dlqLeft: 1},
+		{name: "dead letter at the attribute limit", outcome: sqs.DeadLetter("bad"), deadLetter: true, attributes: 9, result: sqs.OutcomeDeadLettered, dlqLeft: 1},
+		{name: "dead letter without a queue", outcome: sqs.DeadLetter("bad"), result: sqs.OutcomeReleased, left: 1},
+		{name: "quarantine without a queue", outcome: sqs.Quarantine("odd"), result: sqs.OutcomeQuarantined, left: 1},
+		{name: "handler error", handler: func(context.Context, *sqs.RecvMessageResponse) error { return failure }, deadLetter: true, result: sqs.OutcomeReleased, left: 1},
+		{name: "handler error below receive count", handler: func(context.Context, *sqs.RecvMessageResponse) error { return failure }, deadLetter: true, deadLetterAfter: 5, received: 1, result: sqs.OutcomeReleased, left: 1},
+		{name: "handler error at receive count", handler: func(context.Context, *sqs.RecvMessageResponse) error { return failure }, deadLetter: true, deadLetterAfter: 3, received: 2, result: sqs.OutcomeDeadLettered, reason: "boom", dlqLeft: 1},
+		{name: "handler panic", handler: func(context.Context, *sqs.RecvMessageResponse) error { panic("oops") }, result: sqs.OutcomeReleased, left: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqstest.New()
+			q := createQueue(t, f, "orders")
+			dlq := createQueue(t, f, "orders-dlq")
+
+			attrs := map[string]string{"tenant": "acme"}
+			for i := 0; i < tt.attributes; i++ {
+				attrs[fmt.Sprint("a", i)] = "x"
+			}
+			if _, err := q.SendSQSMessageWithOptions([]byte("hello"), &sqs.SendOptions{MessageAttributes: attrs}); err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < tt.received; i++ {
+				msgs, err := q.ReceiveSQSMessages(1, 0)
+				if err != nil || len(msgs) != 1 {
+					t.Fatalf("receive = %v, %v", msgs, err)
+				}
+				if _, err = q.ChangeMessageVisibility(msgs[0].ReceiptHandle, 0); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Messages released by the first call may be received
+			// again before the consumer stops; later calls leave them
+			// on the queue, so that its state reflects the first.
+			var calls int
+			c := sqs.NewOutcomeConsumer(q, func(ctx context.Context, m *sqs.RecvMessageResponse) sqs.Outcome {
+				calls++
+				if calls > 1 {
+					return sqs.Retry(time.Hour)
+				}
+				return tt.outcome
+			})
+			if tt.handler != nil {
+				c = sqs.NewConsumer(q, func(ctx context.Context, m *sqs.RecvMessageResponse) error {
+					calls++
+					if calls > 1 {
+						<-ctx.Done()
+						return ctx.Err()
+					}
+					return tt.handler(ctx, m)
+				})
+			}
+			if tt.deadLetter {
+				c.DeadLetter = dlq
+			}
+			c.DeadLetterAfter = tt.deadLetterAfter
+			c.Audit = newAuditLog()
+			c.WaitSeconds = 1
+
+			runConsumer(t, c)
+
+			if got := c.Audit.(*auditLog).outcome(0); got != tt.result {
+				t.Errorf("outcome = %q, want %q", got, tt.result)
+			}
+			if got := messages(t, q); got != tt.left {
+				t.Errorf("%d messages left on the queue, want %d", got, tt.left)
+			}
+			if got := messages(t, dlq); got != tt.dlqLeft {
+				t.Fatalf("%d messages on the dead-letter queue, want %d", got, tt.dlqLeft)
+			}
+
+			if tt.dlqLeft > 0 {
+				msgs, err := dlq.Peek(1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				m := msgs[0]
+				if m.MessageBody != "hello" || m.MessageAttribute("tenant") != "acme" {
+					t.Errorf("dead-lettered %q with tenant %q", m.MessageBody, m.MessageAttribute("tenant"))
+				}
+				if got := m.MessageAttribute(sqs.DeadLetterReasonAttribute); got != tt.reason {
+					t.Errorf("reason = %q, want %q", got, tt.reason)
+				}
+			}
+		})
+	}
+}
+
+func TestConsumerStopDrains(t *testing.T) {
+	f := sqstest.New()
+	q := createQueue(t, f, "orders")
+	for _, body := range []string{"a", "b", "c"} {
+		if _, err := q.SendSQSMessage([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inFlight := func() int {
+		qar, err := q.GetQueueAttributes(sqs.AttrApproximateNumberOfMessagesNotVisible)
+		if err != nil {
+			t.Error(err)
+			return -1
+		}
+		return qar.Attributes.ApproximateNumberOfMessagesNotVisible
+	}
+
+	// The single worker holds on to the first message while the poller
+	// receives the next one, which cannot be dispatched, then waits for
+	// Stop to release it.
+	var handled []string
+	var once sync.Once
+	started := make(chan struct{})
+	c := sqs.NewConsumer(q, func(ctx context.Context, m *sqs.RecvMessageResponse) error {
+		handled = append(handled, m.MessageBody)
+
+		for inFlight() == 1 {
+			time.Sleep(time.Millisecond)
+		}
+		once.Do(func() { close(started) })
+		for inFlight() == 2 {
+			time.Sleep(time.Millisecond)
+		}
+
+		return nil
+	})
+
+	done := make(chan error)
+	go func() { done <- c.Run(context.Background()) }()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message was handled")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop did not return")
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Run = %v", err)
+	}
+
+	if len(handled) != 1 || handled[0] != "a" {
+		t.Errorf("handled %v, want [a]", handled)
+	}
+
+	msgs, err := q.ReceiveSQSMessages(10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for _, m := range msgs {
+		left = append(left, m.MessageBody)
+	}
+	if len(left) != 2 || left[0] != "b" || left[1] != "c" {
+		t.Errorf("after Stop the queue holds %v, want [b c]", left)
+	}
+	if msgs[0].ReceiveCount() != 2 {
+		t.Errorf("b was received %d times, want it released by Stop after one", msgs[0].ReceiveCount())
+	}
+}
@@ -0,0 +1,160 @@
+package sqs
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// RedrivePolicy is the typed form of a queue's RedrivePolicy attribute.
+type RedrivePolicy struct {
+	DeadLetterTargetArn string
+	MaxReceiveCount     int
+}
+
+type redrivePolicyJSON struct {
+	DeadLetterTargetArn string          `json:"deadLetterTargetArn"`
+	MaxReceiveCount     json.RawMessage `json:"maxReceiveCount"`
+}
+
+func (rp RedrivePolicy) MarshalJSON() ([]byte, error) {
+	return json.Marshal(redrivePolicyJSON{
+		rp.DeadLetterTargetArn,
+		json.RawMessage(strconv.Itoa(rp.MaxReceiveCount)),
+	})
+}
+
+// UnmarshalJSON accepts maxReceiveCount both as a number and as a string,
+// since SQS has returned either form.
+func (rp *RedrivePolicy) UnmarshalJSON(data []byte) error {
+	var raw redrivePolicyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	count := string(raw.MaxReceiveCount)
+	if unquoted, err := strconv.Unquote(count); err == nil {
+		count = unquoted
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil {
+		return fmt.Errorf("Invalid maxReceiveCount in redrive policy: %s", raw.MaxReceiveCount)
+	}
+
+	rp.DeadLetterTargetArn = raw.DeadLetterTargetArn
+	rp.MaxReceiveCount = n
+
+	return nil
+}
+
+func (rp RedrivePolicy) Validate() error {
+	if rp.DeadLetterTargetArn == "" {
+		return errors.New("Redrive policy needs a dead-letter target ARN.")
+	}
+	if rp.MaxReceiveCount < 1 || rp.MaxReceiveCount > 1000 {
+		return fmt.Errorf("Redrive policy maxReceiveCount must be between 1 and 1000, got %d.", rp.MaxReceiveCount)
+	}
+
+	return nil
+}
+
+// String returns the policy as the JSON document SQS expects for the
+// RedrivePolicy attribute.
+func (rp RedrivePolicy) String() string {
+	b, _ := json.Marshal(rp)
+	return string(b)
+}
+
+// QueueARN returns the ARN of the queue, as needed to reference it from
+// another queue's redrive policy.
+func (s *SQSRequest) QueueARN() (string, error) {
+	return queueARN(s)
+}
+
+// ConfigureDeadLetterQueue routes messages that have been received more
+// than maxReceiveCount times to the dlq queue.
+func (s *SQSRequest) ConfigureDeadLetterQueue(dlq *SQSRequest, maxReceiveCount int) (*BasicResponse, error) {
+	arn, err := dlq.QueueARN()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.SetRedrivePolicy(RedrivePolicy{arn, maxReceiveCount})
+}
+
+func (s *SQSRequest) SetRedrivePolicy(rp RedrivePolicy) (*BasicResponse, error) {
+	if err := rp.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.SetQueueAttributes(map[string]string{
+		AttrRedrivePolicy: rp.String(),
+	})
+}
+
+type DeadLetterSourceQueuesResponse struct {
+	QueueURLs []string `xml:"ListDeadLetterSourceQueuesResult>QueueUrl"`
+	NextToken string   `xml:"ListDeadLetterSourceQueuesResult>NextToken"`
+	BasicResponse
+}
+
+// ListDeadLetterSourceQueues returns the queues whose redrive policy
+// targets this queue. Like ListQueues it returns at most 1000 queues; use
+// ListDeadLetterSourceQueuesPages to see all of them.
+func (s *SQSRequest) ListDeadLetterSourceQueues() (*DeadLetterSourceQueuesResponse, error) {
+	return s.ListDeadLetterSourceQueuesPage("", 0)
+}
+
+func (s *SQSRequest) ListDeadLetterSourceQueuesPage(nextToken string, maxResults int) (*DeadLetterSourceQueuesResponse, error) {
+	params := map[string]string{
+		"Action": "ListDeadLetterSourceQueues",
+	}
+
+	if nextToken != "" {
+		params["NextToken"] = nextToken
+	}
+	if maxResults > 0 {
+		params["MaxResults"] = strconv.Itoa(maxResults)
+	}
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	dsr := new(DeadLetterSourceQueuesResponse)
+	if err = xml.NewDecoder(reader).Decode(dsr); err != nil {
+		return nil, err
+	}
+
+	return dsr, nil
+}
+
+func (s *SQSRequest) ListDeadLetterSourceQueuesPages(fn func(page *DeadLetterSourceQueuesResponse) bool) error {
+	token := ""
+	for {
+		dsr, err := s.ListDeadLetterSourceQueuesPage(token, maxListResults)
+		if err != nil {
+			return err
+		}
+
+		if !fn(dsr) || dsr.NextToken == "" {
+			return nil
+		}
+		token = dsr.NextToken
+	}
+}
+
+func (s *SQSRequest) ListAllDeadLetterSourceQueues() ([]string, error) {
+	var urls []string
+	err := s.ListDeadLetterSourceQueuesPages(func(page *DeadLetterSourceQueuesResponse) bool {
+		urls = append(urls, page.QueueURLs...)
+		return true
+	})
+
+	return urls, err
+}
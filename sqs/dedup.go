@@ -0,0 +1,150 @@
+package sqs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DedupKeyAttribute carries a key shared by copies of the same logical
+// message, such as those sent by a HedgedSender or by SendOnce.
+const DedupKeyAttribute = "sqs-dedup-key"
+
+// dedupRetryDelay is how long a copy is put back while another copy with
+// the same key is being handled.
+const dedupRetryDelay = 30 * time.Second
+
+// SendOnce sends message at most once per idempotency key, as far as SQS
+// and consumers allow. The key is stamped into DedupKeyAttribute, for
+// consumers with a DedupFilter, and on FIFO queues it also becomes the
+// MessageDeduplicationId, so that SQS drops copies sent within five
+// minutes. An empty key is derived from the message, so that identical
+// payloads are sent once.
+func (s *SQSRequest) SendOnce(ctx context.Context, message []byte, key string, opts *SendOptions) (*SendMessageResponse, error) {
+	if key == "" {
+		sum := sha256.Sum256(message)
+		key = hex.EncodeToString(sum[:])
+	}
+
+	o := opts.withAttribute(DedupKeyAttribute, key)
+
+	if strings.HasSuffix(s.QueueName, ".fifo") && o.MessageDeduplicationId == "" {
+		o.MessageDeduplicationId = key
+		// Keys SQS would reject as deduplication ids are hashed instead.
+		if validateFifoId("MessageDeduplicationId", key) != nil {
+			sum := sha256.Sum256([]byte(key))
+			o.MessageDeduplicationId = hex.EncodeToString(sum[:])
+		}
+	}
+
+	return s.SendSQSMessageContext(ctx, message, o)
+}
+
+// DedupState tells whether a dedup key is new to a DedupStore.
+type DedupState int
+
+const (
+	DedupNew     DedupState = iota // claimed for handling
+	DedupRunning                   // being handled elsewhere
+	DedupDone                      // already handled
+)
+
+// DedupStore keeps the dedup keys of messages being and having been
+// handled. A store shared by consumer processes, backed for instance by
+// Redis or DynamoDB, catches duplicates delivered to different processes;
+// it should let claims expire, so that a crashed process does not hold on
+// to a key for good.
+type DedupStore interface {
+	// Begin claims key for handling, unless it is being or has been
+	// handled.
+	Begin(ctx context.Context, key string) (DedupState, error)
+
+	// End releases a key claimed by Begin, remembering it if the message
+	// was handled for good.
+	End(ctx context.Context, key string, handled bool) error
+}
+
+// DedupFilter lets a Consumer handle only one of the messages sharing a
+// DedupKeyAttribute. Keys are kept in Store or, by default, in memory for
+// TTL after the message was handled, in which case duplicates must reach
+// the same consumer process within that time to be caught.
+type DedupFilter struct {
+	TTL   time.Duration // of the in-memory store, defaults to 5 minutes
+	Store DedupStore
+
+	once   sync.Once
+	memory *MemoryDedupStore
+}
+
+func (df *DedupFilter) store() DedupStore {
+	if df.Store != nil {
+		return df.Store
+	}
+
+	df.once.Do(func() { df.memory = &MemoryDedupStore{TTL: df.TTL} })
+
+	return df.memory
+}
+
+// MemoryDedupStore is a DedupStore for a single process.
+type MemoryDedupStore struct {
+	TTL time.Duration // defaults to 5 minutes
+
+	mu        sync.Mutex
+	done      map[string]time.Time
+	running   map[string]bool
+	lastSweep time.Time
+}
+
+func (ms *MemoryDedupStore) ttl() time.Duration {
+	if ms.TTL <= 0 {
+		return 5 * time.Minute
+	}
+
+	return ms.TTL
+}
+
+func (ms *MemoryDedupStore) Begin(ctx context.Context, key string) (DedupState, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.done == nil {
+		ms.done = make(map[string]time.Time)
+		ms.running = make(map[string]bool)
+	}
+
+	now := time.Now()
+	if now.Sub(ms.lastSweep) > ms.ttl() {
+		for k, at := range ms.done {
+			if now.Sub(at) > ms.ttl() {
+				delete(ms.done, k)
+			}
+		}
+		ms.lastSweep = now
+	}
+
+	if at, ok := ms.done[key]; ok && now.Sub(at) <= ms.ttl() {
+		return DedupDone, nil
+	}
+	if ms.running[key] {
+		return DedupRunning, nil
+	}
+
+	ms.running[key] = true
+	return DedupNew, nil
+}
+
+func (ms *MemoryDedupStore) End(ctx context.Context, key string, handled bool) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	delete(ms.running, key)
+	if handled && ms.done != nil {
+		ms.done[key] = time.Now()
+	}
+
+	return nil
+}
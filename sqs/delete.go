@@ -0,0 +1,101 @@
+package sqs
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+)
+
+type DeleteMessageBatchResponse struct {
+	Successful []string           `xml:"DeleteMessageBatchResult>DeleteMessageBatchResultEntry>Id"`
+	Failed     []BatchResultError `xml:"DeleteMessageBatchResult>BatchResultErrorEntry"`
+	BasicResponse
+}
+
+// DeleteSQSMessageBatch deletes up to ten messages in a single request,
+// along with the external payloads of those that were deleted. The ids in
+// the response are the indexes of the handles.
+func (s *SQSRequest) DeleteSQSMessageBatch(handles []string) (*DeleteMessageBatchResponse, error) {
+	return s.deleteMessageBatch(context.Background(), handles)
+}
+
+func (s *SQSRequest) deleteMessageBatch(ctx context.Context, handles []string) (*DeleteMessageBatchResponse, error) {
+	if len(handles) == 0 || len(handles) > maxBatchEntries {
+		return nil, fmt.Errorf("A batch needs between 1 and %d entries, got %d.", maxBatchEntries, len(handles))
+	}
+
+	params := map[string]string{
+		"Action": "DeleteMessageBatch",
+	}
+
+	payloads := make(map[string]string)
+	for i, handle := range handles {
+		handle, payload := splitReceiptHandle(handle)
+		if payload != "" {
+			payloads[strconv.Itoa(i)] = payload
+		}
+
+		prefix := fmt.Sprintf("DeleteMessageBatchRequestEntry.%d.", i+1)
+		params[prefix+"Id"] = strconv.Itoa(i)
+		params[prefix+"ReceiptHandle"] = handle
+	}
+
+	s.observeBatch("DeleteMessageBatch", len(handles))
+
+	reader, err := s.makeSQSQueueRequestContext(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	dmr := new(DeleteMessageBatchResponse)
+	if err = xml.NewDecoder(reader).Decode(dmr); err != nil {
+		return nil, err
+	}
+
+	for _, id := range dmr.Successful {
+		if payload, ok := payloads[id]; ok {
+			if err = s.deletePayload(ctx, payload); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return dmr, nil
+}
+
+// DeleteAll deletes the messages with the given receipt handles, batching
+// the calls to DeleteMessage. Entries rejected by SQS are reported through
+// a BatchError whose ids are the indexes of the corresponding handles.
+func (s *SQSRequest) DeleteAll(ctx context.Context, handles []string) error {
+	var failed BatchError
+
+	for start := 0; start < len(handles); start += maxBatchEntries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := start + maxBatchEntries
+		if end > len(handles) {
+			end = len(handles)
+		}
+
+		dmr, err := s.deleteMessageBatch(ctx, handles[start:end])
+		if err != nil {
+			return err
+		}
+
+		for _, f := range dmr.Failed {
+			idx, _ := strconv.Atoi(f.Id)
+			f.Id = strconv.Itoa(start + idx)
+			failed = append(failed, f)
+		}
+	}
+
+	if len(failed) > 0 {
+		return failed
+	}
+
+	return nil
+}
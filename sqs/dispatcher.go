@@ -0,0 +1,77 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// TypeAttribute is the message attribute MessageType looks at first.
+const TypeAttribute = "type"
+
+// MessageType returns the type of a message: its TypeAttribute message
+// attribute if set, otherwise the "type" field of a JSON envelope such as
+// a structured-mode CloudEvent.
+func MessageType(m *RecvMessageResponse) string {
+	if typ := m.MessageAttribute(TypeAttribute); typ != "" {
+		return typ
+	}
+
+	var envelope struct {
+		Type string `json:"type"`
+	}
+	json.Unmarshal([]byte(m.MessageBody), &envelope)
+
+	return envelope.Type
+}
+
+// Dispatcher routes messages to the handler registered for their type. Its
+// Handle method is itself a Handler, so a Dispatcher can be passed straight
+// to a Consumer.
+type Dispatcher struct {
+	// TypeOf extracts the type of a message. Defaults to MessageType.
+	TypeOf func(m *RecvMessageResponse) string
+
+	// Default handles messages of unregistered types. Without it such
+	// messages fail.
+	Default Handler
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{}
+}
+
+func (d *Dispatcher) Register(messageType string, h Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.handlers == nil {
+		d.handlers = make(map[string]Handler)
+	}
+	d.handlers[messageType] = h
+}
+
+func (d *Dispatcher) Handle(ctx context.Context, m *RecvMessageResponse) error {
+	typeOf := d.TypeOf
+	if typeOf == nil {
+		typeOf = MessageType
+	}
+	typ := typeOf(m)
+
+	d.mu.RLock()
+	h, ok := d.handlers[typ]
+	d.mu.RUnlock()
+
+	if !ok {
+		h = d.Default
+	}
+	if h == nil {
+		return fmt.Errorf("No handler registered for message type %q.", typ)
+	}
+
+	return h(ctx, m)
+}
@@ -0,0 +1,39 @@
+package sqs
+
+import (
+	"context"
+)
+
+// DrainAvailable receives the queue's current backlog with back-to-back
+// short polls, stopping at the first empty receive or once max messages
+// have been collected. A max of zero or less collects until the queue
+// looks empty.
+//
+// Short polls sample only a subset of the SQS servers, so an empty receive
+// does not guarantee the queue is empty; DrainAvailable is meant for batch
+// jobs that take what is there and exit. The messages are hidden for the
+// queue's visibility timeout and must be deleted or released by the
+// caller. On error, the messages received so far are returned along with
+// it.
+func (s *SQSRequest) DrainAvailable(ctx context.Context, max int) ([]*RecvMessageResponse, error) {
+	var all []*RecvMessageResponse
+
+	for max <= 0 || len(all) < max {
+		n := maxBatchEntries
+		if max > 0 && max-len(all) < n {
+			n = max - len(all)
+		}
+
+		msgs, err := s.ReceiveSQSMessagesContext(ctx, n, 0)
+		if err != nil {
+			return all, err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+
+		all = append(all, msgs...)
+	}
+
+	return all, nil
+}
@@ -0,0 +1,101 @@
+package sqs
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDSN returns a client for the queue described by a DSN of the form
+//
+//	sqs://[key:secret@]region/account/queue[?param=value&...]
+//
+// such as sqs://us-east-1/123456789012/orders?wait=20s&visibility=60s.
+// The secret must be URL-escaped. Parameters are:
+//
+//	wait        long-poll wait, see SQSRequest.WaitSeconds
+//	visibility  visibility timeout of received messages
+//	endpoint    base URL of the service, see SQSRequest.Endpoint
+//	readonly    true to restrict the client to read-only actions
+//	protocol    json or query, see SQSRequest.Protocol
+//
+// Durations are given as Go durations or whole seconds.
+func ParseDSN(dsn string) (*SQSRequest, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "sqs" {
+		return nil, fmt.Errorf("Invalid DSN scheme %q, expected sqs.", u.Scheme)
+	}
+
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Host == "" || len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("Invalid DSN %q, expected sqs://region/account/queue.", u.Redacted())
+	}
+
+	s := &SQSRequest{
+		RegionId:  u.Host,
+		UUID:      parts[0],
+		QueueName: parts[1],
+	}
+	if u.User != nil {
+		s.AWSAccessKey = u.User.Username()
+		s.AWSSecret, _ = u.User.Password()
+	}
+
+	for name, values := range u.Query() {
+		value := values[len(values)-1]
+
+		switch name {
+		case "wait":
+			s.WaitSeconds, err = dsnSeconds(name, value, 20)
+		case "visibility":
+			s.VisibilityTimeout, err = dsnSeconds(name, value, maxVisibilityTimeout)
+		case "endpoint":
+			e, perr := url.Parse(value)
+			if perr != nil || e.Scheme == "" || e.Host == "" {
+				err = fmt.Errorf("Invalid DSN endpoint %q.", value)
+			}
+			s.Endpoint = value
+		case "readonly":
+			s.ReadOnly, err = strconv.ParseBool(value)
+			if err != nil {
+				err = fmt.Errorf("DSN parameter readonly must be true or false, got %q.", value)
+			}
+		case "protocol":
+			s.Protocol = Protocol(value)
+			if s.Protocol != ProtocolJSON && s.Protocol != ProtocolQuery {
+				err = fmt.Errorf("DSN parameter protocol must be json or query, got %q.", value)
+			}
+		default:
+			err = fmt.Errorf("Unknown DSN parameter %s.", name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// dsnSeconds parses a duration parameter as whole seconds, from 0 to max.
+func dsnSeconds(name, value string, max int) (int, error) {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		d, derr := time.ParseDuration(value)
+		if derr != nil || d%time.Second != 0 {
+			return 0, fmt.Errorf("DSN parameter %s must be a whole number of seconds, got %q.", name, value)
+		}
+		n = int(d / time.Second)
+	}
+
+	if n < 0 || n > max {
+		return 0, fmt.Errorf("DSN parameter %s must be between 0 and %ds, got %q.", name, max, value)
+	}
+
+	return n, nil
+}
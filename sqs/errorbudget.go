@@ -0,0 +1,242 @@
+package sqs
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ErrorBudget degrades a Consumer while its handlers keep failing, so that
+// a broken dependency does not churn the whole queue into the dead-letter
+// queue. Once more than Threshold of the outcomes seen in a window are
+// failures, the consumer's concurrency is halved; once it is down to a
+// single handler, polling is paused for Pause instead. Each window with
+// failures within budget doubles concurrency again, up to the consumer's
+// own.
+type ErrorBudget struct {
+	// Threshold is the share of failed outcomes, between 0 and 1, above
+	// which the budget trips. Defaults to 0.5.
+	Threshold float64
+
+	// MinSamples is the number of outcomes a window needs before the
+	// budget may trip. Defaults to 10.
+	MinSamples int
+
+	Window time.Duration // defaults to 1 minute
+	Pause  time.Duration // defaults to 30 seconds
+
+	// IsFailure decides which outcomes count against the budget. By
+	// default those are handler errors and Retry and DeadLetter outcomes.
+	IsFailure func(o Outcome, err error) bool
+
+	// OnChange, if set, is called with the new concurrency whenever it
+	// changes, with 0 while polling is paused.
+	OnChange func(concurrency int)
+
+	mu          sync.Mutex
+	level       int // concurrency is halved this many times
+	total       int
+	failures    int
+	windowStart time.Time
+	pausedUntil time.Time
+	changed     chan struct{}
+}
+
+func (b *ErrorBudget) threshold() float64 {
+	if b.Threshold <= 0 || b.Threshold > 1 {
+		return 0.5
+	}
+
+	return b.Threshold
+}
+
+func (b *ErrorBudget) minSamples() int {
+	if b.MinSamples <= 0 {
+		return 10
+	}
+
+	return b.MinSamples
+}
+
+func (b *ErrorBudget) window() time.Duration {
+	if b.Window <= 0 {
+		return time.Minute
+	}
+
+	return b.Window
+}
+
+func (b *ErrorBudget) pause() time.Duration {
+	if b.Pause <= 0 {
+		return 30 * time.Second
+	}
+
+	return b.Pause
+}
+
+func (b *ErrorBudget) isFailure(o Outcome, err error) bool {
+	if b.IsFailure != nil {
+		return b.IsFailure(o, err)
+	}
+
+	return err != nil || o.Kind == OutcomeRetry || o.Kind == OutcomeDeadLetter
+}
+
+// limit returns the concurrency allowed out of max. The caller must hold
+// the lock.
+func (b *ErrorBudget) limit(max int) int {
+	n := max >> uint(b.level)
+	if n < 1 {
+		n = 1
+	}
+
+	return n
+}
+
+// Concurrency returns the number of handlers allowed to run out of max,
+// or 0 while polling is paused.
+func (b *ErrorBudget) Concurrency(max int) int {
+	if b == nil {
+		return max
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if time.Now().Before(b.pausedUntil) {
+		return 0
+	}
+
+	return b.limit(max)
+}
+
+// observe records the outcome of a handled message, adjusting the allowed
+// concurrency out of max at the end of each window or when the budget
+// trips.
+func (b *ErrorBudget) observe(o Outcome, err error, max int) {
+	if b == nil {
+		return
+	}
+
+	failed := b.isFailure(o, err)
+
+	b.mu.Lock()
+
+	now := time.Now()
+	if b.windowStart.IsZero() {
+		b.windowStart = now
+	}
+
+	b.total++
+	if failed {
+		b.failures++
+	}
+
+	before := b.limit(max)
+	paused := false
+	ratio := float64(b.failures) / float64(b.total)
+
+	switch {
+	case b.total >= b.minSamples() && ratio > b.threshold():
+		if before > 1 {
+			b.level++
+		} else {
+			b.pausedUntil = now.Add(b.pause())
+			paused = true
+		}
+		b.reset(now)
+	case now.Sub(b.windowStart) >= b.window():
+		if ratio <= b.threshold() && b.level > 0 {
+			b.level--
+		}
+		b.reset(now)
+	}
+
+	after := b.limit(max)
+	if paused {
+		after = 0
+	}
+
+	if after != before {
+		b.notify()
+	}
+	b.mu.Unlock()
+
+	if after != before && b.OnChange != nil {
+		b.OnChange(after)
+	}
+}
+
+// reset starts a new window. The caller must hold the lock.
+func (b *ErrorBudget) reset(now time.Time) {
+	b.total, b.failures = 0, 0
+	b.windowStart = now
+}
+
+// notify wakes up everyone blocked in wait. The caller must hold the lock.
+func (b *ErrorBudget) notify() {
+	if b.changed != nil {
+		close(b.changed)
+		b.changed = nil
+	}
+}
+
+// wait blocks while polling is paused or while slot, counted from 0, is
+// beyond the allowed concurrency out of max. It returns false if ctx is
+// done first.
+func (b *ErrorBudget) wait(ctx context.Context, slot, max int) bool {
+	if b == nil {
+		return ctx.Err() == nil
+	}
+
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		resumed := !b.pausedUntil.IsZero() && !now.Before(b.pausedUntil)
+		if resumed {
+			b.pausedUntil = time.Time{}
+			b.notify()
+		}
+
+		var pause time.Duration
+		if now.Before(b.pausedUntil) {
+			pause = b.pausedUntil.Sub(now)
+		}
+		limit := b.limit(max)
+		allowed := pause == 0 && slot < limit
+		if b.changed == nil {
+			b.changed = make(chan struct{})
+		}
+		changed := b.changed
+		b.mu.Unlock()
+
+		if resumed && b.OnChange != nil {
+			b.OnChange(limit)
+		}
+		if allowed {
+			return ctx.Err() == nil
+		}
+
+		var (
+			timer *time.Timer
+			timeC <-chan time.Time
+		)
+		if pause > 0 {
+			timer = time.NewTimer(pause)
+			timeC = timer.C
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-timeC:
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// budgetStep is a run of outcomes fed to an ErrorBudget: 'f' for a handler
+// error, 'r' for a Retry outcome and 'o' for an Ack. aged ends the current
+// window first.
+type budgetStep struct {
+	outcomes string
+	aged     bool
+}
+
+func TestErrorBudget(t *testing.T) {
+	onlyErrors := func(o Outcome, err error) bool { return err != nil }
+
+	tests := []struct {
+		name      string
+		isFailure func(Outcome, error) bool
+		steps     []budgetStep
+		want      []int // concurrency out of 8 after each step
+		changes   []int
+	}{
+		{name: "below MinSamples", steps: []budgetStep{{outcomes: "fff"}}, want: []int{8}},
+		{name: "at the threshold", steps: []budgetStep{{outcomes: "ffoo"}}, want: []int{8}},
+		{name: "trips", steps: []budgetStep{{outcomes: "offf"}}, want: []int{4}, changes: []int{4}},
+		{
+			name:    "halves down to one, then pauses",
+			steps:   []budgetStep{{outcomes: "ffff"}, {outcomes: "ffff"}, {outcomes: "ffff"}, {outcomes: "ffff"}},
+			want:    []int{4, 2, 1, 0},
+			changes: []int{4, 2, 1, 0},
+		},
+		{
+			name:    "recovers a level per window",
+			steps:   []budgetStep{{outcomes: "ffff"}, {outcomes: "ffff"}, {outcomes: "o", aged: true}, {outcomes: "o", aged: true}, {outcomes: "o", aged: true}},
+			want:    []int{4, 2, 4, 8, 8},
+			changes: []int{4, 2, 4, 8},
+		},
+		{
+			name:    "failing window does not recover",
+			steps:   []budgetStep{{outcomes: "ffff"}, {outcomes: "f", aged: true}},
+			want:    []int{4, 4},
+			changes: []int{4},
+		},
+		{name: "retries count by default", steps: []budgetStep{{outcomes: "rrrr"}}, want: []int{4}, changes: []int{4}},
+		{name: "IsFailure", isFailure: onlyErrors, steps: []budgetStep{{outcomes: "rrrr"}, {outcomes: "fffff"}}, want: []int{8, 4}, changes: []int{4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var changes []int
+			b := &ErrorBudget{
+				MinSamples: 4,
+				Window:     time.Hour,
+				Pause:      time.Hour,
+				IsFailure:  tt.isFailure,
+				OnChange:   func(n int) { changes = append(changes, n) },
+			}
+
+			var got []int
+			for _, step := range tt.steps {
+				if step.aged {
+					b.windowStart = time.Now().Add(-time.Hour)
+				}
+				for _, c := range step.outcomes {
+					switch c {
+					case 'f':
+						b.observe(Outcome{}, errors.New("boom"), 8)
+					case 'r':
+						b.observe(Retry(time.Second), nil, 8)
+					case 'o':
+						b.observe(Ack, nil, 8)
+					}
+				}
+				got = append(got, b.Concurrency(8))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("concurrency = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(changes, tt.changes) {
+				t.Errorf("OnChange calls = %v, want %v", changes, tt.changes)
+			}
+		})
+	}
+}
+
+func TestErrorBudgetPause(t *testing.T) {
+	var changes []int
+	b := &ErrorBudget{MinSamples: 1, Pause: time.Hour, OnChange: func(n int) { changes = append(changes, n) }}
+	b.observe(Outcome{}, errors.New("boom"), 1)
+
+	if n := b.Concurrency(1); n != 0 {
+		t.Fatalf("concurrency = %d, want polling paused", n)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if b.wait(ctx, 0, 1) {
+		t.Fatal("wait returned while paused")
+	}
+
+	// Once the pause is over, polling resumes at a single handler.
+	b.mu.Lock()
+	b.pausedUntil = time.Now().Add(-time.Second)
+	b.mu.Unlock()
+	if !b.wait(context.Background(), 0, 1) {
+		t.Fatal("wait failed after the pause")
+	}
+	if n := b.Concurrency(1); n != 1 {
+		t.Errorf("concurrency = %d after the pause, want 1", n)
+	}
+	if !reflect.DeepEqual(changes, []int{0, 1}) {
+		t.Errorf("OnChange calls = %v, want [0 1]", changes)
+	}
+}
+
+func TestNilErrorBudget(t *testing.T) {
+	var b *ErrorBudget
+	b.observe(Outcome{}, errors.New("boom"), 4)
+
+	if n := b.Concurrency(4); n != 4 {
+		t.Errorf("concurrency = %d, want 4", n)
+	}
+	if !b.wait(context.Background(), 3, 4) {
+		t.Error("wait failed without a budget")
+	}
+}
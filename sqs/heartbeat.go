@@ -0,0 +1,147 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxVisibilityTimeout is the longest visibility timeout SQS accepts, in
+// seconds.
+const maxVisibilityTimeout = 43200
+
+var ErrVisibilityLost = errors.New("Visibility timeout could not be extended in time; the message may have been redelivered.")
+
+// Heartbeat keeps an in-flight message invisible to other consumers until
+// it is stopped. See SQSRequest.KeepMessageVisible.
+type Heartbeat struct {
+	c      SQSClient
+	handle string
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// KeepMessageVisible extends the visibility timeout of the message with the
+// given receipt handle every interval until ctx is cancelled or the
+// returned Heartbeat is stopped.
+//
+// Each extension asks for twice the interval, so a single failed or slow
+// call never lets the message become visible. Failed calls are retried on
+// the next tick, unless less than an interval of the last timeout is left:
+// the retry would come too late, so the heartbeat gives up with
+// ErrVisibilityLost, since another consumer may soon hold the message.
+func (s *SQSRequest) KeepMessageVisible(ctx context.Context, handle string, interval time.Duration) *Heartbeat {
+	return keepMessageVisible(ctx, s, handle, interval)
+}
+
+func keepMessageVisible(ctx context.Context, c SQSClient, handle string, interval time.Duration) *Heartbeat {
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	hb := &Heartbeat{
+		c:      c,
+		handle: handle,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+
+	go hb.run(ctx, interval)
+
+	return hb
+}
+
+func (hb *Heartbeat) run(ctx context.Context, interval time.Duration) {
+	defer close(hb.done)
+
+	timeout := 2 * interval
+	seconds := int((timeout + time.Second - 1) / time.Second)
+	if seconds > maxVisibilityTimeout {
+		seconds = maxVisibilityTimeout
+		timeout = maxVisibilityTimeout * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	lastExtended := time.Now()
+	for {
+		err := hb.extend(ctx, seconds)
+		if ctx.Err() != nil {
+			return
+		}
+
+		var er *ErrorResponse
+		switch {
+		case err == nil:
+			lastExtended = time.Now()
+		case errors.As(err, &er) && er.Code == "ReceiptHandleIsInvalid":
+			hb.setErr(err)
+			return
+		case timeout-time.Since(lastExtended) < interval:
+			hb.setErr(fmt.Errorf("%w Last error: %s", ErrVisibilityLost, err))
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// extend sets the visibility timeout of the message, abandoning the call
+// when ctx is cancelled if the client allows it.
+func (hb *Heartbeat) extend(ctx context.Context, seconds int) error {
+	var err error
+	if r, ok := hb.c.(requester); ok {
+		_, err = r.request().changeMessageVisibility(ctx, hb.handle, seconds)
+	} else {
+		_, err = hb.c.ChangeMessageVisibility(hb.handle, seconds)
+	}
+
+	return err
+}
+
+func (hb *Heartbeat) setErr(err error) {
+	hb.mu.Lock()
+	hb.err = err
+	hb.mu.Unlock()
+}
+
+// Done is closed once the heartbeat has stopped, either because it was
+// stopped explicitly or because it could no longer extend the timeout.
+func (hb *Heartbeat) Done() <-chan struct{} {
+	return hb.done
+}
+
+// Err returns the reason the heartbeat gave up, or nil if it is still
+// running or was stopped normally.
+func (hb *Heartbeat) Err() error {
+	hb.mu.Lock()
+	defer hb.mu.Unlock()
+
+	return hb.err
+}
+
+// Stop ends the heartbeat and waits for its goroutine to exit. The message
+// stays invisible until the last requested timeout expires.
+func (hb *Heartbeat) Stop() error {
+	hb.cancel()
+	<-hb.done
+
+	return hb.Err()
+}
+
+// Delete stops the heartbeat and deletes the message from the queue.
+func (hb *Heartbeat) Delete() (*BasicResponse, error) {
+	hb.Stop()
+	return hb.c.DeleteSQSMessage(hb.handle)
+}
@@ -0,0 +1,115 @@
+package sqs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+	"github.com/neurodrone/aws-sqs/sqs/sqstest"
+)
+
+// flakyVisibility is a fake queue on which only the first ok calls to
+// ChangeMessageVisibility succeed.
+type flakyVisibility struct {
+	*sqstest.Queue
+	ok int
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (fv *flakyVisibility) ChangeMessageVisibility(handle string, timeout int) (*sqs.BasicResponse, error) {
+	fv.mu.Lock()
+	fv.calls++
+	n := fv.calls
+	fv.mu.Unlock()
+
+	if n > fv.ok {
+		return nil, errors.New("connection reset")
+	}
+	return fv.Queue.ChangeMessageVisibility(handle, timeout)
+}
+
+func (fv *flakyVisibility) visibilityCalls() int {
+	fv.mu.Lock()
+	defer fv.mu.Unlock()
+
+	return fv.calls
+}
+
+// runHeartbeat handles a single message for hold with a one-second
+// heartbeat, calling during halfway through, and returns the errors the
+// consumer reported.
+func runHeartbeat(t *testing.T, q sqs.SQSClient, hold time.Duration, during func()) []error {
+	t.Helper()
+
+	if _, err := q.SendSQSMessage([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var errs []error
+	c := sqs.NewConsumer(q, func(ctx context.Context, m *sqs.RecvMessageResponse) error {
+		time.Sleep(hold / 2)
+		during()
+		time.Sleep(hold / 2)
+		return nil
+	})
+	c.Heartbeat = time.Second
+	c.WaitSeconds = 1
+	c.Audit = newAuditLog()
+	c.OnError = func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}
+
+	runConsumer(t, c)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	return errs
+}
+
+func TestHeartbeatKeepsMessageInvisible(t *testing.T) {
+	q := createQueue(t, sqstest.New(), "orders")
+	if _, err := q.SetQueueAttributes(map[string]string{sqs.AttrVisibilityTimeout: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Past the queue's visibility timeout, the heartbeat still hides the
+	// message.
+	errs := runHeartbeat(t, q, 3*time.Second, func() {
+		msgs, err := q.ReceiveSQSMessages(1, 0)
+		if err != nil || len(msgs) != 0 {
+			t.Errorf("receive during the handler = %v, %v", msgs, err)
+		}
+	})
+
+	if len(errs) != 0 {
+		t.Errorf("consumer errors: %v", errs)
+	}
+	if n := messages(t, q); n != 0 {
+		t.Errorf("%d messages left after handling", n)
+	}
+}
+
+func TestHeartbeatGivesUpWithAnIntervalLeft(t *testing.T) {
+	fv := &flakyVisibility{Queue: createQueue(t, sqstest.New(), "orders"), ok: 1}
+
+	// The first extension asks for two seconds and the next one, a
+	// second later, fails: a retry would come too late, so the heartbeat
+	// gives up before the handler returns.
+	errs := runHeartbeat(t, fv, 1500*time.Millisecond, func() {})
+
+	if n := fv.visibilityCalls(); n != 2 {
+		t.Errorf("%d visibility changes, want the heartbeat to give up after 2", n)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], sqs.ErrVisibilityLost) {
+		t.Errorf("consumer errors = %v, want ErrVisibilityLost", errs)
+	}
+}
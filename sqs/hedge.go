@@ -0,0 +1,184 @@
+package sqs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HedgedSender sends messages with a second, hedging SendMessage when the
+// first one takes longer than the Percentile of recent send latencies.
+// Both copies carry the same DedupKeyAttribute, so a Consumer with a
+// DedupFilter processes only one of them. Hedging trades extra requests,
+// and duplicate messages, for a shorter tail of publish latency.
+type HedgedSender struct {
+	Queue SQSClient
+
+	// Percentile of recent latencies after which to hedge, defaults to
+	// 0.95. The resulting delay is kept between MinDelay and MaxDelay,
+	// which default to 5ms and one second; MaxDelay is also used until
+	// enough latencies have been observed.
+	Percentile float64
+	MinDelay   time.Duration
+	MaxDelay   time.Duration
+
+	// Window is the number of recent latencies kept, defaults to 100.
+	Window int
+
+	mu        sync.Mutex
+	latencies []time.Duration
+	next      int
+}
+
+// minHedgeSamples is the number of latencies needed before the percentile
+// is trusted.
+const minHedgeSamples = 10
+
+func NewHedgedSender(queue SQSClient) *HedgedSender {
+	return &HedgedSender{Queue: queue}
+}
+
+func (hs *HedgedSender) observe(d time.Duration) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	window := hs.Window
+	if window <= 0 {
+		window = 100
+	}
+
+	if len(hs.latencies) < window {
+		hs.latencies = append(hs.latencies, d)
+		return
+	}
+
+	hs.latencies[hs.next%len(hs.latencies)] = d
+	hs.next++
+}
+
+// HedgeDelay returns how long a send may take before it is hedged.
+func (hs *HedgedSender) HedgeDelay() time.Duration {
+	minDelay, maxDelay := hs.MinDelay, hs.MaxDelay
+	if minDelay <= 0 {
+		minDelay = 5 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = time.Second
+	}
+
+	hs.mu.Lock()
+	if len(hs.latencies) < minHedgeSamples {
+		hs.mu.Unlock()
+		return maxDelay
+	}
+	sorted := make([]time.Duration, len(hs.latencies))
+	copy(sorted, hs.latencies)
+	hs.mu.Unlock()
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	p := hs.Percentile
+	if p <= 0 || p >= 1 {
+		p = 0.95
+	}
+	d := sorted[int(p*float64(len(sorted)-1))]
+
+	if d < minDelay {
+		return minDelay
+	}
+	if d > maxDelay {
+		return maxDelay
+	}
+
+	return d
+}
+
+func newDedupKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+type hedgeResult struct {
+	smr *SendMessageResponse
+	err error
+}
+
+// Send sends message, hedging it if the first attempt is slow, and returns
+// the response of whichever attempt succeeds first. It fails only if every
+// attempt made fails, with the error of the last one. Each attempt has its
+// own context; the one still in flight when Send returns is cancelled, as
+// are both when ctx is.
+func (hs *HedgedSender) Send(ctx context.Context, message []byte, opts *SendOptions) (*SendMessageResponse, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	key, err := newDedupKey()
+	if err != nil {
+		return nil, err
+	}
+
+	o := opts.withAttribute(DedupKeyAttribute, key)
+
+	var cancels []context.CancelFunc
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	results := make(chan hedgeResult, 2)
+	send := func() {
+		attemptCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+
+		go func() {
+			start := time.Now()
+			smr, err := hs.Queue.SendSQSMessageContext(attemptCtx, message, o)
+			if err == nil {
+				hs.observe(time.Since(start))
+			}
+			results <- hedgeResult{smr, err}
+		}()
+	}
+
+	send()
+	pending := 1
+
+	hedge := time.NewTimer(hs.HedgeDelay())
+	defer hedge.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case <-hedge.C:
+			send()
+			pending++
+
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				return r.smr, nil
+			}
+
+			// A failed first attempt is hedged right away.
+			if pending == 0 && hedge.Stop() {
+				send()
+				pending++
+				continue
+			}
+			if pending == 0 {
+				return nil, r.err
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package sqs_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+	"github.com/neurodrone/aws-sqs/sqs/sqstest"
+)
+
+// slowClient sends to a fake queue. Attempts listed in stall block until
+// their context is cancelled, and those in fail fail at once; the others
+// go through.
+type slowClient struct {
+	*sqstest.Queue
+
+	stall, fail map[int]bool
+	cancelled   chan int
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (sc *slowClient) SendSQSMessageContext(ctx context.Context, message []byte, opts *sqs.SendOptions) (*sqs.SendMessageResponse, error) {
+	sc.mu.Lock()
+	n := sc.calls
+	sc.calls++
+	sc.mu.Unlock()
+
+	switch {
+	case sc.stall[n]:
+		<-ctx.Done()
+		sc.cancelled <- n
+		return nil, ctx.Err()
+	case sc.fail[n]:
+		return nil, errors.New("connection reset")
+	}
+
+	return sc.Queue.SendSQSMessageContext(ctx, message, opts)
+}
+
+func (sc *slowClient) attempts() int {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+
+	return sc.calls
+}
+
+func newSlowClient(t *testing.T, stall, fail map[int]bool) *slowClient {
+	return &slowClient{
+		Queue:     createQueue(t, sqstest.New(), "events"),
+		stall:     stall,
+		fail:      fail,
+		cancelled: make(chan int, 2),
+	}
+}
+
+func TestHedgeDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		min, max time.Duration
+		sends    int
+		want     time.Duration
+	}{
+		{"no samples", 0, 0, 0, time.Second},
+		{"too few samples", 0, 0, 9, time.Second},
+		{"fast sends", 0, 0, 10, 5 * time.Millisecond},
+		{"configured maximum", time.Millisecond, 50 * time.Millisecond, 0, 50 * time.Millisecond},
+		{"configured minimum", 20 * time.Millisecond, 50 * time.Millisecond, 20, 20 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hs := sqs.NewHedgedSender(newSlowClient(t, nil, nil))
+			hs.MinDelay, hs.MaxDelay = tt.min, tt.max
+
+			// The fake answers in well under the minimum delay.
+			for i := 0; i < tt.sends; i++ {
+				if _, err := hs.Send(context.Background(), []byte("x"), nil); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := hs.HedgeDelay(); got != tt.want {
+				t.Errorf("HedgeDelay = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHedgedSend(t *testing.T) {
+	tests := []struct {
+		name        string
+		stall, fail map[int]bool
+		attempts    int
+		hedged      bool // after the hedge delay
+		cancelled   []int
+	}{
+		{name: "fast", attempts: 1},
+		{name: "slow first attempt", stall: map[int]bool{0: true}, attempts: 2, hedged: true, cancelled: []int{0}},
+		{name: "failed first attempt", fail: map[int]bool{0: true}, attempts: 2},
+	}
+
+	const delay = 50 * time.Millisecond
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sc := newSlowClient(t, tt.stall, tt.fail)
+			hs := sqs.NewHedgedSender(sc)
+			hs.MaxDelay = delay
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			start := time.Now()
+			smr, err := hs.Send(ctx, []byte("hello"), nil)
+			elapsed := time.Since(start)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := sc.attempts(); got != tt.attempts {
+				t.Errorf("%d attempts, want %d", got, tt.attempts)
+			}
+			if hedged := elapsed >= delay; hedged != tt.hedged {
+				t.Errorf("Send took %v with a hedge delay of %v", elapsed, delay)
+			}
+			for _, want := range tt.cancelled {
+				select {
+				case n := <-sc.cancelled:
+					if n != want {
+						t.Errorf("cancelled attempt %d, want %d", n, want)
+					}
+				case <-time.After(time.Second):
+					t.Errorf("attempt %d was not cancelled", want)
+				}
+			}
+
+			msgs, err := sc.Peek(10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(msgs) != 1 || msgs[0].MessageId != smr.MessageId {
+				t.Fatalf("queue holds %d messages, want the one sent", len(msgs))
+			}
+			if msgs[0].MessageAttribute(sqs.DedupKeyAttribute) == "" {
+				t.Error("message has no dedup key")
+			}
+		})
+	}
+}
+
+func TestHedgedSendCancelled(t *testing.T) {
+	sc := newSlowClient(t, map[int]bool{0: true, 1: true}, nil)
+	hs := sqs.NewHedgedSender(sc)
+	hs.MaxDelay = time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		for sc.attempts() < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		cancel()
+	}()
+
+	if _, err := hs.Send(ctx, []byte("hello"), nil); err != context.Canceled {
+		t.Fatalf("Send = %v, want context.Canceled", err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-sc.cancelled:
+		case <-time.After(time.Second):
+			t.Fatal("an attempt was not cancelled")
+		}
+	}
+}
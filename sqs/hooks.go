@@ -0,0 +1,51 @@
+package sqs
+
+import (
+	"time"
+)
+
+// Hooks lets code layered on top of the client observe what it does. All
+// hooks are optional and are called synchronously, so they should return
+// quickly.
+type Hooks struct {
+	// OnQueueEvent is called after management calls change a queue.
+	OnQueueEvent func(ev QueueEvent)
+
+	// OnRetry is called before a failed request is retried.
+	OnRetry func(ev RetryEvent)
+}
+
+type QueueEventType string
+
+const (
+	QueueCreated           QueueEventType = "created"
+	QueueAttributesChanged QueueEventType = "attributes-changed"
+	QueueDeleted           QueueEventType = "deleted"
+	QueuePurgeStarted      QueueEventType = "purge-started"
+	QueuePurgeFinished     QueueEventType = "purge-finished"
+)
+
+type QueueEvent struct {
+	Type      QueueEventType
+	Time      time.Time
+	QueueName string
+	QueueURL  string
+
+	// Attributes holds the attributes a queue was created with or that
+	// were changed.
+	Attributes map[string]string
+}
+
+func (s *SQSRequest) emitQueueEvent(typ QueueEventType, queueName, queueURL string, attrs map[string]string) {
+	if s.Hooks == nil || s.Hooks.OnQueueEvent == nil {
+		return
+	}
+
+	s.Hooks.OnQueueEvent(QueueEvent{
+		Type:       typ,
+		Time:       time.Now(),
+		QueueName:  queueName,
+		QueueURL:   queueURL,
+		Attributes: attrs,
+	})
+}
@@ -0,0 +1,161 @@
+package sqs
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// RoundTripFunc sends an HTTP request and returns its response.
+type RoundTripFunc func(req *http.Request) (*http.Response, error)
+
+// Interceptor wraps the sending of HTTP requests. It may inspect or change
+// the request before passing it on to next, and the response or error
+// after. Requests are already signed, so changing their parameters makes
+// them fail; headers outside the signature may be added freely.
+type Interceptor func(req *http.Request, next RoundTripFunc) (*http.Response, error)
+
+func (s *SQSRequest) roundTrip(req *http.Request) (*http.Response, error) {
+	client := s.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	next := RoundTripFunc(client.Do)
+	for i := len(s.Interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := s.Interceptors[i], next
+		next = func(req *http.Request) (*http.Response, error) {
+			return interceptor(req, inner)
+		}
+	}
+
+	return next(req)
+}
+
+// redactedParams are the request parameters LoggingInterceptor leaves out.
+var redactedParams = map[string]bool{
+	"AWSAccessKeyId": true,
+	"Signature":      true,
+	"SecurityToken":  true,
+}
+
+// LoggingInterceptor logs every request at debug level with its action,
+// parameters, status, request id and duration. Credentials and signatures
+// are redacted. A nil logger logs to slog.Default().
+func LoggingInterceptor(logger *slog.Logger) Interceptor {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	return func(req *http.Request, next RoundTripFunc) (*http.Response, error) {
+		ctx := req.Context()
+		if !logger.Enabled(ctx, slog.LevelDebug) {
+			return next(req)
+		}
+
+		action, params := requestParams(req)
+
+		start := time.Now()
+		resp, err := next(req)
+
+		attrs := []slog.Attr{
+			slog.String("action", action),
+			slog.String("url", req.URL.Redacted()),
+			slog.Any("params", params),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if err != nil {
+			attrs = append(attrs, slog.String("error", err.Error()))
+			logger.LogAttrs(ctx, slog.LevelDebug, "sqs request failed", attrs...)
+			return resp, err
+		}
+
+		attrs = append(attrs,
+			slog.Int("status", resp.StatusCode),
+			slog.String("requestId", responseRequestId(resp)),
+		)
+		logger.LogAttrs(ctx, slog.LevelDebug, "sqs request", attrs...)
+
+		return resp, nil
+	}
+}
+
+// requestParams returns the action and redacted parameters of an SQS
+// request, leaving the body in place. Other requests, such as those to S3,
+// are described by their method.
+func requestParams(req *http.Request) (string, map[string]string) {
+	contentType := req.Header.Get("Content-Type")
+	isQuery := strings.HasPrefix(contentType, "application/x-www-form-urlencoded")
+	isJSON := strings.HasPrefix(contentType, "application/x-amz-json-")
+	if req.Body == nil || !isQuery && !isJSON {
+		return req.Method, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return req.Method, nil
+	}
+
+	if isJSON {
+		var fields map[string]json.RawMessage
+		json.Unmarshal(body, &fields)
+
+		params := make(map[string]string, len(fields))
+		for key, value := range fields {
+			var str string
+			if json.Unmarshal(value, &str) != nil {
+				str = string(value)
+			}
+			params[key] = str
+		}
+		return strings.TrimPrefix(req.Header.Get("X-Amz-Target"), jsonTargetPrefix), params
+	}
+
+	uv, _ := url.ParseQuery(string(body))
+	params := make(map[string]string, len(uv))
+	for key := range uv {
+		if redactedParams[key] {
+			params[key] = "REDACTED"
+			continue
+		}
+		params[key] = uv.Get(key)
+	}
+
+	return uv.Get("Action"), params
+}
+
+// responseRequestId returns the request id of a response, from its headers
+// or else its body, which is left in place.
+func responseRequestId(resp *http.Response) string {
+	for _, header := range []string{"X-Amzn-Requestid", "X-Amz-Request-Id"} {
+		if id := resp.Header.Get(header); id != "" {
+			return id
+		}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var v struct {
+		RequestId      string `xml:"ResponseMetadata>RequestId"`
+		ErrorRequestId string `xml:"RequestId"`
+	}
+	xml.Unmarshal(body, &v)
+	if v.RequestId == "" {
+		return v.ErrorRequestId
+	}
+
+	return v.RequestId
+}
@@ -0,0 +1,99 @@
+package sqs
+
+import (
+	"expvar"
+	"strconv"
+	"time"
+)
+
+// MetricsCollector receives measurements of the requests a client makes.
+// Implementations must be safe for concurrent use and should return
+// quickly. ExpvarMetrics and PrometheusMetrics are ready-made ones.
+type MetricsCollector interface {
+	// ObserveRequest is called after every attempt at an SQS request with
+	// its HTTP status, or 0 if no response was received.
+	ObserveRequest(action string, status int, latency time.Duration)
+
+	// ObserveRetry is called when a failed request is about to be retried.
+	ObserveRetry(action string, class RetryClass)
+
+	// ObserveBatch is called with the number of entries of every batch
+	// request.
+	ObserveBatch(action string, entries int)
+
+	// ObserveReceive is called with the number of messages every receive
+	// returned; zero for empty receives.
+	ObserveReceive(messages int)
+}
+
+func (s *SQSRequest) observeBatch(action string, entries int) {
+	if s.Metrics != nil {
+		s.Metrics.ObserveBatch(action, entries)
+	}
+}
+
+// ExpvarMetrics publishes metrics as an expvar map, served as JSON under
+// /debug/vars by the expvar package.
+type ExpvarMetrics struct {
+	requests      *expvar.Map // by action
+	statuses      *expvar.Map // by action and status
+	latency       *expvar.Map // total milliseconds by action
+	retries       *expvar.Map // by action and class
+	batches       *expvar.Map // by action
+	batchEntries  *expvar.Map // by action
+	receives      *expvar.Int
+	emptyReceives *expvar.Int
+	received      *expvar.Int
+}
+
+// NewExpvarMetrics publishes the metrics under name, which must not be
+// published already.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	em := &ExpvarMetrics{
+		requests:      new(expvar.Map),
+		statuses:      new(expvar.Map),
+		latency:       new(expvar.Map),
+		retries:       new(expvar.Map),
+		batches:       new(expvar.Map),
+		batchEntries:  new(expvar.Map),
+		receives:      new(expvar.Int),
+		emptyReceives: new(expvar.Int),
+		received:      new(expvar.Int),
+	}
+
+	m := expvar.NewMap(name)
+	m.Set("requests", em.requests)
+	m.Set("statuses", em.statuses)
+	m.Set("latencyMs", em.latency)
+	m.Set("retries", em.retries)
+	m.Set("batches", em.batches)
+	m.Set("batchEntries", em.batchEntries)
+	m.Set("receives", em.receives)
+	m.Set("emptyReceives", em.emptyReceives)
+	m.Set("receivedMessages", em.received)
+
+	return em
+}
+
+func (em *ExpvarMetrics) ObserveRequest(action string, status int, latency time.Duration) {
+	em.requests.Add(action, 1)
+	em.statuses.Add(action+"."+strconv.Itoa(status), 1)
+	em.latency.AddFloat(action, float64(latency)/float64(time.Millisecond))
+}
+
+func (em *ExpvarMetrics) ObserveRetry(action string, class RetryClass) {
+	em.retries.Add(action+"."+string(class), 1)
+}
+
+func (em *ExpvarMetrics) ObserveBatch(action string, entries int) {
+	em.batches.Add(action, 1)
+	em.batchEntries.Add(action, int64(entries))
+}
+
+func (em *ExpvarMetrics) ObserveReceive(messages int) {
+	em.receives.Add(1)
+	em.received.Add(int64(messages))
+	if messages == 0 {
+		em.emptyReceives.Add(1)
+	}
+}
@@ -0,0 +1,149 @@
+package sqs
+
+import (
+	"encoding/xml"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Message move task statuses, as reported by ListMessageMoveTasks.
+const (
+	MoveTaskRunning    = "RUNNING"
+	MoveTaskCompleted  = "COMPLETED"
+	MoveTaskCancelling = "CANCELLING"
+	MoveTaskCancelled  = "CANCELLED"
+	MoveTaskFailed     = "FAILED"
+)
+
+// maxMoveTasksPerSecond is the highest redrive rate SQS accepts.
+const maxMoveTasksPerSecond = 500
+
+type StartMessageMoveTaskResponse struct {
+	TaskHandle string `xml:"StartMessageMoveTaskResult>TaskHandle"`
+	BasicResponse
+}
+
+type CancelMessageMoveTaskResponse struct {
+	ApproximateNumberOfMessagesMoved int64 `xml:"CancelMessageMoveTaskResult>ApproximateNumberOfMessagesMoved"`
+	BasicResponse
+}
+
+type MessageMoveTask struct {
+	TaskHandle                        string
+	Status                            string
+	SourceArn                         string
+	DestinationArn                    string
+	MaxNumberOfMessagesPerSecond      int
+	ApproximateNumberOfMessagesMoved  int64
+	ApproximateNumberOfMessagesToMove int64
+	FailureReason                     string
+	StartedTimestamp                  int64
+}
+
+// Started returns the time the task was started.
+func (mmt *MessageMoveTask) Started() time.Time {
+	return time.Unix(0, mmt.StartedTimestamp*int64(time.Millisecond))
+}
+
+type ListMessageMoveTasksResponse struct {
+	Tasks []MessageMoveTask `xml:"ListMessageMoveTasksResult>ListMessageMoveTasksResultEntry"`
+	BasicResponse
+}
+
+// StartMessageMoveTask redrives the messages of this dead-letter queue.
+// With a nil dest they go back to the queues they came from; otherwise
+// they all move to dest. A maxPerSecond of zero lets SQS pick the rate.
+func (s *SQSRequest) StartMessageMoveTask(dest SQSClient, maxPerSecond int) (*StartMessageMoveTaskResponse, error) {
+	if maxPerSecond < 0 || maxPerSecond > maxMoveTasksPerSecond {
+		return nil, errors.New("Message move rate must be 0 to 500 messages per second (0 lets SQS choose).")
+	}
+
+	sourceArn, err := s.QueueARN()
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"Action":    "StartMessageMoveTask",
+		"SourceArn": sourceArn,
+	}
+
+	if d, ok := dest.(*SQSRequest); ok && d == nil {
+		dest = nil
+	}
+	if dest != nil {
+		if params["DestinationArn"], err = queueARN(dest); err != nil {
+			return nil, err
+		}
+	}
+	if maxPerSecond > 0 {
+		params["MaxNumberOfMessagesPerSecond"] = strconv.Itoa(maxPerSecond)
+	}
+
+	reader, err := s.makeSQSAdminRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	smr := new(StartMessageMoveTaskResponse)
+	if err = xml.NewDecoder(reader).Decode(smr); err != nil {
+		return nil, err
+	}
+
+	return smr, nil
+}
+
+// CancelMessageMoveTask stops a running task. Messages already moved stay
+// where they are.
+func (s *SQSRequest) CancelMessageMoveTask(taskHandle string) (*CancelMessageMoveTaskResponse, error) {
+	params := map[string]string{
+		"Action":     "CancelMessageMoveTask",
+		"TaskHandle": taskHandle,
+	}
+
+	reader, err := s.makeSQSAdminRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	cmr := new(CancelMessageMoveTaskResponse)
+	if err = xml.NewDecoder(reader).Decode(cmr); err != nil {
+		return nil, err
+	}
+
+	return cmr, nil
+}
+
+// ListMessageMoveTasks returns the most recent move tasks of this
+// dead-letter queue, at most maxResults (up to 10) of them, newest first.
+func (s *SQSRequest) ListMessageMoveTasks(maxResults int) (*ListMessageMoveTasksResponse, error) {
+	sourceArn, err := s.QueueARN()
+	if err != nil {
+		return nil, err
+	}
+
+	params := map[string]string{
+		"Action":    "ListMessageMoveTasks",
+		"SourceArn": sourceArn,
+	}
+
+	if maxResults > 0 {
+		params["MaxResults"] = strconv.Itoa(maxResults)
+	}
+
+	reader, err := s.makeSQSAdminRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	lmr := new(ListMessageMoveTasksResponse)
+	if err = xml.NewDecoder(reader).Decode(lmr); err != nil {
+		return nil, err
+	}
+
+	return lmr, nil
+}
@@ -0,0 +1,143 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	maxQueueNameLength = 80
+	fifoSuffix         = ".fifo"
+)
+
+// QueueNaming enforces a naming convention on queue names. Normalize adds
+// whatever the convention requires and is idempotent, so names that
+// already follow it are left unchanged.
+type QueueNaming struct {
+	// Prefix is prepended to names that do not already start with it.
+	Prefix string
+
+	// Environment, if set, is appended as a Separator-delimited suffix,
+	// before any ".fifo" suffix.
+	Environment string
+
+	// Separator joins the name and the environment. Defaults to "-".
+	Separator string
+
+	// Lowercase folds names to lower case.
+	Lowercase bool
+}
+
+// Normalize applies the convention to name. FIFO queues get the ".fifo"
+// suffix SQS requires; a name that already has it is treated as FIFO.
+func (qn *QueueNaming) Normalize(name string, fifo bool) (string, error) {
+	if strings.HasSuffix(name, fifoSuffix) {
+		name = strings.TrimSuffix(name, fifoSuffix)
+		fifo = true
+	}
+
+	if qn.Lowercase {
+		name = strings.ToLower(name)
+	}
+
+	if !strings.HasPrefix(name, qn.Prefix) {
+		name = qn.Prefix + name
+	}
+
+	if qn.Environment != "" {
+		sep := qn.Separator
+		if sep == "" {
+			sep = "-"
+		}
+		if !strings.HasSuffix(name, sep+qn.Environment) {
+			name += sep + qn.Environment
+		}
+	}
+
+	if fifo {
+		name += fifoSuffix
+	}
+
+	return name, ValidateQueueName(name)
+}
+
+// ValidateQueueName checks name against the rules SQS applies: at most 80
+// characters, including any ".fifo" suffix, of letters, digits, hyphens
+// and underscores.
+func ValidateQueueName(name string) error {
+	base := strings.TrimSuffix(name, fifoSuffix)
+
+	if base == "" {
+		return errors.New("Queue name must not be empty.")
+	}
+	if len(name) > maxQueueNameLength {
+		return fmt.Errorf("Queue name %q is longer than %d characters.", name, maxQueueNameLength)
+	}
+
+	for _, r := range base {
+		if !isNameRune(r) {
+			return fmt.Errorf("Queue name %q contains invalid character %q.", name, r)
+		}
+	}
+
+	return nil
+}
+
+func isNameRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_'
+}
+
+// queueName applies the client's naming convention, if any, to the name of
+// a queue about to be created.
+func (s *SQSRequest) queueName(name string, fifo bool) (string, error) {
+	if s.Naming != nil {
+		return s.Naming.Normalize(name, fifo)
+	}
+
+	if fifo && !strings.HasSuffix(name, fifoSuffix) {
+		return "", fmt.Errorf("FIFO queue name %q must end in %s.", name, fifoSuffix)
+	}
+
+	return name, ValidateQueueName(name)
+}
+
+// EnsureQueue makes sure the queue named by QueueName exists, creating it
+// with opts if it does not. The name is normalized first, and QueueName
+// updated, when the client has a QueueNaming. The attributes of an
+// existing queue are not checked against opts.
+func (s *SQSRequest) EnsureQueue(opts *CreateQueueOptions) (*QueueURLResponse, error) {
+	qur, _, err := s.ensureQueue(opts)
+	return qur, err
+}
+
+// ensureQueue is EnsureQueue, also telling whether the queue was created.
+func (s *SQSRequest) ensureQueue(opts *CreateQueueOptions) (*QueueURLResponse, bool, error) {
+	attrs, err := opts.QueueAttributes()
+	if err != nil {
+		return nil, false, err
+	}
+
+	name, err := s.queueName(s.QueueName, attrs[AttrFifoQueue] == "true")
+	if err != nil {
+		return nil, false, err
+	}
+	s.QueueName = name
+
+	qur, err := s.QueueURL()
+	if err == nil {
+		return qur, false, nil
+	}
+
+	var er *ErrorResponse
+	if !errors.As(err, &er) || er.Code != "AWS.SimpleQueueService.NonExistentQueue" {
+		return nil, false, err
+	}
+
+	qur, err = s.CreateQueueWithOptions(name, opts)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return qur, true, nil
+}
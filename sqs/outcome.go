@@ -0,0 +1,78 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// DeadLetterReasonAttribute carries the reason a message was dead-lettered
+// or quarantined by a Consumer. It is left out of messages that already
+// have as many attributes as SQS allows.
+const DeadLetterReasonAttribute = "sqs-dead-letter-reason"
+
+type OutcomeKind string
+
+const (
+	OutcomeAck        OutcomeKind = "ack"
+	OutcomeRetry      OutcomeKind = "retry"
+	OutcomeDeadLetter OutcomeKind = "dead-letter"
+	OutcomeQuarantine OutcomeKind = "quarantine"
+)
+
+// Outcome tells a Consumer what to do with a handled message. Outcomes are
+// comparable, so handlers can be tested with ==.
+type Outcome struct {
+	Kind   OutcomeKind
+	After  time.Duration // Retry only
+	Reason string        // DeadLetter and Quarantine only
+}
+
+// Ack deletes the message.
+var Ack = Outcome{Kind: OutcomeAck}
+
+// Retry makes the message visible again after the given delay, rounded up
+// to whole seconds and capped at 12 hours.
+func Retry(after time.Duration) Outcome {
+	return Outcome{Kind: OutcomeRetry, After: after}
+}
+
+// DeadLetter moves the message to the consumer's dead-letter queue. Without
+// one the message is released, leaving it to the queue's redrive policy.
+func DeadLetter(reason string) Outcome {
+	return Outcome{Kind: OutcomeDeadLetter, Reason: reason}
+}
+
+// Quarantine moves the message to the consumer's quarantine queue for
+// manual inspection. Without one the message is hidden for as long as SQS
+// allows.
+func Quarantine(reason string) Outcome {
+	return Outcome{Kind: OutcomeQuarantine, Reason: reason}
+}
+
+func (o Outcome) String() string {
+	switch o.Kind {
+	case OutcomeRetry:
+		return fmt.Sprintf("retry after %s", o.After)
+	case OutcomeDeadLetter, OutcomeQuarantine:
+		return fmt.Sprintf("%s: %s", o.Kind, o.Reason)
+	}
+
+	return string(o.Kind)
+}
+
+// OutcomeHandler processes a single message and decides its fate.
+type OutcomeHandler func(ctx context.Context, m *RecvMessageResponse) Outcome
+
+// visibilitySeconds converts a retry delay to a visibility timeout.
+func (o Outcome) visibilitySeconds() int {
+	seconds := int((o.After + time.Second - 1) / time.Second)
+	if seconds < 0 {
+		return 0
+	}
+	if seconds > maxVisibilityTimeout {
+		return maxVisibilityTimeout
+	}
+
+	return seconds
+}
@@ -0,0 +1,166 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxMessageBytes is the largest message SQS accepts, counting the body
+// and the message attributes.
+const maxMessageBytes = 256 * 1024
+
+// ExtendedPayloadSizeAttribute marks messages whose body is a reference to
+// a payload kept in a PayloadStore, and carries the size of that payload.
+// The name is that of the AWS extended client libraries, which use it for
+// S3 pointers.
+const ExtendedPayloadSizeAttribute = "ExtendedPayloadSize"
+
+// maxPayloadRefBytes is the size assumed for references returned by a
+// PayloadStore when estimating message sizes.
+const maxPayloadRefBytes = 1024
+
+// Receipt handles of messages with an external payload carry its
+// reference between these markers, so that deleting the message can
+// delete the payload too.
+const payloadRefMarker = "-..payloadRef..-"
+
+// PayloadStore keeps message payloads too large for SQS; the message only
+// carries the reference Put returns. Received references are resolved
+// with Get, and the payload is deleted along with the message.
+// References must be valid message bodies and must not contain newlines.
+type PayloadStore interface {
+	Put(ctx context.Context, payload []byte) (ref string, err error)
+	Get(ctx context.Context, ref string) ([]byte, error)
+	Delete(ctx context.Context, ref string) error
+}
+
+// payloadStore returns the store of oversized payloads, if any, and the
+// message size above which it is used.
+func (s *SQSRequest) payloadStore() (PayloadStore, int) {
+	threshold := s.PayloadThreshold
+	store := s.Payloads
+	if store == nil && s.S3 != nil {
+		store, threshold = &s3Store{s}, s.S3.Threshold
+	}
+
+	if threshold <= 0 || threshold > maxMessageBytes {
+		threshold = maxMessageBytes
+	}
+
+	return store, threshold
+}
+
+func messageSize(body string, opts *SendOptions) int {
+	size := len(body)
+	if opts != nil {
+		for name, value := range opts.MessageAttributes {
+			size += len(name) + len("String") + len(value)
+		}
+	}
+
+	return size
+}
+
+// offloadSize returns the size of the message that replaces a body too
+// large to send, for estimating batch sizes without storing anything.
+func (s *SQSRequest) offloadSize(body string, opts *SendOptions) int {
+	size := messageSize(body, opts)
+	store, threshold := s.payloadStore()
+	if store == nil || size <= threshold {
+		return size
+	}
+
+	refSize := maxPayloadRefBytes
+	if ss, ok := store.(*s3Store); ok {
+		refSize = ss.pointerSize()
+	}
+
+	return refSize + len(ExtendedPayloadSizeAttribute) + len("String") + len(strconv.Itoa(len(body))) + size - len(body)
+}
+
+// offload stores an encoded body if the message would exceed the
+// threshold, and returns the reference body and options to send instead.
+func (s *SQSRequest) offload(ctx context.Context, body string, opts *SendOptions) (string, *SendOptions, error) {
+	store, threshold := s.payloadStore()
+	if store == nil || messageSize(body, opts) <= threshold {
+		return body, opts, nil
+	}
+
+	ref, err := store.Put(ctx, []byte(body))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return ref, opts.withAttribute(ExtendedPayloadSizeAttribute, strconv.Itoa(len(body))), nil
+}
+
+// inflate replaces the reference body of a message marked with
+// ExtendedPayloadSizeAttribute with the payload it refers to, and embeds
+// the reference in the receipt handle.
+func (s *SQSRequest) inflate(ctx context.Context, m *recvMessage) error {
+	marked := false
+	for _, attr := range m.MessageAttributes {
+		marked = marked || attr.Name == ExtendedPayloadSizeAttribute
+	}
+	if !marked {
+		return nil
+	}
+
+	store, _ := s.payloadStore()
+	if store == nil {
+		return fmt.Errorf("Message %s has an external payload, but the client has no payload store.", m.MessageId)
+	}
+
+	body, err := store.Get(ctx, m.MessageBody)
+	if err != nil {
+		return err
+	}
+
+	m.ReceiptHandle = payloadRefMarker + m.MessageBody + payloadRefMarker + m.ReceiptHandle
+	m.MessageBody = string(body)
+
+	return nil
+}
+
+// splitReceiptHandle separates the SQS receipt handle from the payload
+// reference embedded by inflate, if any. Handles in the format of the
+// AWS extended client libraries are understood as well.
+func splitReceiptHandle(handle string) (string, string) {
+	if handle, ref, ok := splitS3ReceiptHandle(handle); ok {
+		return handle, ref
+	}
+
+	if !strings.HasPrefix(handle, payloadRefMarker) {
+		return handle, ""
+	}
+
+	parts := strings.SplitN(handle[len(payloadRefMarker):], payloadRefMarker, 2)
+	if len(parts) != 2 {
+		return handle, ""
+	}
+
+	return parts[1], parts[0]
+}
+
+func stripReceiptHandle(handle string) string {
+	handle, _ = splitReceiptHandle(handle)
+	return handle
+}
+
+// SQSReceiptHandle returns the receipt handle SQS issued for the message,
+// without the payload reference the client may have embedded.
+func (rmr *RecvMessageResponse) SQSReceiptHandle() string {
+	return stripReceiptHandle(rmr.ReceiptHandle)
+}
+
+func (s *SQSRequest) deletePayload(ctx context.Context, ref string) error {
+	store, _ := s.payloadStore()
+	if store == nil {
+		return errors.New("Cannot delete an external payload without a payload store.")
+	}
+
+	return store.Delete(ctx, ref)
+}
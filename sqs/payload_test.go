@@ -0,0 +1,169 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memPayloads is a PayloadStore in memory.
+type memPayloads struct {
+	mu       sync.Mutex
+	payloads map[string][]byte
+	next     int
+}
+
+func (mp *memPayloads) Put(ctx context.Context, payload []byte) (string, error) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if mp.payloads == nil {
+		mp.payloads = make(map[string][]byte)
+	}
+	mp.next++
+	ref := fmt.Sprint("payload-", mp.next)
+	mp.payloads[ref] = payload
+
+	return ref, nil
+}
+
+func (mp *memPayloads) Get(ctx context.Context, ref string) ([]byte, error) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	payload, ok := mp.payloads[ref]
+	if !ok {
+		return nil, errors.New("No such payload.")
+	}
+
+	return payload, nil
+}
+
+func (mp *memPayloads) Delete(ctx context.Context, ref string) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	delete(mp.payloads, ref)
+	return nil
+}
+
+func TestPayloadStoreThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		body      int
+		offloaded bool
+	}{
+		{"below", 100, 99, false},
+		{"at", 100, 100, false},
+		{"above", 100, 101, true},
+		{"default", 0, maxMessageBytes, false},
+		{"default exceeded", 0, maxMessageBytes + 1, true},
+		{"capped at the SQS limit", 2 * maxMessageBytes, maxMessageBytes + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &memPayloads{}
+			s := &SQSRequest{Payloads: store, PayloadThreshold: tt.threshold}
+			body := strings.Repeat("x", tt.body)
+
+			ref, opts, err := s.offload(context.Background(), body, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if offloaded := ref != body; offloaded != tt.offloaded {
+				t.Fatalf("offloaded = %v, want %v", offloaded, tt.offloaded)
+			}
+			if !tt.offloaded {
+				return
+			}
+
+			if got := opts.MessageAttributes[ExtendedPayloadSizeAttribute]; got != strconv.Itoa(tt.body) {
+				t.Errorf("%s = %q, want %d", ExtendedPayloadSizeAttribute, got, tt.body)
+			}
+			if got := s.offloadSize(body, nil); got < messageSize(ref, opts) {
+				t.Errorf("offloadSize = %d, below the size of the message sent, %d", got, messageSize(ref, opts))
+			}
+
+			m := &recvMessage{
+				MessageId:         "m1",
+				MessageBody:       ref,
+				ReceiptHandle:     "h1",
+				MessageAttributes: []MessageAttribute{{ExtendedPayloadSizeAttribute, "Number", strconv.Itoa(tt.body)}},
+			}
+			if err = s.inflate(context.Background(), m); err != nil {
+				t.Fatal(err)
+			}
+			if m.MessageBody != body {
+				t.Errorf("inflated body of %d bytes, want %d", len(m.MessageBody), tt.body)
+			}
+
+			rmr := &RecvMessageResponse{ReceiptHandle: m.ReceiptHandle}
+			if got := rmr.SQSReceiptHandle(); got != "h1" {
+				t.Errorf("SQSReceiptHandle = %q, want h1", got)
+			}
+			_, gotRef := splitReceiptHandle(m.ReceiptHandle)
+			if err = s.deletePayload(context.Background(), gotRef); err != nil {
+				t.Fatal(err)
+			}
+			if len(store.payloads) != 0 {
+				t.Errorf("%d payloads left after deleting the message", len(store.payloads))
+			}
+		})
+	}
+}
+
+func TestPayloadsTakePrecedenceOverS3(t *testing.T) {
+	store := &memPayloads{}
+	s := &SQSRequest{Payloads: store, PayloadThreshold: 10, S3: &S3Offload{Bucket: "payloads", Threshold: 1000}}
+
+	got, threshold := s.payloadStore()
+	if got != store || threshold != 10 {
+		t.Errorf("payloadStore = %T, %d, want the Payloads store and its threshold", got, threshold)
+	}
+}
+
+func TestInflateWithoutStore(t *testing.T) {
+	s := &SQSRequest{}
+
+	plain := &recvMessage{MessageId: "m1", MessageBody: "hello", ReceiptHandle: "h1"}
+	if err := s.inflate(context.Background(), plain); err != nil || plain.MessageBody != "hello" || plain.ReceiptHandle != "h1" {
+		t.Errorf("inflate changed an ordinary message: %+v, %v", plain, err)
+	}
+
+	marked := &recvMessage{
+		MessageId:         "m2",
+		MessageBody:       "payload-1",
+		MessageAttributes: []MessageAttribute{{ExtendedPayloadSizeAttribute, "Number", "300000"}},
+	}
+	if err := s.inflate(context.Background(), marked); err == nil {
+		t.Error("inflated an external payload without a store")
+	}
+}
+
+func TestPayloadReceiptHandle(t *testing.T) {
+	tests := []struct {
+		name   string
+		handle string
+		want   string
+		ref    string
+	}{
+		{"plain", "h1", "h1", ""},
+		{"embedded reference", payloadRefMarker + "payload-1" + payloadRefMarker + "h1", "h1", "payload-1"},
+		{"unterminated reference", payloadRefMarker + "payload-1", payloadRefMarker + "payload-1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle, ref := splitReceiptHandle(tt.handle)
+			if handle != tt.want || ref != tt.ref {
+				t.Errorf("splitReceiptHandle = %q, %q, want %q, %q", handle, ref, tt.want, tt.ref)
+			}
+		})
+	}
+}
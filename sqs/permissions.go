@@ -0,0 +1,67 @@
+package sqs
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// AddPermission grants each of the given AWS accounts each of the given
+// actions (e.g. "SendMessage", or "*" for all) on the queue. The label
+// identifies the resulting policy statement for RemovePermission.
+func (s *SQSRequest) AddPermission(label string, accountIds, actions []string) (*BasicResponse, error) {
+	if len(accountIds) == 0 || len(actions) == 0 {
+		return nil, errors.New("AddPermission needs at least one account id and one action.")
+	}
+
+	if label == "" || len(label) > 80 || strings.IndexFunc(label, func(r rune) bool { return !isNameRune(r) }) >= 0 {
+		return nil, fmt.Errorf("Invalid permission label %q: use up to 80 letters, digits, hyphens and underscores.", label)
+	}
+
+	params := map[string]string{
+		"Action": "AddPermission",
+		"Label":  label,
+	}
+
+	for i, id := range accountIds {
+		params[fmt.Sprintf("AWSAccountId.%d", i+1)] = id
+	}
+	for i, action := range actions {
+		params[fmt.Sprintf("ActionName.%d", i+1)] = action
+	}
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	bmr := new(BasicResponse)
+	if err = xml.NewDecoder(reader).Decode(bmr); err != nil {
+		return nil, err
+	}
+
+	return bmr, nil
+}
+
+// RemovePermission revokes the policy statement added under label.
+func (s *SQSRequest) RemovePermission(label string) (*BasicResponse, error) {
+	params := map[string]string{
+		"Action": "RemovePermission",
+		"Label":  label,
+	}
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	bmr := new(BasicResponse)
+	if err = xml.NewDecoder(reader).Decode(bmr); err != nil {
+		return nil, err
+	}
+
+	return bmr, nil
+}
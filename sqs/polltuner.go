@@ -0,0 +1,81 @@
+package sqs
+
+import (
+	"math"
+	"sync"
+)
+
+// PollTuner adapts the receive parameters of a Consumer to the rate at
+// which messages arrive. It tracks how full recent receives were: as the
+// queue gets busier it asks for bigger batches and waits less, and as it
+// goes idle it falls back to single messages and the longest wait, which
+// costs the fewest requests.
+type PollTuner struct {
+	// MinWait and MaxWait bound the long-poll wait, in seconds. They
+	// default to 1 and 20.
+	MinWait int
+	MaxWait int
+
+	// Smoothing is the weight of the latest receive in the moving average
+	// of how full receives are, between 0 and 1. Defaults to 0.3.
+	Smoothing float64
+
+	mu   sync.Mutex
+	load float64
+}
+
+func (pt *PollTuner) waits() (int, int) {
+	minWait, maxWait := pt.MinWait, pt.MaxWait
+	if minWait <= 0 {
+		minWait = 1
+	}
+	if maxWait <= 0 || maxWait > 20 {
+		maxWait = 20
+	}
+	if minWait > maxWait {
+		minWait = maxWait
+	}
+
+	return minWait, maxWait
+}
+
+// Current returns the number of messages and the wait, in seconds, of the
+// next receive.
+func (pt *PollTuner) Current() (maxMessages, waitSeconds int) {
+	pt.mu.Lock()
+	load := pt.load
+	pt.mu.Unlock()
+
+	// Ask for twice what recent receives got, so that a growing backlog
+	// is noticed quickly.
+	maxMessages = int(math.Ceil(2 * load * maxBatchEntries))
+	if maxMessages < 1 {
+		maxMessages = 1
+	}
+	if maxMessages > maxBatchEntries {
+		maxMessages = maxBatchEntries
+	}
+
+	minWait, maxWait := pt.waits()
+	waitSeconds = minWait + int(math.Round(float64(maxWait-minWait)*(1-load)))
+
+	return maxMessages, waitSeconds
+}
+
+// Observe records that a receive returned n messages.
+func (pt *PollTuner) Observe(n int) {
+	alpha := pt.Smoothing
+	if alpha <= 0 || alpha > 1 {
+		alpha = 0.3
+	}
+
+	fill := float64(n) / maxBatchEntries
+	if fill > 1 {
+		fill = 1
+	}
+
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	pt.load = alpha*fill + (1-alpha)*pt.load
+}
@@ -0,0 +1,60 @@
+package sqs
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestPollTuner(t *testing.T) {
+	tests := []struct {
+		name             string
+		minWait, maxWait int
+		smoothing        float64
+		observed         []int
+		max, wait        int
+	}{
+		{name: "idle", max: 1, wait: 20},
+		{name: "saturated", smoothing: 1, observed: []int{10}, max: 10, wait: 1},
+		{name: "more than a batch", smoothing: 1, observed: []int{15}, max: 10, wait: 1},
+		{name: "half full", smoothing: 1, observed: []int{5}, max: 10, wait: 11},
+		{name: "a fifth full", smoothing: 1, observed: []int{2}, max: 4, wait: 16},
+		{name: "default smoothing", observed: []int{10}, max: 6, wait: 14},
+		{name: "decays", smoothing: 0.5, observed: []int{10, 0}, max: 5, wait: 15},
+		{name: "MaxWait capped at 20", minWait: 5, maxWait: 30, max: 1, wait: 20},
+		{name: "MinWait", minWait: 5, smoothing: 1, observed: []int{10}, max: 10, wait: 5},
+		{name: "MinWait above MaxWait", minWait: 15, maxWait: 10, smoothing: 1, observed: []int{10}, max: 10, wait: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pt := &PollTuner{MinWait: tt.minWait, MaxWait: tt.maxWait, Smoothing: tt.smoothing}
+			for _, n := range tt.observed {
+				pt.Observe(n)
+			}
+
+			if max, wait := pt.Current(); max != tt.max || wait != tt.wait {
+				t.Errorf("Current = %d, %d, want %d, %d", max, wait, tt.max, tt.wait)
+			}
+		})
+	}
+}
+
+func TestPollTunerBounds(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for _, bounds := range [][2]int{{0, 0}, {2, 8}, {20, 20}} {
+		t.Run(fmt.Sprint(bounds), func(t *testing.T) {
+			pt := &PollTuner{MinWait: bounds[0], MaxWait: bounds[1], Smoothing: r.Float64()}
+			minWait, maxWait := pt.waits()
+
+			for i := 0; i < 1000; i++ {
+				pt.Observe(r.Intn(12))
+				max, wait := pt.Current()
+				if max < 1 || max > maxBatchEntries || wait < minWait || wait > maxWait {
+					t.Fatalf("Current = %d, %d, out of bounds", max, wait)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,458 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var ErrProducerClosed = errors.New("Producer is closed.")
+
+// SendResult reports the outcome of a message enqueued on a Producer.
+type SendResult struct {
+	Body      []byte
+	MessageId string
+	Err       error
+}
+
+// Producer aggregates enqueued messages into SendMessageBatch requests. A
+// batch is sent as soon as it holds ten messages or would exceed the SQS
+// payload limit, and otherwise after FlushInterval. Entries that fail
+// through no fault of the sender are retried up to MaxRetries times before
+// their failure is reported.
+//
+// Messages are sent in the order they were enqueued, one batch at a time,
+// and failed entries are retried before any entry enqueued after them, so
+// the messages of a FIFO group reach the queue in order. An entry that
+// runs out of retries is reported and does not hold back the rest of its
+// group. SQS accepts or rejects the entries of a batch individually,
+// though, so a message may still overtake an earlier one of its group
+// that failed in the same batch; StrictOrdering rules that out.
+type Producer struct {
+	Queue SQSClient
+
+	FlushInterval time.Duration // defaults to 100ms
+	MaxRetries    int           // defaults to 3; negative disables retries
+
+	// StrictOrdering puts at most one message of each MessageGroupId in a
+	// batch, at the cost of smaller batches when few groups are busy.
+	StrictOrdering bool
+
+	// Results, if set, receives the outcome of every message, in addition
+	// to any per-message callback. Sends block if it is not drained.
+	Results chan<- SendResult
+
+	// Spool, if set, persists every accepted message until SQS has taken
+	// or rejected it, so that messages survive a crash or an outage.
+	// Messages that run out of retries stay spooled and are sent again
+	// every ReplayInterval, and those left over by a previous process are
+	// sent when the producer starts. Replayed messages have no callback.
+	Spool          Spool
+	ReplayInterval time.Duration // defaults to 1 minute
+
+	// OnError is called with errors of the spool.
+	OnError func(err error)
+
+	once    sync.Once
+	in      chan *producerEntry
+	flush   chan chan struct{}
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+	pending []*producerEntry
+	size    int
+
+	spoolMu sync.Mutex
+	spooled map[string]bool // ids of spooled messages held in memory
+}
+
+type producerEntry struct {
+	body     []byte
+	opts     SendOptions
+	size     int
+	attempts int
+	lastErr  error
+	callback func(SendResult)
+	spoolId  string
+}
+
+func NewProducer(queue SQSClient) *Producer {
+	return &Producer{Queue: queue}
+}
+
+func (p *Producer) init() {
+	if p.FlushInterval <= 0 {
+		p.FlushInterval = 100 * time.Millisecond
+	}
+	if p.MaxRetries == 0 {
+		p.MaxRetries = 3
+	}
+	if p.ReplayInterval <= 0 {
+		p.ReplayInterval = time.Minute
+	}
+	p.spooled = make(map[string]bool)
+
+	p.in = make(chan *producerEntry, maxBatchEntries)
+	p.flush = make(chan chan struct{})
+	p.done = make(chan struct{})
+
+	go p.run()
+}
+
+// Enqueue queues body for sending. callback, if not nil, is called from the
+// producer's goroutine once the message has been sent or has failed.
+func (p *Producer) Enqueue(body []byte, callback func(SendResult)) error {
+	return p.EnqueueWithOptions(body, nil, callback)
+}
+
+// EnqueueContext queues body for sending, carrying attributes forward from
+// the message being handled in ctx according to the Propagation policy of
+// the producer's queue, along with the trace context of ctx.
+func (p *Producer) EnqueueContext(ctx context.Context, body []byte, opts *SendOptions, callback func(SendResult)) error {
+	s := settings(p.Queue)
+	opts = s.injectTrace(ctx, opts)
+	opts, err := s.Propagation.apply(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	return p.EnqueueWithOptions(body, opts, callback)
+}
+
+func (p *Producer) EnqueueWithOptions(body []byte, opts *SendOptions, callback func(SendResult)) error {
+	p.once.Do(p.init)
+
+	e, err := p.newEntry(body, opts, callback)
+	if err != nil {
+		return err
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return ErrProducerClosed
+	}
+
+	if p.Spool != nil {
+		id, err := p.Spool.Append(body, e.opts)
+		if err != nil {
+			return err
+		}
+		e.spoolId = id
+
+		p.spoolMu.Lock()
+		p.spooled[id] = true
+		p.spoolMu.Unlock()
+	}
+
+	p.in <- e
+
+	return nil
+}
+
+func (p *Producer) newEntry(body []byte, opts *SendOptions, callback func(SendResult)) (*producerEntry, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	s := settings(p.Queue)
+	encoded, encodedOpts, err := s.encodeBody(body, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &producerEntry{
+		body:     body,
+		size:     s.offloadSize(encoded, encodedOpts),
+		callback: callback,
+	}
+	if opts != nil {
+		e.opts = *opts
+	}
+
+	return e, nil
+}
+
+// Flush sends everything enqueued so far and waits for the outcome.
+func (p *Producer) Flush() {
+	p.once.Do(p.init)
+
+	ack := make(chan struct{})
+	select {
+	case p.flush <- ack:
+		<-ack
+	case <-p.done:
+	}
+}
+
+// Close flushes pending messages, waits for them to be sent and stops the
+// producer. Enqueue fails with ErrProducerClosed afterwards.
+func (p *Producer) Close() error {
+	p.once.Do(p.init)
+
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.in)
+	}
+	p.mu.Unlock()
+
+	<-p.done
+
+	return nil
+}
+
+func (p *Producer) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.FlushInterval)
+	defer ticker.Stop()
+
+	var replay <-chan time.Time
+	if p.Spool != nil {
+		p.replay()
+
+		replayTicker := time.NewTicker(p.ReplayInterval)
+		defer replayTicker.Stop()
+		replay = replayTicker.C
+	}
+
+	for {
+		select {
+		case e, ok := <-p.in:
+			if !ok {
+				for len(p.pending) > 0 {
+					p.sendBatch()
+				}
+				return
+			}
+			p.accept(e)
+		case <-ticker.C:
+			p.sendBatch()
+		case <-replay:
+			p.replay()
+		case ack := <-p.flush:
+			p.drainInput()
+			for len(p.pending) > 0 {
+				p.sendBatch()
+			}
+			close(ack)
+		}
+	}
+}
+
+// drainInput moves entries already waiting on the input channel into the
+// pending list without blocking.
+func (p *Producer) drainInput() {
+	for {
+		select {
+		case e, ok := <-p.in:
+			if !ok {
+				return
+			}
+			p.add(e)
+		default:
+			return
+		}
+	}
+}
+
+// accept adds an entry to the pending list, sending batches as they fill
+// up.
+func (p *Producer) accept(e *producerEntry) {
+	if p.size+e.size > maxBatchBytes {
+		p.sendBatch()
+	}
+	p.add(e)
+	if len(p.pending) >= maxBatchEntries {
+		p.sendBatch()
+	}
+}
+
+// replay accepts the spooled messages that are not held in memory: those
+// that ran out of retries and those left over by a previous process.
+func (p *Producer) replay() {
+	records, err := p.Spool.Pending()
+	if err != nil {
+		p.reportError(err)
+		return
+	}
+
+	for _, r := range records {
+		p.spoolMu.Lock()
+		held := p.spooled[r.Id]
+		p.spooled[r.Id] = true
+		p.spoolMu.Unlock()
+		if held {
+			continue
+		}
+
+		e, err := p.newEntry(r.Body, &r.Options, nil)
+		if err != nil {
+			p.reportError(err)
+			p.unspool(&producerEntry{spoolId: r.Id}, true)
+			continue
+		}
+		e.spoolId = r.Id
+
+		p.accept(e)
+	}
+}
+
+// unspool forgets a message that is no longer held in memory. Messages
+// that were sent, or rejected for good, are also removed from the spool;
+// others stay there to be replayed.
+func (p *Producer) unspool(e *producerEntry, remove bool) {
+	if e.spoolId == "" {
+		return
+	}
+
+	p.spoolMu.Lock()
+	delete(p.spooled, e.spoolId)
+	p.spoolMu.Unlock()
+
+	if remove {
+		if err := p.Spool.Remove(e.spoolId); err != nil {
+			p.reportError(err)
+		}
+	}
+}
+
+func (p *Producer) reportError(err error) {
+	if p.OnError != nil {
+		p.OnError(err)
+	}
+}
+
+func (p *Producer) add(e *producerEntry) {
+	p.pending = append(p.pending, e)
+	p.size += e.size
+}
+
+// nextBatch takes the longest prefix of pending entries that fits into a
+// single request. With StrictOrdering, entries of groups that already
+// have one in the batch are passed over and stay pending, in order.
+func (p *Producer) nextBatch() []*producerEntry {
+	var (
+		batch, rest []*producerEntry
+		size        int
+		groups      = make(map[string]bool)
+	)
+	for i, e := range p.pending {
+		if len(batch) == maxBatchEntries || len(batch) > 0 && size+e.size > maxBatchBytes {
+			rest = append(rest, p.pending[i:]...)
+			break
+		}
+
+		group := e.opts.MessageGroupId
+		if p.StrictOrdering && group != "" && groups[group] {
+			rest = append(rest, e)
+			continue
+		}
+		groups[group] = true
+
+		batch = append(batch, e)
+		size += e.size
+	}
+
+	p.pending = rest
+	p.size -= size
+
+	return batch
+}
+
+// errUnreported is the error of a batch entry that SQS reported neither as
+// sent nor as failed.
+var errUnreported = errors.New("SQS did not report the outcome of the message.")
+
+func (p *Producer) sendBatch() {
+	if len(p.pending) == 0 {
+		return
+	}
+
+	batch := p.nextBatch()
+	entries := make([]BatchEntry, len(batch))
+	for i, e := range batch {
+		entries[i] = BatchEntry{strconv.Itoa(i), e.body, e.opts}
+	}
+
+	smr, err := p.Queue.SendSQSMessageBatch(entries)
+	if err != nil {
+		for _, e := range batch {
+			e.lastErr = err
+		}
+		p.retry(batch)
+		return
+	}
+
+	reported := make([]bool, len(batch))
+	for _, s := range smr.Successful {
+		if i, err := strconv.Atoi(s.Id); err == nil && i < len(batch) && !reported[i] {
+			reported[i] = true
+			p.unspool(batch[i], true)
+			p.deliver(batch[i], SendResult{batch[i].body, s.MessageId, nil})
+		}
+	}
+
+	// Failed entries are retried in the order they were enqueued, which
+	// need not be the order SQS reports them in. So are entries SQS did
+	// not report on at all.
+	retryable := make([]bool, len(batch))
+	for _, f := range smr.Failed {
+		i, err := strconv.Atoi(f.Id)
+		if err != nil || i >= len(batch) || reported[i] {
+			continue
+		}
+		reported[i] = true
+		batch[i].lastErr = BatchError{f}
+		if f.SenderFault {
+			p.unspool(batch[i], true)
+			p.deliver(batch[i], SendResult{batch[i].body, "", batch[i].lastErr})
+			continue
+		}
+		retryable[i] = true
+	}
+
+	var failed []*producerEntry
+	for i, e := range batch {
+		if !reported[i] {
+			e.lastErr = errUnreported
+			retryable[i] = true
+		}
+		if retryable[i] {
+			failed = append(failed, e)
+		}
+	}
+	p.retry(failed)
+}
+
+// retry puts failed entries back at the front of the pending list, ahead
+// of everything enqueued after them, or reports their failure once they
+// have run out of attempts.
+func (p *Producer) retry(entries []*producerEntry) {
+	var again []*producerEntry
+	for _, e := range entries {
+		e.attempts++
+		if p.MaxRetries < 0 || e.attempts > p.MaxRetries {
+			p.unspool(e, false)
+			p.deliver(e, SendResult{e.body, "", e.lastErr})
+			continue
+		}
+		again = append(again, e)
+		p.size += e.size
+	}
+
+	p.pending = append(again, p.pending...)
+}
+
+func (p *Producer) deliver(e *producerEntry, res SendResult) {
+	if e.callback != nil {
+		e.callback(res)
+	}
+	if p.Results != nil {
+		p.Results <- res
+	}
+}
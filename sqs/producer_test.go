@@ -0,0 +1,377 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchQueue records the SendMessageBatch requests of a Producer. err, if
+// set, fails whole requests; fail, if set, decides which entries of each
+// request SQS rejects, by body, and drop which it leaves out of the
+// response.
+type batchQueue struct {
+	SQSClient
+
+	mu      sync.Mutex
+	batches [][]string
+	sent    []string
+	fail    func(call int, body string) *BatchResultError
+	drop    func(call int, body string) bool
+	err     func(call int) error
+}
+
+func (bq *batchQueue) SendSQSMessageBatch(entries []BatchEntry) (*SendMessageBatchResponse, error) {
+	bq.mu.Lock()
+	defer bq.mu.Unlock()
+
+	call := len(bq.batches)
+	var bodies []string
+	for _, e := range entries {
+		bodies = append(bodies, string(e.Body))
+	}
+	bq.batches = append(bq.batches, bodies)
+
+	if bq.err != nil {
+		if err := bq.err(call); err != nil {
+			return nil, err
+		}
+	}
+
+	smr := new(SendMessageBatchResponse)
+	for _, e := range entries {
+		if bq.drop != nil && bq.drop(call, string(e.Body)) {
+			continue
+		}
+		if bq.fail != nil {
+			if f := bq.fail(call, string(e.Body)); f != nil {
+				f.Id = e.Id
+				smr.Failed = append(smr.Failed, *f)
+				continue
+			}
+		}
+		bq.sent = append(bq.sent, string(e.Body))
+		smr.Successful = append(smr.Successful, SendMessageBatchResultEntry{Id: e.Id, MessageId: "id-" + string(e.Body)})
+	}
+
+	return smr, nil
+}
+
+// groupMessage is a body to enqueue in a FIFO group.
+type groupMessage struct {
+	group, body string
+}
+
+// grouped returns n messages spread over groups round-robin, numbered
+// within each group: a0, b0, a1, b1 and so on.
+func grouped(n int, groups ...string) []groupMessage {
+	msgs := make([]groupMessage, n)
+	for i := range msgs {
+		g := groups[i%len(groups)]
+		msgs[i] = groupMessage{g, fmt.Sprintf("%s%d", g, i/len(groups))}
+	}
+
+	return msgs
+}
+
+func produce(t *testing.T, p *Producer, msgs []groupMessage) []SendResult {
+	t.Helper()
+
+	var mu sync.Mutex
+	var results []SendResult
+	for _, m := range msgs {
+		err := p.EnqueueWithOptions([]byte(m.body), &SendOptions{MessageGroupId: m.group}, func(res SendResult) {
+			mu.Lock()
+			results = append(results, res)
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	p.Close()
+
+	return results
+}
+
+// checkGroupOrder fails the test if the bodies of any group are out of
+// order in sent.
+func checkGroupOrder(t *testing.T, msgs []groupMessage, sent []string) {
+	t.Helper()
+
+	want := make(map[string][]string)
+	for _, m := range msgs {
+		want[m.group] = append(want[m.group], m.body)
+	}
+
+	got := make(map[string][]string)
+	for _, body := range sent {
+		for _, m := range msgs {
+			if m.body == body {
+				got[m.group] = append(got[m.group], body)
+			}
+		}
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("per-group send order = %v, want %v", got, want)
+	}
+}
+
+func TestProducerOrderAcrossBatches(t *testing.T) {
+	bq := &batchQueue{}
+	p := NewProducer(bq)
+	p.FlushInterval = time.Hour
+
+	msgs := grouped(25, "a", "b", "c")
+	produce(t, p, msgs)
+
+	var sizes []int
+	for _, b := range bq.batches {
+		sizes = append(sizes, len(b))
+	}
+	if !reflect.DeepEqual(sizes, []int{10, 10, 5}) {
+		t.Errorf("batch sizes = %v, want [10 10 5]", sizes)
+	}
+	checkGroupOrder(t, msgs, bq.sent)
+}
+
+func TestProducerOrderAcrossSizeSplits(t *testing.T) {
+	bq := &batchQueue{}
+	p := NewProducer(bq)
+	p.FlushInterval = time.Hour
+
+	// Three of these fill a request.
+	pad := strings.Repeat("x", 80*1024)
+	var msgs []groupMessage
+	for i := 0; i < 7; i++ {
+		msgs = append(msgs, groupMessage{"a", fmt.Sprintf("%d%s", i, pad)})
+	}
+	produce(t, p, msgs)
+
+	var sizes []int
+	for _, b := range bq.batches {
+		sizes = append(sizes, len(b))
+	}
+	if !reflect.DeepEqual(sizes, []int{3, 3, 1}) {
+		t.Errorf("batch sizes = %v, want [3 3 1]", sizes)
+	}
+	checkGroupOrder(t, msgs, bq.sent)
+}
+
+func TestProducerRetriesAheadOfLaterEntries(t *testing.T) {
+	bq := &batchQueue{
+		fail: func(call int, body string) *BatchResultError {
+			if call == 0 && body == "a1" {
+				return &BatchResultError{Code: "InternalError"}
+			}
+			return nil
+		},
+	}
+	p := NewProducer(bq)
+	p.FlushInterval = time.Hour
+
+	// a1 fails in the first batch of ten; the retry goes out before the
+	// two messages enqueued after that batch.
+	msgs := grouped(12, "a", "b")
+	produce(t, p, msgs)
+
+	if len(bq.batches) != 2 {
+		t.Fatalf("batches = %v, want 2", bq.batches)
+	}
+	if want := []string{"a1", "a5", "b5"}; !reflect.DeepEqual(bq.batches[1], want) {
+		t.Errorf("second batch = %v, want %v", bq.batches[1], want)
+	}
+}
+
+func TestProducerRetriesFailedRequestInOrder(t *testing.T) {
+	bq := &batchQueue{
+		err: func(call int) error {
+			if call == 0 {
+				return errors.New("connection reset")
+			}
+			return nil
+		},
+	}
+	p := NewProducer(bq)
+	p.FlushInterval = time.Hour
+
+	msgs := grouped(12, "a", "b")
+	results := produce(t, p, msgs)
+
+	for _, res := range results {
+		if res.Err != nil {
+			t.Errorf("%s failed: %v", res.Body, res.Err)
+		}
+	}
+	if !reflect.DeepEqual(bq.batches[0], bq.batches[1]) {
+		t.Errorf("retried batch = %v, want %v", bq.batches[1], bq.batches[0])
+	}
+	checkGroupOrder(t, msgs, bq.sent)
+}
+
+func TestProducerStrictOrdering(t *testing.T) {
+	failing := func(call int, body string) *BatchResultError {
+		if call == 0 && body == "a1" {
+			return &BatchResultError{Code: "InternalError"}
+		}
+		return nil
+	}
+
+	tests := []struct {
+		strict  bool
+		inOrder bool
+	}{
+		// Without StrictOrdering a2 is in the same batch as a1, and
+		// overtakes it when a1 fails.
+		{strict: false, inOrder: false},
+		{strict: true, inOrder: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("strict=%v", tt.strict), func(t *testing.T) {
+			bq := &batchQueue{fail: failing}
+			p := NewProducer(bq)
+			p.FlushInterval = time.Hour
+			p.StrictOrdering = tt.strict
+
+			msgs := []groupMessage{{"a", "a0"}, {"b", "b0"}, {"a", "a1"}, {"a", "a2"}, {"b", "b1"}, {"c", "c0"}}
+			produce(t, p, msgs)
+
+			if tt.strict {
+				for i, b := range bq.batches {
+					seen := make(map[byte]bool)
+					for _, body := range b {
+						if seen[body[0]] {
+							t.Errorf("batch %d = %v holds two messages of group %c", i, b, body[0])
+						}
+						seen[body[0]] = true
+					}
+				}
+			}
+
+			var got []groupMessage
+			for _, body := range bq.sent {
+				got = append(got, groupMessage{body[:1], body})
+			}
+			ordered := true
+			last := make(map[string]string)
+			for _, m := range got {
+				if m.body < last[m.group] {
+					ordered = false
+				}
+				last[m.group] = m.body
+			}
+			if ordered != tt.inOrder {
+				t.Errorf("sent %v; in order = %v, want %v", bq.sent, ordered, tt.inOrder)
+			}
+			if len(bq.sent) != len(msgs) {
+				t.Errorf("sent %d messages, want %d", len(bq.sent), len(msgs))
+			}
+		})
+	}
+}
+
+func TestProducerNextBatchStrictOrdering(t *testing.T) {
+	p := &Producer{StrictOrdering: true}
+	for _, m := range []groupMessage{{"a", "a0"}, {"a", "a1"}, {"b", "b0"}, {"a", "a2"}, {"b", "b1"}, {"c", "c0"}} {
+		p.add(&producerEntry{body: []byte(m.body), opts: SendOptions{MessageGroupId: m.group}, size: 1})
+	}
+
+	var batches [][]string
+	for len(p.pending) > 0 {
+		var bodies []string
+		for _, e := range p.nextBatch() {
+			bodies = append(bodies, string(e.body))
+		}
+		batches = append(batches, bodies)
+	}
+
+	want := [][]string{{"a0", "b0", "c0"}, {"a1", "b1"}, {"a2"}}
+	if !reflect.DeepEqual(batches, want) {
+		t.Errorf("batches = %v, want %v", batches, want)
+	}
+	if p.size != 0 {
+		t.Errorf("size = %d after taking every entry", p.size)
+	}
+}
+
+func TestProducerGivesUpAfterMaxRetries(t *testing.T) {
+	bq := &batchQueue{
+		fail: func(call int, body string) *BatchResultError {
+			if body == "a0" {
+				return &BatchResultError{Code: "InternalError"}
+			}
+			return nil
+		},
+	}
+	p := NewProducer(bq)
+	p.FlushInterval = time.Hour
+	p.MaxRetries = 2
+
+	msgs := []groupMessage{{"a", "a0"}, {"a", "a1"}}
+	results := produce(t, p, msgs)
+
+	attempts := 0
+	for _, b := range bq.batches {
+		for _, body := range b {
+			if body == "a0" {
+				attempts++
+			}
+		}
+	}
+	if attempts != 3 {
+		t.Errorf("a0 was sent %d times, want 3", attempts)
+	}
+
+	for _, res := range results {
+		if failed := string(res.Body) == "a0"; failed != (res.Err != nil) {
+			t.Errorf("result of %s = %v", res.Body, res.Err)
+		}
+	}
+	if !reflect.DeepEqual(bq.sent, []string{"a1"}) {
+		t.Errorf("sent %v, want [a1]", bq.sent)
+	}
+}
+
+func TestProducerRetriesUnreportedEntries(t *testing.T) {
+	bq := &batchQueue{
+		drop: func(call int, body string) bool {
+			return call == 0 && body == "a1"
+		},
+	}
+	p := NewProducer(bq)
+	p.FlushInterval = time.Hour
+
+	msgs := grouped(4, "a", "b")
+	results := produce(t, p, msgs)
+
+	for _, res := range results {
+		if res.Err != nil {
+			t.Errorf("%s failed: %v", res.Body, res.Err)
+		}
+	}
+	if want := [][]string{{"a0", "b0", "a1", "b1"}, {"a1"}}; !reflect.DeepEqual(bq.batches, want) {
+		t.Errorf("batches = %v, want %v", bq.batches, want)
+	}
+}
+
+func TestProducerGivesUpOnUnreportedEntries(t *testing.T) {
+	bq := &batchQueue{drop: func(int, string) bool { return true }}
+	p := NewProducer(bq)
+	p.FlushInterval = time.Hour
+	p.MaxRetries = 1
+
+	results := produce(t, p, []groupMessage{{"a", "a0"}})
+
+	if len(bq.batches) != 2 {
+		t.Errorf("batches = %v, want the message sent twice", bq.batches)
+	}
+	if len(results) != 1 || results[0].Err != errUnreported {
+		t.Errorf("results = %v, want errUnreported", results)
+	}
+}
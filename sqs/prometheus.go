@@ -0,0 +1,197 @@
+package sqs
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request latency
+// histogram. They reach past the 20 second long-poll wait.
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 25}
+
+// PrometheusMetrics collects metrics and serves them in the Prometheus
+// text exposition format, so it can be mounted as a scrape endpoint
+// without depending on the Prometheus client library:
+//
+//	sqs_requests_total{action,status}
+//	sqs_request_duration_seconds{action} (histogram)
+//	sqs_retries_total{action,class}
+//	sqs_batches_total{action}
+//	sqs_batch_entries_total{action}
+//	sqs_receives_total, sqs_empty_receives_total and
+//	sqs_received_messages_total
+type PrometheusMetrics struct {
+	mu            sync.Mutex
+	requests      map[[2]string]uint64
+	latency       map[string]*histogram
+	retries       map[[2]string]uint64
+	batches       map[string]uint64
+	batchEntries  map[string]uint64
+	receives      uint64
+	emptyReceives uint64
+	received      uint64
+}
+
+type histogram struct {
+	buckets []uint64 // cumulative counts, by latencyBuckets
+	count   uint64
+	sum     float64
+}
+
+func NewPrometheusMetrics() *PrometheusMetrics {
+	return &PrometheusMetrics{
+		requests:     make(map[[2]string]uint64),
+		latency:      make(map[string]*histogram),
+		retries:      make(map[[2]string]uint64),
+		batches:      make(map[string]uint64),
+		batchEntries: make(map[string]uint64),
+	}
+}
+
+func (pm *PrometheusMetrics) ObserveRequest(action string, status int, latency time.Duration) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.requests[[2]string{action, strconv.Itoa(status)}]++
+
+	h, ok := pm.latency[action]
+	if !ok {
+		h = &histogram{buckets: make([]uint64, len(latencyBuckets))}
+		pm.latency[action] = h
+	}
+
+	sec := latency.Seconds()
+	for i, le := range latencyBuckets {
+		if sec <= le {
+			h.buckets[i]++
+		}
+	}
+	h.count++
+	h.sum += sec
+}
+
+func (pm *PrometheusMetrics) ObserveRetry(action string, class RetryClass) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.retries[[2]string{action, string(class)}]++
+}
+
+func (pm *PrometheusMetrics) ObserveBatch(action string, entries int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.batches[action]++
+	pm.batchEntries[action] += uint64(entries)
+}
+
+func (pm *PrometheusMetrics) ObserveReceive(messages int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.receives++
+	pm.received += uint64(messages)
+	if messages == 0 {
+		pm.emptyReceives++
+	}
+}
+
+// ServeHTTP writes the current metrics.
+func (pm *PrometheusMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	pm.WriteTo(w)
+}
+
+// WriteTo writes the current metrics to w in the text exposition format.
+func (pm *PrometheusMetrics) WriteTo(w io.Writer) (int64, error) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	var b strings.Builder
+
+	header := func(name, typ, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	}
+
+	header("sqs_requests_total", "counter", "SQS request attempts by action and HTTP status, 0 if none was received.")
+	for _, key := range sortedPairs(pm.requests) {
+		fmt.Fprintf(&b, "sqs_requests_total{action=%s,status=%s} %d\n", quoteLabel(key[0]), quoteLabel(key[1]), pm.requests[key])
+	}
+
+	header("sqs_request_duration_seconds", "histogram", "Latency of SQS request attempts.")
+	actions := make([]string, 0, len(pm.latency))
+	for action := range pm.latency {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		h := pm.latency[action]
+		for i, le := range latencyBuckets {
+			fmt.Fprintf(&b, "sqs_request_duration_seconds_bucket{action=%s,le=\"%g\"} %d\n", quoteLabel(action), le, h.buckets[i])
+		}
+		fmt.Fprintf(&b, "sqs_request_duration_seconds_bucket{action=%s,le=\"+Inf\"} %d\n", quoteLabel(action), h.count)
+		fmt.Fprintf(&b, "sqs_request_duration_seconds_sum{action=%s} %g\n", quoteLabel(action), h.sum)
+		fmt.Fprintf(&b, "sqs_request_duration_seconds_count{action=%s} %d\n", quoteLabel(action), h.count)
+	}
+
+	header("sqs_retries_total", "counter", "Retried SQS requests by action and failure class.")
+	for _, key := range sortedPairs(pm.retries) {
+		fmt.Fprintf(&b, "sqs_retries_total{action=%s,class=%s} %d\n", quoteLabel(key[0]), quoteLabel(key[1]), pm.retries[key])
+	}
+
+	header("sqs_batches_total", "counter", "SQS batch requests by action.")
+	for _, action := range sortedKeys(pm.batches) {
+		fmt.Fprintf(&b, "sqs_batches_total{action=%s} %d\n", quoteLabel(action), pm.batches[action])
+	}
+
+	header("sqs_batch_entries_total", "counter", "Entries of SQS batch requests by action.")
+	for _, action := range sortedKeys(pm.batchEntries) {
+		fmt.Fprintf(&b, "sqs_batch_entries_total{action=%s} %d\n", quoteLabel(action), pm.batchEntries[action])
+	}
+
+	header("sqs_receives_total", "counter", "Receive requests that succeeded.")
+	fmt.Fprintf(&b, "sqs_receives_total %d\n", pm.receives)
+	header("sqs_empty_receives_total", "counter", "Receive requests that returned no messages.")
+	fmt.Fprintf(&b, "sqs_empty_receives_total %d\n", pm.emptyReceives)
+	header("sqs_received_messages_total", "counter", "Messages received.")
+	fmt.Fprintf(&b, "sqs_received_messages_total %d\n", pm.received)
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+func quoteLabel(value string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(value) + `"`
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func sortedPairs(m map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	return keys
+}
@@ -0,0 +1,66 @@
+package sqs
+
+import (
+	"context"
+	"fmt"
+)
+
+type messageKey struct{}
+
+// ContextWithMessage returns a context carrying the message being handled.
+// Consumer does this for every handler it runs.
+func ContextWithMessage(ctx context.Context, m *RecvMessageResponse) context.Context {
+	return context.WithValue(ctx, messageKey{}, m)
+}
+
+// MessageFromContext returns the message being handled in ctx, if any.
+func MessageFromContext(ctx context.Context) (*RecvMessageResponse, bool) {
+	m, ok := ctx.Value(messageKey{}).(*RecvMessageResponse)
+	return m, ok
+}
+
+// PropagationPolicy decides which message attributes a message sent while
+// handling another one inherits from it, such as tenant ids or
+// correlation ids, so that chains of messages stay linked.
+type PropagationPolicy struct {
+	// Attributes are copied from the handled message unless the outgoing
+	// message sets them itself.
+	Attributes []string
+
+	// Required attributes must be present on every message sent with a
+	// context, inherited or not; sends without them fail.
+	Required []string
+}
+
+// apply returns opts extended with the attributes to propagate from the
+// message in ctx. A nil policy propagates nothing.
+func (pp *PropagationPolicy) apply(ctx context.Context, opts *SendOptions) (*SendOptions, error) {
+	if pp == nil {
+		return opts, nil
+	}
+
+	var attrs map[string]string
+	if opts != nil {
+		attrs = opts.MessageAttributes
+	}
+
+	if m, ok := MessageFromContext(ctx); ok {
+		for _, name := range pp.Attributes {
+			if _, set := attrs[name]; set {
+				continue
+			}
+			if value := m.MessageAttribute(name); value != "" {
+				opts = opts.withAttribute(name, value)
+				attrs = opts.MessageAttributes
+			}
+		}
+	}
+
+	for _, name := range pp.Required {
+		if attrs[name] == "" {
+			return nil, fmt.Errorf("Message is missing required attribute %s.", name)
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,354 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Protocol is the wire format of requests to SQS.
+type Protocol string
+
+const (
+	// ProtocolJSON speaks AWS JSON 1.0, signed with Signature Version 4.
+	// It is the default.
+	ProtocolJSON Protocol = "json"
+
+	// ProtocolQuery speaks the legacy query protocol: form-encoded
+	// requests and XML responses, signed with Signature Version 2.
+	ProtocolQuery Protocol = "query"
+)
+
+const jsonTargetPrefix = "AmazonSQS."
+
+// Requests are built as query parameters whatever the protocol. A JSON
+// request is converted from them, and a JSON response is rewritten as the
+// document the query protocol would have returned, so that both protocols
+// share response types and decoding.
+
+// newJSONRequest builds the JSON request equivalent to the query request
+// given by params, and returns it along with the string it signed.
+func (s *SQSRequest) newJSONRequest(ctx context.Context, params map[string]string, isQueueRequest bool) (*http.Request, string, error) {
+	body := jsonRequestBody(params)
+	if isQueueRequest {
+		body["QueueUrl"] = strings.TrimSuffix(s.generateSQSQueueURI(), "/")
+	}
+
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r, err := http.NewRequestWithContext(ctx, "POST", s.generateSQSURI(), bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	r.Header.Set("X-Amz-Target", jsonTargetPrefix+params["Action"])
+	// Asks SQS for the error codes of the query protocol, which callers
+	// and the retry policy check for.
+	r.Header.Set("X-Amzn-Query-Mode", "true")
+
+	region := s.RegionId
+	if region == "" {
+		region = "us-east-1"
+	}
+	stringToSign := signV4(r, sha256Hex(data), s.AWSAccessKey, s.AWSSecret, region, "sqs", time.Now())
+
+	return r, stringToSign, nil
+}
+
+// jsonLists are the JSON names of the indexed query parameters.
+var jsonLists = map[string]string{
+	"AttributeName":        "AttributeNames",
+	"MessageAttributeName": "MessageAttributeNames",
+	"TagKey":               "TagKeys",
+	"AWSAccountId":         "AWSAccountIds",
+	"ActionName":           "Actions",
+	"Attribute":            "Attributes",
+	"Tag":                  "Tags",
+	"MessageAttribute":     "MessageAttributes",
+}
+
+// jsonMaps are the indexed query parameters that are name-value pairs,
+// sent as JSON objects.
+var jsonMaps = map[string]bool{
+	"Attribute":        true,
+	"Tag":              true,
+	"MessageAttribute": true,
+}
+
+// jsonNumbers are the query parameters sent as JSON numbers.
+var jsonNumbers = map[string]bool{
+	"DelaySeconds":                 true,
+	"MaxNumberOfMessages":          true,
+	"MaxNumberOfMessagesPerSecond": true,
+	"MaxResults":                   true,
+	"VisibilityTimeout":            true,
+	"WaitTimeSeconds":              true,
+}
+
+// jsonRequestBody converts query parameters to a JSON request body:
+// indexed parameters, such as AttributeName.1 or
+// SendMessageBatchRequestEntry.1.Id, become lists, and name-value pairs,
+// such as Attribute.1.Name and Attribute.1.Value, become objects.
+func jsonRequestBody(params map[string]string) map[string]interface{} {
+	root := make(paramTree)
+	for key, value := range params {
+		if key != "Action" {
+			root.add(strings.Split(key, "."), value)
+		}
+	}
+
+	body := root.object()
+
+	// CreateQueue alone takes its tags in lower case.
+	if tags, ok := body["Tags"]; ok && params["Action"] == "CreateQueue" {
+		delete(body, "Tags")
+		body["tags"] = tags
+	}
+
+	return body
+}
+
+// paramTree holds query parameters by the parts of their names.
+type paramTree map[string]*paramNode
+
+type paramNode struct {
+	value    string
+	children paramTree
+}
+
+func (t paramTree) add(path []string, value string) {
+	n := t[path[0]]
+	if n == nil {
+		n = new(paramNode)
+		t[path[0]] = n
+	}
+
+	if len(path) == 1 {
+		n.value = value
+		return
+	}
+
+	if n.children == nil {
+		n.children = make(paramTree)
+	}
+	n.children.add(path[1:], value)
+}
+
+// indexed reports whether the tree is a list, keyed by 1-based indexes.
+func (t paramTree) indexed() bool {
+	for key := range t {
+		if i, err := strconv.Atoi(key); err != nil || i < 1 {
+			return false
+		}
+	}
+
+	return len(t) > 0
+}
+
+func (t paramTree) object() map[string]interface{} {
+	obj := make(map[string]interface{}, len(t))
+	for name, n := range t {
+		key := name
+		if n.children.indexed() {
+			if list, ok := jsonLists[name]; ok {
+				key = list
+			} else if strings.HasSuffix(name, "BatchRequestEntry") {
+				key = "Entries"
+			}
+		}
+		obj[key] = n.json(name)
+	}
+
+	return obj
+}
+
+func (n *paramNode) json(name string) interface{} {
+	if n.children == nil {
+		if v, err := strconv.Atoi(n.value); err == nil && jsonNumbers[name] {
+			return v
+		}
+		return n.value
+	}
+
+	if !n.children.indexed() {
+		return n.children.object()
+	}
+
+	items := make([]*paramNode, 0, len(n.children))
+	indexes := make([]int, 0, len(n.children))
+	for key := range n.children {
+		i, _ := strconv.Atoi(key)
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		items = append(items, n.children[strconv.Itoa(i)])
+	}
+
+	if jsonMaps[name] {
+		m := make(map[string]interface{}, len(items))
+		for _, item := range items {
+			key := item.children["Name"]
+			if key == nil {
+				key = item.children["Key"]
+			}
+			if value := item.children["Value"]; key != nil && value != nil {
+				m[key.value] = value.json("Value")
+			}
+		}
+		return m
+	}
+
+	list := make([]interface{}, len(items))
+	for i, item := range items {
+		list[i] = item.json("")
+	}
+
+	return list
+}
+
+// xmlLists are the XML element names of the items of JSON lists. Lists of
+// batch results are named after the action.
+var xmlLists = map[string]string{
+	"Messages":  "Message",
+	"QueueUrls": "QueueUrl",
+	"queueUrls": "QueueUrl",
+	"Failed":    "BatchResultErrorEntry",
+}
+
+// xmlMaps are the XML element and key names of the items of JSON objects
+// that stand for name-value lists.
+var xmlMaps = map[string][2]string{
+	"Attributes":        {"Attribute", "Name"},
+	"MessageAttributes": {"MessageAttribute", "Name"},
+	"Tags":              {"Tag", "Key"},
+}
+
+// queryResponse rewrites the body of a successful JSON response as the
+// XML document the query protocol returns for action.
+func queryResponse(action string, resp *http.Response) (io.ReadCloser, error) {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var v map[string]interface{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err = dec.Decode(&v); err != nil {
+			return nil, err
+		}
+	}
+
+	var b bytes.Buffer
+	b.WriteString("<" + action + "Response><" + action + "Result>")
+	writeXMLFields(&b, action, v)
+	b.WriteString("</" + action + "Result><ResponseMetadata><RequestId>")
+	xml.EscapeText(&b, []byte(resp.Header.Get("X-Amzn-Requestid")))
+	b.WriteString("</RequestId></ResponseMetadata></" + action + "Response>")
+
+	return io.NopCloser(&b), nil
+}
+
+func writeXMLFields(b *bytes.Buffer, action string, obj map[string]interface{}) {
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		writeXMLField(b, action, name, obj[name])
+	}
+}
+
+func writeXMLField(b *bytes.Buffer, action, name string, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+	case []interface{}:
+		elem, ok := xmlLists[name]
+		if !ok && (name == "Successful" || name == "Results") {
+			elem, ok = action+"ResultEntry", true
+		}
+		if !ok {
+			elem = name
+		}
+		for _, item := range v {
+			writeXMLField(b, action, elem, item)
+		}
+	case map[string]interface{}:
+		m, ok := xmlMaps[name]
+		if !ok {
+			b.WriteString("<" + name + ">")
+			writeXMLFields(b, action, v)
+			b.WriteString("</" + name + ">")
+			return
+		}
+
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			b.WriteString("<" + m[0] + "><" + m[1] + ">")
+			xml.EscapeText(b, []byte(key))
+			b.WriteString("</" + m[1] + ">")
+			writeXMLField(b, action, "Value", v[key])
+			b.WriteString("</" + m[0] + ">")
+		}
+	default:
+		b.WriteString("<" + name + ">")
+		xml.EscapeText(b, []byte(fmt.Sprint(v)))
+		b.WriteString("</" + name + ">")
+	}
+}
+
+// jsonError decodes the error document of a failed JSON request. The code
+// and type come from the X-Amzn-Query-Error header when SQS sends it, so
+// that they match those of the query protocol.
+func jsonError(resp *http.Response, body []byte) *ErrorResponse {
+	var v struct {
+		Type    string `json:"__type"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &v) != nil {
+		return nil
+	}
+
+	er := &ErrorResponse{
+		Type:      "Sender",
+		Code:      v.Type[strings.LastIndex(v.Type, "#")+1:],
+		Message:   v.Message,
+		RequestId: resp.Header.Get("X-Amzn-Requestid"),
+	}
+	if resp.StatusCode >= 500 {
+		er.Type = "Receiver"
+	}
+
+	if qe := resp.Header.Get("X-Amzn-Query-Error"); qe != "" {
+		parts := strings.SplitN(qe, ";", 2)
+		er.Code = parts[0]
+		if len(parts) == 2 {
+			er.Type = parts[1]
+		}
+	}
+
+	return er
+}
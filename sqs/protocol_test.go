@@ -0,0 +1,328 @@
+package sqs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// jsonDoc decodes a JSON document for comparison.
+func jsonDoc(t *testing.T, doc string) interface{} {
+	t.Helper()
+
+	var v interface{}
+	if err := json.Unmarshal([]byte(doc), &v); err != nil {
+		t.Fatalf("invalid JSON %s: %v", doc, err)
+	}
+
+	return v
+}
+
+func TestJSONRequestBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   string
+	}{
+		{
+			"create queue",
+			map[string]string{
+				"Action":            "CreateQueue",
+				"QueueName":         "orders",
+				"Attribute.1.Name":  "DelaySeconds",
+				"Attribute.1.Value": "5",
+				"Attribute.2.Name":  "FifoQueue",
+				"Attribute.2.Value": "true",
+				"Tag.1.Key":         "team",
+				"Tag.1.Value":       "payments",
+			},
+			`{"QueueName":"orders","Attributes":{"DelaySeconds":"5","FifoQueue":"true"},"tags":{"team":"payments"}}`,
+		},
+		{
+			"tag queue",
+			map[string]string{
+				"Action":      "TagQueue",
+				"Tag.1.Key":   "team",
+				"Tag.1.Value": "payments",
+				"Tag.2.Key":   "env",
+				"Tag.2.Value": "prod",
+			},
+			`{"Tags":{"team":"payments","env":"prod"}}`,
+		},
+		{
+			"untag queue",
+			map[string]string{
+				"Action":   "UntagQueue",
+				"TagKey.1": "team",
+				"TagKey.2": "env",
+			},
+			`{"TagKeys":["team","env"]}`,
+		},
+		{
+			"receive message",
+			map[string]string{
+				"Action":                 "ReceiveMessage",
+				"AttributeName.1":        "All",
+				"MessageAttributeName.1": "All",
+				"MaxNumberOfMessages":    "10",
+				"WaitTimeSeconds":        "20",
+				"VisibilityTimeout":      "0",
+			},
+			`{"AttributeNames":["All"],"MessageAttributeNames":["All"],"MaxNumberOfMessages":10,"WaitTimeSeconds":20,"VisibilityTimeout":0}`,
+		},
+		{
+			"send message",
+			map[string]string{
+				"Action":                               "SendMessage",
+				"MessageBody":                          "42",
+				"DelaySeconds":                         "5",
+				"MessageGroupId":                       "7",
+				"MessageAttribute.1.Name":              "kind",
+				"MessageAttribute.1.Value.DataType":    "String",
+				"MessageAttribute.1.Value.StringValue": "order",
+				"MessageAttribute.2.Name":              "n",
+				"MessageAttribute.2.Value.DataType":    "String",
+				"MessageAttribute.2.Value.StringValue": "3",
+			},
+			`{"MessageBody":"42","DelaySeconds":5,"MessageGroupId":"7","MessageAttributes":{"kind":{"DataType":"String","StringValue":"order"},"n":{"DataType":"String","StringValue":"3"}}}`,
+		},
+		{
+			"send message batch",
+			map[string]string{
+				"Action":                            "SendMessageBatch",
+				"SendMessageBatchRequestEntry.1.Id": "0",
+				"SendMessageBatchRequestEntry.1.MessageBody":                          "a",
+				"SendMessageBatchRequestEntry.2.Id":                                   "1",
+				"SendMessageBatchRequestEntry.2.MessageBody":                          "b",
+				"SendMessageBatchRequestEntry.2.DelaySeconds":                         "9",
+				"SendMessageBatchRequestEntry.2.MessageAttribute.1.Name":              "kind",
+				"SendMessageBatchRequestEntry.2.MessageAttribute.1.Value.DataType":    "String",
+				"SendMessageBatchRequestEntry.2.MessageAttribute.1.Value.StringValue": "order",
+			},
+			`{"Entries":[{"Id":"0","MessageBody":"a"},{"Id":"1","MessageBody":"b","DelaySeconds":9,"MessageAttributes":{"kind":{"DataType":"String","StringValue":"order"}}}]}`,
+		},
+		{
+			// Indexes sort as numbers, not as strings.
+			"delete message batch",
+			map[string]string{
+				"Action":                              "DeleteMessageBatch",
+				"DeleteMessageBatchRequestEntry.2.Id": "1",
+				"DeleteMessageBatchRequestEntry.2.ReceiptHandle":  "h1",
+				"DeleteMessageBatchRequestEntry.10.Id":            "9",
+				"DeleteMessageBatchRequestEntry.10.ReceiptHandle": "h9",
+				"DeleteMessageBatchRequestEntry.1.Id":             "0",
+				"DeleteMessageBatchRequestEntry.1.ReceiptHandle":  "h0",
+			},
+			`{"Entries":[{"Id":"0","ReceiptHandle":"h0"},{"Id":"1","ReceiptHandle":"h1"},{"Id":"9","ReceiptHandle":"h9"}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(jsonRequestBody(tt.params))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, want := jsonDoc(t, string(data)), jsonDoc(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Errorf("body = %s\nwant   %s", data, tt.want)
+			}
+		})
+	}
+}
+
+// jsonServer answers JSON protocol requests with the canned response of
+// their action, and records the request bodies by action.
+type jsonServer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	responses map[string]string
+	requests  map[string]map[string]interface{}
+}
+
+func newJSONServer(t *testing.T, responses map[string]string) *jsonServer {
+	js := &jsonServer{responses: responses, requests: make(map[string]map[string]interface{})}
+	js.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), jsonTargetPrefix)
+
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("%s: invalid request body: %v", action, err)
+		}
+
+		js.mu.Lock()
+		js.requests[action] = body
+		js.mu.Unlock()
+
+		if r.Header.Get("Content-Type") != "application/x-amz-json-1.0" {
+			t.Errorf("%s: Content-Type = %q", action, r.Header.Get("Content-Type"))
+		}
+
+		resp, ok := js.responses[action]
+		if !ok {
+			w.Header().Set("X-Amzn-Query-Error", "AWS.SimpleQueueService.NonExistentQueue;Sender")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"com.amazonaws.sqs#QueueDoesNotExist","message":"The specified queue does not exist."}`))
+			return
+		}
+		w.Header().Set("X-Amzn-Requestid", "req-1")
+		w.Write([]byte(resp))
+	}))
+	t.Cleanup(js.Close)
+
+	return js
+}
+
+func (js *jsonServer) request(action string) map[string]interface{} {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+
+	return js.requests[action]
+}
+
+func (js *jsonServer) client() *SQSRequest {
+	return &SQSRequest{
+		RegionId:      "us-east-1",
+		UUID:          "123456789012",
+		QueueName:     "orders",
+		AWSAccessKey:  "key",
+		AWSSecret:     "secret",
+		Endpoint:      js.URL,
+		SkipChecksums: true,
+	}
+}
+
+func TestJSONCreateQueueTags(t *testing.T) {
+	js := newJSONServer(t, map[string]string{
+		"CreateQueue": `{"QueueUrl":"https://sqs.us-east-1.amazonaws.com/123456789012/orders"}`,
+		"TagQueue":    `{}`,
+	})
+	s := js.client()
+
+	qur, err := s.CreateQueueWithOptions("orders", &CreateQueueOptions{
+		DelaySeconds: 5,
+		Tags:         map[string]string{"team": "payments"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qur.QueueURL != "https://sqs.us-east-1.amazonaws.com/123456789012/orders" {
+		t.Errorf("QueueURL = %q", qur.QueueURL)
+	}
+
+	want := jsonDoc(t, `{"QueueName":"orders","Attributes":{"DelaySeconds":"5"},"tags":{"team":"payments"}}`)
+	if got := js.request("CreateQueue"); !reflect.DeepEqual(got, want) {
+		t.Errorf("CreateQueue body = %v, want %v", got, want)
+	}
+
+	if _, err = s.TagQueue(map[string]string{"env": "prod"}); err != nil {
+		t.Fatal(err)
+	}
+
+	want = jsonDoc(t, `{"QueueUrl":"`+js.URL+`/123456789012/orders","Tags":{"env":"prod"}}`)
+	if got := js.request("TagQueue"); !reflect.DeepEqual(got, want) {
+		t.Errorf("TagQueue body = %v, want %v", got, want)
+	}
+}
+
+func TestQueryResponse(t *testing.T) {
+	js := newJSONServer(t, map[string]string{
+		"ReceiveMessage": `{"Messages":[{
+			"MessageId":"m1","ReceiptHandle":"h1","MD5OfBody":"x","Body":"hello <&>",
+			"Attributes":{"ApproximateReceiveCount":"3","MessageGroupId":"g"},
+			"MessageAttributes":{"kind":{"DataType":"String","StringValue":"order"}}
+		},{"MessageId":"m2","ReceiptHandle":"h2","Body":"second"}]}`,
+		"SendMessageBatch": `{
+			"Successful":[{"Id":"0","MessageId":"m1","MD5OfMessageBody":"x"}],
+			"Failed":[{"Id":"1","SenderFault":true,"Code":"InvalidParameterValue","Message":"bad"}]
+		}`,
+		"ListQueues":         `{"QueueUrls":["https://q/1/a","https://q/1/b"],"NextToken":"t"}`,
+		"GetQueueAttributes": `{"Attributes":{"ApproximateNumberOfMessages":"12","VisibilityTimeout":"30"}}`,
+		"ListQueueTags":      `{"Tags":{"team":"payments"}}`,
+		"PurgeQueue":         ``,
+	})
+	s := js.client()
+
+	msgs, err := s.ReceiveSQSMessages(10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("received %d messages, want 2", len(msgs))
+	}
+	m := msgs[0]
+	if m.MessageId != "m1" || m.ReceiptHandle != "h1" || m.MessageBody != "hello <&>" {
+		t.Errorf("message = %+v", m)
+	}
+	if m.ReceiveCount() != 3 || m.MessageGroupId() != "g" || m.MessageAttribute("kind") != "order" {
+		t.Errorf("attributes = %+v, message attributes = %+v", m.Attributes, m.MessageAttributes)
+	}
+	if m.RequestId != "req-1" {
+		t.Errorf("RequestId = %q", m.RequestId)
+	}
+
+	smr, err := s.SendSQSMessageBatch([]BatchEntry{{Id: "0", Body: []byte("a")}, {Id: "1", Body: []byte("b")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(smr.Successful) != 1 || smr.Successful[0].MessageId != "m1" {
+		t.Errorf("Successful = %+v", smr.Successful)
+	}
+	if len(smr.Failed) != 1 || !smr.Failed[0].SenderFault || smr.Failed[0].Code != "InvalidParameterValue" {
+		t.Errorf("Failed = %+v", smr.Failed)
+	}
+
+	qlr, err := s.ListQueues("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(qlr.QueueURLs, []string{"https://q/1/a", "https://q/1/b"}) || qlr.NextToken != "t" {
+		t.Errorf("ListQueues = %+v", qlr)
+	}
+
+	qar, err := s.GetQueueAttributes(AttrAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qar.Attributes.ApproximateNumberOfMessages != 12 || qar.Attributes.VisibilityTimeout != 30 {
+		t.Errorf("attributes = %+v", qar.Attributes)
+	}
+
+	qtr, err := s.ListQueueTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(qtr.Tags, map[string]string{"team": "payments"}) {
+		t.Errorf("tags = %v", qtr.Tags)
+	}
+
+	// Actions without a result answer with an empty body.
+	if _, err = s.PurgeQueue(); err != nil {
+		t.Errorf("PurgeQueue: %v", err)
+	}
+}
+
+func TestJSONError(t *testing.T) {
+	js := newJSONServer(t, nil)
+
+	_, err := js.client().QueueURL()
+	er, ok := err.(*ErrorResponse)
+	if !ok {
+		t.Fatalf("error = %v, want an *ErrorResponse", err)
+	}
+	if er.Code != "AWS.SimpleQueueService.NonExistentQueue" || er.Type != "Sender" || er.Message != "The specified queue does not exist." {
+		t.Errorf("error = %+v", er)
+	}
+
+	// Without the query error header, the code comes from the JSON type.
+	resp := &http.Response{StatusCode: 500, Header: http.Header{}}
+	er = jsonError(resp, []byte(`{"__type":"com.amazonaws.sqs#InternalError","message":"oops"}`))
+	if er.Code != "InternalError" || er.Type != "Receiver" {
+		t.Errorf("error = %+v", er)
+	}
+}
@@ -0,0 +1,238 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strconv"
+)
+
+// QueueReader reads a queue as newline-delimited messages: the body of
+// each message followed by a newline. Messages are deleted once read in
+// full, in batches, and the stream ends with io.EOF at the first receive
+// that comes back empty. With a WaitSeconds of zero, receives are short
+// polls, which may come back empty before the queue is; see
+// DrainAvailable.
+//
+// Close deletes what was read and releases what was not. Bodies are not
+// escaped, so messages should not contain newlines themselves.
+type QueueReader struct {
+	Queue       SQSClient
+	WaitSeconds int
+
+	msgs     []*RecvMessageResponse // received and not yet started
+	current  *RecvMessageResponse
+	buf      []byte // unread part of the current message
+	consumed []string
+	err      error
+}
+
+func NewQueueReader(queue SQSClient) *QueueReader {
+	return &QueueReader{Queue: queue}
+}
+
+func (qr *QueueReader) Read(p []byte) (int, error) {
+	for len(qr.buf) == 0 {
+		if qr.err != nil {
+			return 0, qr.err
+		}
+		qr.err = qr.next()
+	}
+
+	n := copy(p, qr.buf)
+	qr.buf = qr.buf[n:]
+	if len(qr.buf) == 0 {
+		qr.consumed = append(qr.consumed, qr.current.ReceiptHandle)
+		qr.current = nil
+	}
+
+	return n, nil
+}
+
+// next starts on the next message, receiving more once those at hand
+// have been read.
+func (qr *QueueReader) next() error {
+	if len(qr.msgs) == 0 {
+		if err := qr.deleteConsumed(); err != nil {
+			return err
+		}
+
+		msgs, err := qr.Queue.ReceiveSQSMessagesContext(context.Background(), maxBatchEntries, qr.WaitSeconds)
+		if err != nil {
+			return err
+		}
+		if len(msgs) == 0 {
+			return io.EOF
+		}
+		qr.msgs = msgs
+	}
+
+	qr.current, qr.msgs = qr.msgs[0], qr.msgs[1:]
+	qr.buf = append([]byte(qr.current.MessageBody), '\n')
+
+	return nil
+}
+
+func (qr *QueueReader) deleteConsumed() error {
+	if len(qr.consumed) == 0 {
+		return nil
+	}
+
+	err := qr.Queue.DeleteAll(context.Background(), qr.consumed)
+	qr.consumed = nil
+
+	return err
+}
+
+// Close deletes the messages read in full and makes the rest visible
+// again, including one read in part.
+func (qr *QueueReader) Close() error {
+	err := qr.deleteConsumed()
+
+	unread := qr.msgs
+	if qr.current != nil {
+		unread = append([]*RecvMessageResponse{qr.current}, unread...)
+	}
+	qr.msgs, qr.current, qr.buf = nil, nil, nil
+	qr.err = io.ErrClosedPipe
+
+	if len(unread) > 0 {
+		if rerr := qr.Queue.ReleaseAll(context.Background(), unread); err == nil {
+			err = rerr
+		}
+	}
+
+	return err
+}
+
+// QueueWriter writes newline-delimited messages to a queue: every line
+// written becomes a message, without its newline, sent with Options.
+// Lines are sent in batches once ten of them are buffered, or sooner if
+// the batch would exceed the SQS request size limit; Flush sends the rest,
+// and Close sends a final line that lacks a newline as well.
+//
+// A failed send is reported by the Write, Flush or Close that made it.
+// Lines that failed through no fault of the sender stay buffered and are
+// sent again by the next one.
+type QueueWriter struct {
+	Queue   SQSClient
+	Options SendOptions
+
+	partial []byte
+	batch   [][]byte
+	size    int
+}
+
+func NewQueueWriter(queue SQSClient) *QueueWriter {
+	return &QueueWriter{Queue: queue}
+}
+
+func (qw *QueueWriter) Write(p []byte) (int, error) {
+	qw.partial = append(qw.partial, p...)
+
+	for {
+		i := bytes.IndexByte(qw.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		line := append([]byte(nil), qw.partial[:i]...)
+		qw.partial = qw.partial[i+1:]
+
+		if err := qw.add(line); err != nil {
+			return len(p), err
+		}
+	}
+
+	return len(p), nil
+}
+
+// add buffers a line, sending the batch before it would grow too large
+// and once it is full.
+func (qw *QueueWriter) add(line []byte) error {
+	size, err := qw.lineSize(line)
+	if err != nil {
+		return err
+	}
+
+	if len(qw.batch) > 0 && qw.size+size > maxBatchBytes {
+		if err = qw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	qw.batch = append(qw.batch, line)
+	qw.size += size
+
+	if len(qw.batch) >= maxBatchEntries {
+		return qw.Flush()
+	}
+
+	return nil
+}
+
+// lineSize returns the size a line takes up in a batch, once encoded as
+// the queue's settings say.
+func (qw *QueueWriter) lineSize(line []byte) (int, error) {
+	s := settings(qw.Queue)
+	encoded, encodedOpts, err := s.encodeBody(line, &qw.Options)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.offloadSize(encoded, encodedOpts), nil
+}
+
+// Flush sends the complete lines buffered so far.
+func (qw *QueueWriter) Flush() error {
+	if len(qw.batch) == 0 {
+		return nil
+	}
+
+	entries := make([]BatchEntry, len(qw.batch))
+	for i, line := range qw.batch {
+		entries[i] = BatchEntry{strconv.Itoa(i), line, qw.Options}
+	}
+
+	smr, err := qw.Queue.SendSQSMessageBatch(entries)
+	if err != nil {
+		return err
+	}
+
+	// Lines SQS may yet accept are kept, in order, for the next attempt.
+	retryable := make(map[string]bool, len(smr.Failed))
+	for _, f := range smr.Failed {
+		retryable[f.Id] = !f.SenderFault
+	}
+
+	var kept [][]byte
+	qw.size = 0
+	for i, line := range qw.batch {
+		if retryable[strconv.Itoa(i)] {
+			kept = append(kept, line)
+			size, _ := qw.lineSize(line)
+			qw.size += size
+		}
+	}
+	qw.batch = kept
+
+	if len(smr.Failed) > 0 {
+		return BatchError(smr.Failed)
+	}
+
+	return nil
+}
+
+// Close sends everything buffered, including a final line that lacks a
+// newline.
+func (qw *QueueWriter) Close() error {
+	if len(qw.partial) > 0 {
+		line := qw.partial
+		qw.partial = nil
+		if err := qw.add(line); err != nil {
+			return err
+		}
+	}
+
+	return qw.Flush()
+}
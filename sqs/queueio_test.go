@@ -0,0 +1,214 @@
+package sqs_test
+
+import (
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+	"github.com/neurodrone/aws-sqs/sqs/sqstest"
+)
+
+// batchRecorder records the size of every SendMessageBatch request made
+// to a fake queue. fail, if set, decides which entries of each request
+// fail through no fault of the sender.
+type batchRecorder struct {
+	*sqstest.Queue
+
+	sizes []int
+	fail  func(call int, body string) bool
+}
+
+func (br *batchRecorder) SendSQSMessageBatch(entries []sqs.BatchEntry) (*sqs.SendMessageBatchResponse, error) {
+	call := len(br.sizes)
+	br.sizes = append(br.sizes, len(entries))
+
+	var failed []sqs.BatchResultError
+	var rest []sqs.BatchEntry
+	for _, e := range entries {
+		if br.fail != nil && br.fail(call, string(e.Body)) {
+			failed = append(failed, sqs.BatchResultError{Id: e.Id, Code: "InternalError", Message: "try again"})
+			continue
+		}
+		rest = append(rest, e)
+	}
+
+	smr := &sqs.SendMessageBatchResponse{}
+	if len(rest) > 0 {
+		var err error
+		if smr, err = br.Queue.SendSQSMessageBatch(rest); err != nil {
+			return nil, err
+		}
+	}
+	smr.Failed = append(smr.Failed, failed...)
+
+	return smr, nil
+}
+
+// receiveAll returns the bodies of the messages on q, leaving them in
+// flight.
+func receiveAll(t *testing.T, q *sqstest.Queue) []string {
+	t.Helper()
+
+	var bodies []string
+	for {
+		msgs, err := q.ReceiveSQSMessages(10, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(msgs) == 0 {
+			return bodies
+		}
+		for _, m := range msgs {
+			bodies = append(bodies, m.MessageBody)
+		}
+	}
+}
+
+func TestQueueWriterSplitsLines(t *testing.T) {
+	f := sqstest.New()
+	q := createQueue(t, f, "lines")
+	qw := sqs.NewQueueWriter(q)
+
+	for _, chunk := range []string{"a\nb", "", "\nc", "c\nd"} {
+		if _, err := qw.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := qw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveAll(t, q); !reflect.DeepEqual(got, []string{"a", "b", "cc"}) {
+		t.Errorf("after Flush the queue holds %q", got)
+	}
+
+	// The last line, without a newline, is only sent by Close.
+	if _, err := qw.Write([]byte("e")); err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveAll(t, q); len(got) != 0 {
+		t.Errorf("partial line sent early: %q", got)
+	}
+	if err := qw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := receiveAll(t, q); !reflect.DeepEqual(got, []string{"de"}) {
+		t.Errorf("after Close the queue holds %q", got)
+	}
+}
+
+func TestQueueWriterBatchLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines int
+		size  int
+		want  []int
+	}{
+		{"ten lines", 12, 1, []int{10, 2}},
+		{"request size", 5, 100 * 1024, []int{2, 2, 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := sqstest.New()
+			br := &batchRecorder{Queue: createQueue(t, f, "lines")}
+			qw := sqs.NewQueueWriter(br)
+
+			line := strings.Repeat("x", tt.size) + "\n"
+			for i := 0; i < tt.lines; i++ {
+				if _, err := qw.Write([]byte(line)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := qw.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(br.sizes, tt.want) {
+				t.Errorf("batch sizes = %v, want %v", br.sizes, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueueWriterRetriesBufferedLines(t *testing.T) {
+	f := sqstest.New()
+	q := createQueue(t, f, "lines")
+	br := &batchRecorder{Queue: q, fail: func(call int, body string) bool { return call == 0 && body == "b" }}
+	qw := sqs.NewQueueWriter(br)
+
+	qw.Write([]byte("a\nb\nc\n"))
+	err := qw.Flush()
+	if _, ok := err.(sqs.BatchError); !ok {
+		t.Fatalf("Flush = %v, want a BatchError", err)
+	}
+
+	qw.Write([]byte("d\n"))
+	if err = qw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := receiveAll(t, q); !reflect.DeepEqual(got, []string{"a", "c", "b", "d"}) {
+		t.Errorf("queue holds %q, want b sent again before d", got)
+	}
+}
+
+func TestQueueReaderReadsUntilEmpty(t *testing.T) {
+	f := sqstest.New()
+	q := createQueue(t, f, "lines")
+
+	qr := sqs.NewQueueReader(q)
+	if n, err := qr.Read(make([]byte, 10)); n != 0 || err != io.EOF {
+		t.Errorf("Read on an empty queue = %d, %v, want io.EOF", n, err)
+	}
+
+	for _, body := range []string{"a", "bb", "ccc"} {
+		if _, err := q.SendSQSMessage([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	qr = sqs.NewQueueReader(q)
+	b, err := ioutil.ReadAll(qr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "a\nbb\nccc\n" {
+		t.Errorf("read %q", b)
+	}
+	if err = qr.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := messages(t, q); n != 0 {
+		t.Errorf("%d messages left after reading them all", n)
+	}
+}
+
+func TestQueueReaderCloseReleasesUnread(t *testing.T) {
+	f := sqstest.New()
+	q := createQueue(t, f, "lines")
+	for _, body := range []string{"a", "b", "c"} {
+		if _, err := q.SendSQSMessage([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Read a and half of b.
+	qr := sqs.NewQueueReader(q)
+	if b, _ := ioutil.ReadAll(io.LimitReader(qr, 3)); string(b) != "a\nb" {
+		t.Fatalf("read %q", b)
+	}
+	if err := qr.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := receiveAll(t, q); !reflect.DeepEqual(got, []string{"b", "c"}) {
+		t.Errorf("after Close the queue holds %q, want a deleted and b and c released", got)
+	}
+	if _, err := qr.Read(make([]byte, 1)); err != io.ErrClosedPipe {
+		t.Errorf("Read after Close = %v", err)
+	}
+}
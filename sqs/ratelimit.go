@@ -0,0 +1,157 @@
+package sqs
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the requests of the clients sharing it, both in rate,
+// with a token bucket, and in the number in flight at once. When SQS
+// throttles a request, the rate is halved, then recovers gradually to
+// Rate. Each attempt of a retried request counts as a request; long polls
+// count as in flight for as long as they wait.
+type RateLimiter struct {
+	// Rate is the number of requests per second, unlimited if zero. Burst
+	// is the number that may be made at once, defaulting to Rate rounded
+	// up.
+	Rate  float64
+	Burst int
+
+	// MaxInFlight limits concurrent requests, unlimited if zero.
+	MaxInFlight int
+
+	// MinRate is the lowest rate throttling reduces to, defaulting to a
+	// tenth of Rate. Recovery is how long the rate takes to recover from
+	// MinRate to Rate, defaulting to 30 seconds.
+	MinRate  float64
+	Recovery time.Duration
+
+	once     sync.Once
+	inFlight chan struct{}
+
+	mu      sync.Mutex
+	tokens  float64
+	current float64
+	last    time.Time
+}
+
+func NewRateLimiter(rate float64, maxInFlight int) *RateLimiter {
+	return &RateLimiter{Rate: rate, MaxInFlight: maxInFlight}
+}
+
+func (rl *RateLimiter) init() {
+	if rl.MaxInFlight > 0 {
+		rl.inFlight = make(chan struct{}, rl.MaxInFlight)
+	}
+
+	rl.current = rl.Rate
+	rl.tokens = float64(rl.burst())
+	rl.last = time.Now()
+}
+
+func (rl *RateLimiter) burst() int {
+	if rl.Burst > 0 {
+		return rl.Burst
+	}
+
+	return int(math.Max(1, math.Ceil(rl.Rate)))
+}
+
+func (rl *RateLimiter) minRate() float64 {
+	if rl.MinRate > 0 && rl.MinRate < rl.Rate {
+		return rl.MinRate
+	}
+
+	return rl.Rate / 10
+}
+
+// refill adds the tokens earned since the last call and lets the rate
+// recover. It must be called with mu held.
+func (rl *RateLimiter) refill(now time.Time) {
+	elapsed := now.Sub(rl.last)
+	rl.last = now
+
+	recovery := rl.Recovery
+	if recovery <= 0 {
+		recovery = 30 * time.Second
+	}
+	rl.current += (rl.Rate - rl.minRate()) * elapsed.Seconds() / recovery.Seconds()
+	if rl.current > rl.Rate {
+		rl.current = rl.Rate
+	}
+
+	rl.tokens += rl.current * elapsed.Seconds()
+	if max := float64(rl.burst()); rl.tokens > max {
+		rl.tokens = max
+	}
+}
+
+// Wait blocks until a request may be made, or ctx is done. The returned
+// function must be called once the request has completed.
+func (rl *RateLimiter) Wait(ctx context.Context) (func(), error) {
+	rl.once.Do(rl.init)
+
+	done := func() {}
+	if rl.inFlight != nil {
+		select {
+		case rl.inFlight <- struct{}{}:
+			done = func() { <-rl.inFlight }
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if rl.Rate <= 0 {
+		return done, nil
+	}
+
+	for {
+		rl.mu.Lock()
+		rl.refill(time.Now())
+		if rl.tokens >= 1 {
+			rl.tokens--
+			rl.mu.Unlock()
+			return done, nil
+		}
+		wait := time.Duration((1 - rl.tokens) / rl.current * float64(time.Second))
+		rl.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			done()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Throttled halves the current rate, down to MinRate. The client calls it
+// when SQS throttles a request.
+func (rl *RateLimiter) Throttled() {
+	rl.once.Do(rl.init)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.refill(time.Now())
+	rl.current /= 2
+	if min := rl.minRate(); rl.current < min {
+		rl.current = min
+	}
+}
+
+// CurrentRate returns the rate currently allowed, lower than Rate after
+// throttling.
+func (rl *RateLimiter) CurrentRate() float64 {
+	rl.once.Do(rl.init)
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	rl.refill(time.Now())
+	return rl.current
+}
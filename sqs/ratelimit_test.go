@@ -0,0 +1,131 @@
+package sqs
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurst(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		want  int
+	}{
+		{"rate rounded up", 2.5, 0, 3},
+		{"slow rate", 0.1, 0, 1},
+		{"explicit burst", 1, 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := &RateLimiter{Rate: tt.rate, Burst: tt.burst}
+
+			for i := 0; i < tt.want; i++ {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+				done, err := rl.Wait(ctx)
+				cancel()
+				if err != nil {
+					t.Fatalf("request %d of the burst: %v", i+1, err)
+				}
+				done()
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			if _, err := rl.Wait(ctx); err != context.DeadlineExceeded {
+				t.Errorf("request past the burst = %v, want it to wait", err)
+			}
+		})
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	t0 := time.Now()
+
+	tests := []struct {
+		name        string
+		rate        float64
+		current     float64 // after throttling
+		tokens      float64
+		elapsed     time.Duration
+		wantTokens  float64
+		wantCurrent float64
+	}{
+		{"earns tokens at the rate", 10, 10, 0, 250 * time.Millisecond, 2.5, 10},
+		{"capped at the burst", 10, 10, 5, time.Minute, 10, 10},
+		// Recovery from MinRate, 1, to Rate takes 30 seconds: in 15
+		// seconds the rate climbs halfway.
+		{"recovers halfway", 10, 1, 0, 15 * time.Second, 10, 5.5},
+		{"recovers fully", 10, 1, 0, time.Minute, 10, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rl := &RateLimiter{Rate: tt.rate}
+			rl.once.Do(rl.init)
+			rl.current, rl.tokens, rl.last = tt.current, tt.tokens, t0
+
+			rl.refill(t0.Add(tt.elapsed))
+
+			if math.Abs(rl.tokens-tt.wantTokens) > 1e-9 {
+				t.Errorf("tokens = %v, want %v", rl.tokens, tt.wantTokens)
+			}
+			if math.Abs(rl.current-tt.wantCurrent) > 1e-9 {
+				t.Errorf("rate = %v, want %v", rl.current, tt.wantCurrent)
+			}
+		})
+	}
+}
+
+func TestRateLimiterThrottled(t *testing.T) {
+	tests := []struct {
+		name      string
+		minRate   float64
+		throttles int
+		want      float64
+	}{
+		{"once", 0, 1, 50},
+		{"twice", 0, 2, 25},
+		{"down to a tenth", 0, 10, 10},
+		{"down to MinRate", 30, 2, 30},
+		{"MinRate above Rate is ignored", 200, 10, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A long recovery keeps the rate from climbing back during
+			// the test.
+			rl := &RateLimiter{Rate: 100, MinRate: tt.minRate, Recovery: 1000 * time.Hour}
+			for i := 0; i < tt.throttles; i++ {
+				rl.Throttled()
+			}
+
+			if got := rl.CurrentRate(); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("CurrentRate = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterMaxInFlight(t *testing.T) {
+	rl := NewRateLimiter(0, 1)
+
+	done, err := rl.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err = rl.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("second request in flight = %v, want it to wait", err)
+	}
+
+	done()
+	if _, err = rl.Wait(context.Background()); err != nil {
+		t.Errorf("request after the first completed: %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package sqs
+
+import (
+	"fmt"
+)
+
+// readOnlyActions are the actions a ReadOnly client may perform. Receives
+// are additionally restricted to peeks, see allowedReadOnly.
+var readOnlyActions = map[string]bool{
+	"GetQueueUrl":                true,
+	"ListQueues":                 true,
+	"GetQueueAttributes":         true,
+	"ListQueueTags":              true,
+	"ListDeadLetterSourceQueues": true,
+	"ListMessageMoveTasks":       true,
+	"ReceiveMessage":             true,
+}
+
+// ReadOnlyError is returned by a ReadOnly client for any action that could
+// change a queue or its messages.
+type ReadOnlyError struct {
+	Action string
+}
+
+func (roe *ReadOnlyError) Error() string {
+	return fmt.Sprintf("%s is not permitted on a read-only client.", roe.Action)
+}
+
+// allowedReadOnly reports whether the request described by params is
+// permitted on a read-only client. A receive only qualifies if it leaves
+// the messages visible, as Peek does.
+func allowedReadOnly(params map[string]string) bool {
+	action := params["Action"]
+	if action == "ReceiveMessage" {
+		return params["VisibilityTimeout"] == "0"
+	}
+
+	return readOnlyActions[action]
+}
@@ -0,0 +1,149 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// RelayEntry describes a single message in transit between the source and
+// destination queues of a Relay.
+type RelayEntry struct {
+	SourceMessageId string
+	ReceiptHandle   string
+	DestMessageId   string
+}
+
+// RelayJournal records the two phases of a relayed message: Sent once the
+// destination has confirmed the message, and Done once the source copy has
+// been deleted. Entries that are Sent but not Done are returned by Pending.
+type RelayJournal interface {
+	Sent(entry RelayEntry) error
+	Done(entry RelayEntry) error
+	Pending() ([]RelayEntry, error)
+}
+
+// Relay moves messages from Source to Dest. A source message is only
+// deleted after Dest has acknowledged the send with a matching MD5, so a
+// failure at any point leaves the message on at least one of the queues.
+type Relay struct {
+	Source  SQSClient
+	Dest    SQSClient
+	Journal RelayJournal
+
+	// Audit, if set, receives a record for every message the relay
+	// attempts to transfer, tagged with WorkerId.
+	Audit    AuditSink
+	WorkerId string
+}
+
+// RelayOne transfers a single message from Source to Dest.
+func (r *Relay) RelayOne() (*RelayEntry, error) {
+	rmr, err := r.Source.ReceiveSQSMessage()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	entry, err := r.relay(rmr)
+
+	if r.Audit != nil {
+		rec := AuditRecord{
+			Time:         start,
+			MessageId:    rmr.MessageId,
+			ReceiveCount: rmr.ReceiveCount(),
+			Outcome:      "relayed",
+			Duration:     time.Since(start),
+			WorkerId:     r.WorkerId,
+		}
+		if err != nil {
+			rec.Outcome = "failed"
+			rec.Error = err.Error()
+		}
+		r.Audit.Record(rec)
+	}
+
+	return entry, err
+}
+
+func (r *Relay) relay(rmr *RecvMessageResponse) (*RelayEntry, error) {
+	entry := &RelayEntry{
+		SourceMessageId: rmr.MessageId,
+		ReceiptHandle:   rmr.ReceiptHandle,
+	}
+
+	// The send is only confirmed by a matching MD5, whatever the
+	// destination client's settings. An SQSRequest checks it on the
+	// body as encoded for the wire; other clients send the body as is.
+	dest := r.Dest
+	req, ok := dest.(requester)
+	if ok && req.request().SkipChecksums {
+		checked := *req.request()
+		checked.SkipChecksums = false
+		dest = &checked
+	}
+
+	smr, err := dest.SendSQSMessageWithOptions([]byte(rmr.MessageBody), forwardOptions(rmr, dest))
+	if err == nil && !ok {
+		err = (&SQSRequest{}).verifyMessage(smr.MessageId, rmr.MessageBody, smr.MessageMD5, nil, "")
+	}
+	if err != nil {
+		var ce *ChecksumError
+		if errors.As(err, &ce) {
+			return nil, fmt.Errorf("Relay of message %s was not confirmed: %w", rmr.MessageId, err)
+		}
+		return nil, err
+	}
+	entry.DestMessageId = smr.MessageId
+
+	if r.Journal != nil {
+		if err = r.Journal.Sent(*entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return entry, r.complete(*entry)
+}
+
+// Recover finishes entries the journal recorded as sent but not yet
+// deleted from the source, e.g. after a crash between the two phases. If a
+// receipt handle has since expired the source message is redelivered and
+// relayed again, so messages may be duplicated but never lost; the entry
+// is cleared from the journal either way. Entries whose source copy could
+// not be deleted for any other reason are kept for the next Recover.
+func (r *Relay) Recover() error {
+	if r.Journal == nil {
+		return errors.New("Relay has no journal to recover from.")
+	}
+
+	pending, err := r.Journal.Pending()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range pending {
+		_, err = r.Source.DeleteSQSMessage(entry.ReceiptHandle)
+		var er *ErrorResponse
+		if err != nil && !(errors.As(err, &er) && er.Code == "ReceiptHandleIsInvalid") {
+			return err
+		}
+
+		if err = r.Journal.Done(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Relay) complete(entry RelayEntry) error {
+	if _, err := r.Source.DeleteSQSMessage(entry.ReceiptHandle); err != nil {
+		return err
+	}
+
+	if r.Journal != nil {
+		return r.Journal.Done(entry)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package sqs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+	"github.com/neurodrone/aws-sqs/sqs/sqstest"
+)
+
+// memJournal is a RelayJournal in memory, whose phases can be made to
+// fail.
+type memJournal struct {
+	entries            map[string]sqs.RelayEntry
+	failSent, failDone bool
+}
+
+func (mj *memJournal) Sent(entry sqs.RelayEntry) error {
+	if mj.failSent {
+		return errors.New("journal is full")
+	}
+	mj.entries[entry.SourceMessageId] = entry
+	return nil
+}
+
+func (mj *memJournal) Done(entry sqs.RelayEntry) error {
+	if mj.failDone {
+		return errors.New("journal is full")
+	}
+	delete(mj.entries, entry.SourceMessageId)
+	return nil
+}
+
+func (mj *memJournal) Pending() ([]sqs.RelayEntry, error) {
+	var pending []sqs.RelayEntry
+	for _, e := range mj.entries {
+		pending = append(pending, e)
+	}
+	return pending, nil
+}
+
+// faultyQueue is a fake queue whose sends fail or come back with a wrong
+// MD5, and whose deletes fail while deleteErr is set.
+type faultyQueue struct {
+	*sqstest.Queue
+
+	sendErr   error
+	badMD5    bool
+	deleteErr error
+}
+
+func (fq *faultyQueue) SendSQSMessageWithOptions(message []byte, opts *sqs.SendOptions) (*sqs.SendMessageResponse, error) {
+	if fq.sendErr != nil {
+		return nil, fq.sendErr
+	}
+
+	smr, err := fq.Queue.SendSQSMessageWithOptions(message, opts)
+	if err == nil && fq.badMD5 {
+		smr.MessageMD5 = "00000000000000000000000000000000"
+	}
+	return smr, err
+}
+
+func (fq *faultyQueue) DeleteSQSMessage(handle string) (*sqs.BasicResponse, error) {
+	if fq.deleteErr != nil {
+		return nil, fq.deleteErr
+	}
+	return fq.Queue.DeleteSQSMessage(handle)
+}
+
+func newRelay(t *testing.T) (*sqs.Relay, *faultyQueue, *faultyQueue, *memJournal) {
+	f := sqstest.New()
+	src := &faultyQueue{Queue: createQueue(t, f, "orders")}
+	dst := &faultyQueue{Queue: createQueue(t, f, "orders-copy")}
+	if _, err := src.SendSQSMessage([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	journal := &memJournal{entries: make(map[string]sqs.RelayEntry)}
+	return &sqs.Relay{Source: src, Dest: dst, Journal: journal}, src, dst, journal
+}
+
+func TestRelayOne(t *testing.T) {
+	failure := errors.New("connection reset")
+
+	tests := []struct {
+		name                  string
+		sendErr               error
+		badMD5                bool
+		failSent, failDone    bool
+		deleteErr             error
+		relayed               bool
+		checksum              bool // fails with a ChecksumError
+		source, dest, pending int
+		sourceAfterRecover    int
+	}{
+		{name: "relayed", relayed: true, dest: 1},
+		{name: "dest fails", sendErr: failure, source: 1, sourceAfterRecover: 1},
+		{name: "MD5 mismatch", badMD5: true, checksum: true, source: 1, dest: 1, sourceAfterRecover: 1},
+		{name: "journal fails before the send is recorded", failSent: true, source: 1, dest: 1, sourceAfterRecover: 1},
+		{name: "crash between phases", deleteErr: failure, source: 1, dest: 1, pending: 1},
+		{name: "journal fails after the delete", failDone: true, dest: 1, pending: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, src, dst, journal := newRelay(t)
+			dst.sendErr, dst.badMD5 = tt.sendErr, tt.badMD5
+			journal.failSent, journal.failDone = tt.failSent, tt.failDone
+			src.deleteErr = tt.deleteErr
+
+			entry, err := r.RelayOne()
+			if (err == nil) != tt.relayed {
+				t.Fatalf("RelayOne = %+v, %v", entry, err)
+			}
+			var ce *sqs.ChecksumError
+			if errors.As(err, &ce) != tt.checksum {
+				t.Errorf("RelayOne error = %v, want a ChecksumError: %v", err, tt.checksum)
+			}
+
+			if got := messages(t, src.Queue); got != tt.source {
+				t.Errorf("%d messages on the source, want %d", got, tt.source)
+			}
+			if got := messages(t, dst.Queue); got != tt.dest {
+				t.Errorf("%d messages on the destination, want %d", got, tt.dest)
+			}
+			if got := len(journal.entries); got != tt.pending {
+				t.Fatalf("%d entries pending, want %d", got, tt.pending)
+			}
+
+			// Recover finishes the transfer once the faults are gone.
+			src.deleteErr, journal.failDone = nil, false
+			if err = r.Recover(); err != nil {
+				t.Fatalf("Recover: %v", err)
+			}
+			if got := messages(t, src.Queue); got != tt.sourceAfterRecover {
+				t.Errorf("%d messages on the source after Recover, want %d", got, tt.sourceAfterRecover)
+			}
+			if got := len(journal.entries); got != 0 {
+				t.Errorf("%d entries pending after Recover", got)
+			}
+		})
+	}
+}
+
+func TestRelayRecoverExpiredHandle(t *testing.T) {
+	r, src, dst, journal := newRelay(t)
+	src.deleteErr = errors.New("connection reset")
+	if _, err := r.RelayOne(); err == nil {
+		t.Fatal("RelayOne succeeded without deleting the source message")
+	}
+
+	// Another receive of the message, once it is visible again,
+	// invalidates the journaled receipt handle.
+	src.deleteErr = nil
+	pending, _ := journal.Pending()
+	if _, err := src.ChangeMessageVisibility(pending[0].ReceiptHandle, 0); err != nil {
+		t.Fatal(err)
+	}
+	msgs, err := src.ReceiveSQSMessages(1, 0)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("receive = %v, %v", msgs, err)
+	}
+
+	if err = r.Recover(); err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if len(journal.entries) != 0 {
+		t.Errorf("%d entries pending after Recover", len(journal.entries))
+	}
+
+	// The message stays on the source, to be relayed again: a duplicate,
+	// not a loss.
+	if got := messages(t, src.Queue); got != 1 {
+		t.Errorf("%d messages on the source, want 1", got)
+	}
+	if got := messages(t, dst.Queue); got != 1 {
+		t.Errorf("%d messages on the destination, want 1", got)
+	}
+}
+
+func TestRelayRecoverKeepsFailedEntries(t *testing.T) {
+	r, src, _, journal := newRelay(t)
+	src.deleteErr = errors.New("connection reset")
+	if _, err := r.RelayOne(); err == nil {
+		t.Fatal("RelayOne succeeded without deleting the source message")
+	}
+
+	if err := r.Recover(); err != src.deleteErr {
+		t.Fatalf("Recover = %v, want the delete error", err)
+	}
+	if len(journal.entries) != 1 {
+		t.Errorf("%d entries pending, want the one that could not be deleted", len(journal.entries))
+	}
+}
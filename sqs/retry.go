@@ -0,0 +1,191 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryClass classifies the failures that are worth retrying.
+type RetryClass string
+
+const (
+	RetryThrottled   RetryClass = "throttled"
+	RetryServerError RetryClass = "server-error"
+	RetryNetwork     RetryClass = "network"
+)
+
+// throttlingCodes are the error codes SQS uses to reject requests beyond
+// the allowed rate.
+var throttlingCodes = map[string]bool{
+	"Throttling":           true,
+	"ThrottlingException":  true,
+	"RequestThrottled":     true,
+	"RequestLimitExceeded": true,
+}
+
+// classifyRetry returns the class of a failed attempt, and false if the
+// failure is not worth retrying.
+func classifyRetry(status int, err error) (RetryClass, bool) {
+	var er *ErrorResponse
+	if errors.As(err, &er) && throttlingCodes[er.Code] {
+		return RetryThrottled, true
+	}
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		return RetryThrottled, true
+	case status >= 500:
+		return RetryServerError, true
+	case status == 0 && !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded):
+		return RetryNetwork, true
+	}
+
+	return "", false
+}
+
+// RetryPolicy sets how many times and how quickly failed requests are
+// retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts made, including the first.
+	// Defaults to 3.
+	MaxAttempts int
+
+	// Backoff is the delay before each retry; it defaults to 100ms,
+	// doubling up to 5s.
+	Backoff Backoff
+
+	// Metrics, if set, counts the retries of each action.
+	Metrics *RetryMetrics
+}
+
+func (rp *RetryPolicy) maxAttempts() int {
+	if rp.MaxAttempts <= 0 {
+		return 3
+	}
+
+	return rp.MaxAttempts
+}
+
+func (rp *RetryPolicy) delay(n int) time.Duration {
+	if rp.Backoff.Initial <= 0 {
+		b := Backoff{Initial: 100 * time.Millisecond, Max: 5 * time.Second}
+		return b.Delay(n)
+	}
+
+	return rp.Backoff.Delay(n)
+}
+
+// RetryEvent describes a request about to be retried.
+type RetryEvent struct {
+	Action  string
+	Attempt int // the attempt that failed, counting from 1
+	Delay   time.Duration
+	Class   RetryClass
+	Err     error
+	Time    time.Time
+}
+
+// retryDelay decides whether the failed attempt of action is retried and
+// after how long, recording the decision.
+func (s *SQSRequest) retryDelay(ctx context.Context, action string, attempt, status int, err error) (time.Duration, bool) {
+	if s.Retry == nil || ctx.Err() != nil {
+		return 0, false
+	}
+
+	class, ok := classifyRetry(status, err)
+	if !ok {
+		return 0, false
+	}
+
+	if attempt >= s.Retry.maxAttempts() {
+		s.Retry.Metrics.observe(action, class, true)
+		return 0, false
+	}
+
+	delay := s.Retry.delay(attempt)
+	s.Retry.Metrics.observe(action, class, false)
+	if s.Metrics != nil {
+		s.Metrics.ObserveRetry(action, class)
+	}
+	if s.Hooks != nil && s.Hooks.OnRetry != nil {
+		s.Hooks.OnRetry(RetryEvent{
+			Action:  action,
+			Attempt: attempt,
+			Delay:   delay,
+			Class:   class,
+			Err:     err,
+			Time:    time.Now(),
+		})
+	}
+
+	return delay, true
+}
+
+// RetryCounts counts the retries of an action by class, and the requests
+// that still failed once out of attempts.
+type RetryCounts struct {
+	Throttled   int
+	ServerError int
+	Network     int
+	Exhausted   int
+}
+
+// Retries returns the total number of retries.
+func (rc RetryCounts) Retries() int {
+	return rc.Throttled + rc.ServerError + rc.Network
+}
+
+// RetryMetrics keeps per-action retry counts. It is safe for concurrent
+// use and may be shared by several clients.
+type RetryMetrics struct {
+	mu      sync.Mutex
+	actions map[string]*RetryCounts
+}
+
+func (rm *RetryMetrics) observe(action string, class RetryClass, exhausted bool) {
+	if rm == nil {
+		return
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if rm.actions == nil {
+		rm.actions = make(map[string]*RetryCounts)
+	}
+	rc, ok := rm.actions[action]
+	if !ok {
+		rc = new(RetryCounts)
+		rm.actions[action] = rc
+	}
+
+	if exhausted {
+		rc.Exhausted++
+		return
+	}
+
+	switch class {
+	case RetryThrottled:
+		rc.Throttled++
+	case RetryServerError:
+		rc.ServerError++
+	case RetryNetwork:
+		rc.Network++
+	}
+}
+
+// Snapshot returns the counts so far, by action.
+func (rm *RetryMetrics) Snapshot() map[string]RetryCounts {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	snap := make(map[string]RetryCounts, len(rm.actions))
+	for action, rc := range rm.actions {
+		snap[action] = *rc
+	}
+
+	return snap
+}
@@ -0,0 +1,141 @@
+package sqs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClassifyRetry(t *testing.T) {
+	netErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+	tests := []struct {
+		name   string
+		status int
+		err    error
+		class  RetryClass
+		retry  bool
+	}{
+		{"throttling code", 400, &ErrorResponse{Code: "Throttling"}, RetryThrottled, true},
+		{"request throttled", 403, &ErrorResponse{Code: "RequestThrottled"}, RetryThrottled, true},
+		{"wrapped throttling code", 400, fmt.Errorf("send: %w", &ErrorResponse{Code: "ThrottlingException"}), RetryThrottled, true},
+		{"too many requests", 429, errors.New("slow down"), RetryThrottled, true},
+		{"internal error", 500, &ErrorResponse{Code: "InternalError"}, RetryServerError, true},
+		{"unavailable", 503, errors.New("unavailable"), RetryServerError, true},
+		{"network", 0, netErr, RetryNetwork, true},
+		{"cancelled", 0, context.Canceled, "", false},
+		{"deadline", 0, fmt.Errorf("send: %w", context.DeadlineExceeded), "", false},
+		{"sender fault", 400, &ErrorResponse{Code: "InvalidParameterValue"}, "", false},
+		{"not found", 404, &ErrorResponse{Code: "AWS.SimpleQueueService.NonExistentQueue"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			class, retry := classifyRetry(tt.status, tt.err)
+			if class != tt.class || retry != tt.retry {
+				t.Errorf("classifyRetry = %q, %v, want %q, %v", class, retry, tt.class, tt.retry)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff Backoff
+		want    []time.Duration // for attempts 1, 2, ...
+	}{
+		{
+			"default",
+			Backoff{},
+			[]time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, 1600 * time.Millisecond, 3200 * time.Millisecond, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			"capped",
+			Backoff{Initial: time.Second, Max: 3 * time.Second},
+			[]time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			"multiplier",
+			Backoff{Initial: time.Second, Multiplier: 3},
+			[]time.Duration{time.Second, 3 * time.Second, 9 * time.Second},
+		},
+		{
+			"uncapped",
+			Backoff{Initial: time.Millisecond},
+			[]time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := &RetryPolicy{Backoff: tt.backoff}
+
+			var got []time.Duration
+			for n := 1; n <= len(tt.want); n++ {
+				got = append(got, rp.delay(n))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("delays = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelayAttempts(t *testing.T) {
+	throttled := &ErrorResponse{Code: "Throttling"}
+
+	tests := []struct {
+		name        string
+		maxAttempts int
+		attempt     int
+		retry       bool
+	}{
+		{"first of the default three", 0, 1, true},
+		{"second of the default three", 0, 2, true},
+		{"last of the default three", 0, 3, false},
+		{"last of one", 1, 1, false},
+		{"fourth of five", 5, 4, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics := new(RetryMetrics)
+			s := &SQSRequest{Retry: &RetryPolicy{MaxAttempts: tt.maxAttempts, Metrics: metrics}}
+
+			delay, retry := s.retryDelay(context.Background(), "SendMessage", tt.attempt, 400, throttled)
+			if retry != tt.retry {
+				t.Fatalf("retry = %v, want %v", retry, tt.retry)
+			}
+			if retry && delay != s.Retry.delay(tt.attempt) {
+				t.Errorf("delay = %v, want %v", delay, s.Retry.delay(tt.attempt))
+			}
+
+			want := RetryCounts{Throttled: 1}
+			if !tt.retry {
+				want = RetryCounts{Exhausted: 1}
+			}
+			if got := metrics.Snapshot()["SendMessage"]; got != want {
+				t.Errorf("counts = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestRetryDelayWithoutPolicy(t *testing.T) {
+	s := &SQSRequest{}
+	if _, retry := s.retryDelay(context.Background(), "SendMessage", 1, 500, errors.New("boom")); retry {
+		t.Error("retried without a RetryPolicy")
+	}
+
+	s.Retry = &RetryPolicy{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, retry := s.retryDelay(ctx, "SendMessage", 1, 500, errors.New("boom")); retry {
+		t.Error("retried after the context was cancelled")
+	}
+}
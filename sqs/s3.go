@@ -0,0 +1,222 @@
+package sqs
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const s3PointerClass = "software.amazon.payloadoffloading.PayloadS3Pointer"
+
+// The AWS extended client libraries embed the location of an S3 payload
+// in receipt handles between these markers.
+const (
+	s3BucketMarker = "-..s3BucketName..-"
+	s3KeyMarker    = "-..s3Key..-"
+)
+
+// S3Offload configures the extended client: message bodies too large for
+// SQS are stored in an S3 bucket and a pointer to them is sent instead.
+// Received pointers are resolved transparently, and the object is removed
+// when the message is deleted. Pointers have the format of the AWS
+// extended client libraries.
+type S3Offload struct {
+	Bucket string
+	Prefix string // prepended to object keys
+
+	// Region defaults to the queue's region.
+	Region string
+
+	// Endpoint, if set, replaces the S3 endpoint, as in
+	// "http://localhost:9000". Buckets are then addressed path-style.
+	Endpoint string
+
+	// Threshold is the message size, body and attributes, above which the
+	// body is offloaded. It defaults to, and cannot exceed, 256 KiB.
+	Threshold int
+}
+
+type s3Pointer struct {
+	Bucket string `json:"s3BucketName"`
+	Key    string `json:"s3Key"`
+}
+
+func (p *s3Pointer) String() string {
+	b, _ := json.Marshal([]interface{}{s3PointerClass, p})
+	return string(b)
+}
+
+// S3Error is returned for S3 requests that fail.
+type S3Error struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+	RequestId  string `xml:"RequestId"`
+}
+
+func (se *S3Error) Error() string {
+	return fmt.Sprintf("S3 error %d, Code: %s, Message: %s", se.StatusCode, se.Code, se.Message)
+}
+
+// s3Store is the PayloadStore configured by SQSRequest.S3, using the
+// client's credentials. References are S3 pointers.
+type s3Store struct {
+	s *SQSRequest
+}
+
+func (ss *s3Store) region() string {
+	if ss.s.S3.Region != "" {
+		return ss.s.S3.Region
+	}
+
+	return ss.s.RegionId
+}
+
+func (ss *s3Store) objectURL(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	key = strings.Join(segments, "/")
+
+	if ss.s.S3.Endpoint != "" {
+		return strings.TrimSuffix(ss.s.S3.Endpoint, "/") + "/" + bucket + "/" + key
+	}
+
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, ss.region(), key)
+}
+
+func (ss *s3Store) request(ctx context.Context, method string, p *s3Pointer, body []byte) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, method, ss.objectURL(p.Bucket, p.Key), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	SignV4(req, sha256Hex(body), ss.s.AWSAccessKey, ss.s.AWSSecret, ss.region(), "s3", time.Now())
+
+	resp, err := ss.s.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer resp.Body.Close()
+
+		se := &S3Error{StatusCode: resp.StatusCode}
+		xml.NewDecoder(resp.Body).Decode(se)
+		return nil, se
+	}
+
+	return resp.Body, nil
+}
+
+func newObjectKey(prefix string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return prefix + hex.EncodeToString(b), nil
+}
+
+// pointerSize returns the size of the pointers Put returns.
+func (ss *s3Store) pointerSize() int {
+	p := &s3Pointer{ss.s.S3.Bucket, ss.s.S3.Prefix + strings.Repeat("0", 32)}
+	return len(p.String())
+}
+
+func (ss *s3Store) Put(ctx context.Context, payload []byte) (string, error) {
+	key, err := newObjectKey(ss.s.S3.Prefix)
+	if err != nil {
+		return "", err
+	}
+
+	p := &s3Pointer{ss.s.S3.Bucket, key}
+	reader, err := ss.request(ctx, http.MethodPut, p, payload)
+	if err != nil {
+		return "", err
+	}
+	reader.Close()
+
+	return p.String(), nil
+}
+
+func (ss *s3Store) Get(ctx context.Context, ref string) ([]byte, error) {
+	p, err := parseS3Pointer(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := ss.request(ctx, http.MethodGet, p, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+func (ss *s3Store) Delete(ctx context.Context, ref string) error {
+	p, err := parseS3Pointer(ref)
+	if err != nil {
+		return err
+	}
+
+	reader, err := ss.request(ctx, http.MethodDelete, p, nil)
+	if err != nil {
+		return err
+	}
+
+	return reader.Close()
+}
+
+func parseS3Pointer(body string) (*s3Pointer, error) {
+	var parts []json.RawMessage
+	if err := json.Unmarshal([]byte(body), &parts); err != nil || len(parts) != 2 {
+		return nil, errors.New("Message body is not an S3 payload pointer.")
+	}
+
+	var class string
+	if err := json.Unmarshal(parts[0], &class); err != nil || class != s3PointerClass {
+		return nil, errors.New("Message body is not an S3 payload pointer.")
+	}
+
+	p := new(s3Pointer)
+	if err := json.Unmarshal(parts[1], p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// splitS3ReceiptHandle separates the SQS receipt handle from an S3 payload
+// location embedded in the format of the AWS extended client libraries,
+// returning the location as a pointer.
+func splitS3ReceiptHandle(handle string) (string, string, bool) {
+	if !strings.HasPrefix(handle, s3BucketMarker) {
+		return handle, "", false
+	}
+
+	parts := strings.SplitN(handle[len(s3BucketMarker):], s3BucketMarker, 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[1], s3KeyMarker) {
+		return handle, "", false
+	}
+	bucket := parts[0]
+
+	parts = strings.SplitN(parts[1][len(s3KeyMarker):], s3KeyMarker, 2)
+	if len(parts) != 2 {
+		return handle, "", false
+	}
+
+	p := &s3Pointer{bucket, parts[0]}
+	return parts[1], p.String(), true
+}
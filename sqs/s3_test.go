@@ -0,0 +1,199 @@
+package sqs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// s3Server keeps objects in memory, by path, answering PUT, GET and DELETE
+// requests as S3 does.
+type s3Server struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func newS3Server(t *testing.T) *s3Server {
+	ss := &s3Server{objects: make(map[string][]byte)}
+	ss.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+			t.Errorf("%s %s is not signed", r.Method, r.URL.Path)
+		}
+
+		ss.mu.Lock()
+		defer ss.mu.Unlock()
+
+		switch r.Method {
+		case http.MethodPut:
+			ss.objects[r.URL.Path], _ = io.ReadAll(r.Body)
+		case http.MethodGet:
+			data, ok := ss.objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+				return
+			}
+			w.Write(data)
+		case http.MethodDelete:
+			delete(ss.objects, r.URL.Path)
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	t.Cleanup(ss.Close)
+
+	return ss
+}
+
+func (ss *s3Server) count() int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	return len(ss.objects)
+}
+
+func TestS3OffloadThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		body      int
+		attrs     map[string]string
+		offloaded bool
+	}{
+		{"below", 1000, 999, nil, false},
+		{"at", 1000, 1000, nil, false},
+		{"above", 1000, 1001, nil, true},
+		// "tenant", "String" and "acme" add 16 bytes.
+		{"attributes count", 1000, 990, map[string]string{"tenant": "acme"}, true},
+		{"default", 0, maxMessageBytes, nil, false},
+		{"default exceeded", 0, maxMessageBytes + 1, nil, true},
+		{"capped at the SQS limit", 2 * maxMessageBytes, maxMessageBytes + 1, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newS3Server(t)
+			s := &SQSRequest{
+				RegionId:     "us-east-1",
+				AWSAccessKey: "key",
+				AWSSecret:    "secret",
+				S3:           &S3Offload{Bucket: "payloads", Prefix: "orders/", Endpoint: srv.URL, Threshold: tt.threshold},
+			}
+			body := strings.Repeat("x", tt.body)
+			var opts *SendOptions
+			if tt.attrs != nil {
+				opts = &SendOptions{MessageAttributes: tt.attrs}
+			}
+
+			ref, refOpts, err := s.offload(context.Background(), body, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if offloaded := ref != body; offloaded != tt.offloaded {
+				t.Fatalf("offloaded = %v, want %v", offloaded, tt.offloaded)
+			}
+			if !tt.offloaded {
+				if srv.count() != 0 {
+					t.Errorf("stored %d objects for a message kept inline", srv.count())
+				}
+				return
+			}
+
+			p, err := parseS3Pointer(ref)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Bucket != "payloads" || !strings.HasPrefix(p.Key, "orders/") {
+				t.Errorf("pointer = %+v", p)
+			}
+			if got := refOpts.MessageAttributes[ExtendedPayloadSizeAttribute]; got != strconv.Itoa(tt.body) {
+				t.Errorf("%s = %q, want %d", ExtendedPayloadSizeAttribute, got, tt.body)
+			}
+			if got, want := s.offloadSize(body, opts), messageSize(ref, refOpts); got != want {
+				t.Errorf("offloadSize = %d, want the size of the message sent, %d", got, want)
+			}
+
+			// The pointer comes back on a received message, which is
+			// inflated, and deleting it deletes the object.
+			m := &recvMessage{
+				MessageId:         "m1",
+				MessageBody:       ref,
+				ReceiptHandle:     "h1",
+				MessageAttributes: []MessageAttribute{{ExtendedPayloadSizeAttribute, "Number", strconv.Itoa(tt.body)}},
+			}
+			if err = s.inflate(context.Background(), m); err != nil {
+				t.Fatal(err)
+			}
+			if m.MessageBody != body {
+				t.Errorf("inflated body of %d bytes, want %d", len(m.MessageBody), tt.body)
+			}
+
+			handle, gotRef := splitReceiptHandle(m.ReceiptHandle)
+			if handle != "h1" || gotRef != ref {
+				t.Errorf("splitReceiptHandle = %q, %q, want h1, %q", handle, gotRef, ref)
+			}
+			if err = s.deletePayload(context.Background(), gotRef); err != nil {
+				t.Fatal(err)
+			}
+			if srv.count() != 0 {
+				t.Errorf("%d objects left after deleting the message", srv.count())
+			}
+		})
+	}
+}
+
+func TestS3ReceiptHandle(t *testing.T) {
+	p := &s3Pointer{"payloads", "orders/abc"}
+
+	tests := []struct {
+		name   string
+		handle string
+		want   string
+		ref    string
+	}{
+		{"plain", "h1", "h1", ""},
+		{"extended client", s3BucketMarker + "payloads" + s3BucketMarker + s3KeyMarker + "orders/abc" + s3KeyMarker + "h1", "h1", p.String()},
+		{"unterminated bucket", s3BucketMarker + "payloads", s3BucketMarker + "payloads", ""},
+		{"no key", s3BucketMarker + "payloads" + s3BucketMarker + "h1", s3BucketMarker + "payloads" + s3BucketMarker + "h1", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handle, ref := splitReceiptHandle(tt.handle)
+			if handle != tt.want || ref != tt.ref {
+				t.Errorf("splitReceiptHandle = %q, %q, want %q, %q", handle, ref, tt.want, tt.ref)
+			}
+		})
+	}
+}
+
+func TestParseS3Pointer(t *testing.T) {
+	p := &s3Pointer{"payloads", "orders/abc"}
+	got, err := parseS3Pointer(p.String())
+	if err != nil || *got != *p {
+		t.Errorf("parseS3Pointer(%s) = %+v, %v", p, got, err)
+	}
+
+	for _, body := range []string{"hello", `["other.Class",{"s3BucketName":"b","s3Key":"k"}]`, `["` + s3PointerClass + `"]`} {
+		if _, err := parseS3Pointer(body); err == nil {
+			t.Errorf("parseS3Pointer(%s) succeeded", body)
+		}
+	}
+}
+
+func TestS3GetMissingObject(t *testing.T) {
+	srv := newS3Server(t)
+	s := &SQSRequest{RegionId: "us-east-1", S3: &S3Offload{Bucket: "payloads", Endpoint: srv.URL}}
+
+	_, err := (&s3Store{s}).Get(context.Background(), (&s3Pointer{"payloads", "gone"}).String())
+	se, ok := err.(*S3Error)
+	if !ok || se.StatusCode != http.StatusNotFound || se.Code != "NoSuchKey" {
+		t.Errorf("Get = %v, want a NoSuchKey S3Error", err)
+	}
+}
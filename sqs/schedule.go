@@ -0,0 +1,106 @@
+package sqs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NotBeforeAttribute carries, in Unix milliseconds, the time before which
+// a message scheduled beyond the 15 minute delay limit must not be
+// processed.
+const NotBeforeAttribute = "sqs-not-before"
+
+// DelayUntil returns the DelaySeconds that postpones delivery until at.
+// Times further away than SQS can delay a message yield the maximum delay
+// and deferred set, meaning the message has to be re-enqueued on arrival.
+func DelayUntil(at, now time.Time) (delaySeconds int, deferred bool) {
+	d := at.Sub(now)
+	if d <= 0 {
+		return 0, false
+	}
+
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds > maxDelaySeconds {
+		return maxDelaySeconds, true
+	}
+
+	return seconds, false
+}
+
+// SendSQSMessageAt sends a message that is not to be processed before at.
+// Within 15 minutes this is a plain delayed send; beyond that the message
+// is stamped with NotBeforeAttribute and a Consumer re-enqueues it each
+// time it arrives early, until at is reached.
+//
+// FIFO queues reject per-message delays, so on them at must not be in the
+// future; schedule such messages on a standard queue and relay them.
+func (s *SQSRequest) SendSQSMessageAt(message []byte, at time.Time, opts *SendOptions) (*SendMessageResponse, error) {
+	return sendAt(s, message, at, opts)
+}
+
+func sendAt(c SQSClient, message []byte, at time.Time, opts *SendOptions) (*SendMessageResponse, error) {
+	delay, deferred := DelayUntil(at, time.Now())
+	if delay > 0 && (strings.HasSuffix(queueName(c), fifoSuffix) || opts != nil && opts.MessageGroupId != "") {
+		return nil, fmt.Errorf("Messages on FIFO queue %q cannot be delayed.", queueName(c))
+	}
+	if deferred {
+		opts = opts.withAttribute(NotBeforeAttribute, strconv.FormatInt(at.UnixNano()/int64(time.Millisecond), 10))
+	}
+
+	o := SendOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	o.DelaySeconds = delay
+
+	return c.SendSQSMessageWithOptions(message, &o)
+}
+
+// NotBefore returns the time stamped on a message by SendSQSMessageAt, if
+// any.
+func (rmr *RecvMessageResponse) NotBefore() (time.Time, bool) {
+	v := rmr.MessageAttribute(NotBeforeAttribute)
+	if v == "" {
+		return time.Time{}, false
+	}
+
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(0, ms*int64(time.Millisecond)), true
+}
+
+// RescheduleIfEarly re-enqueues a message that arrived before its
+// NotBeforeAttribute time, keeping its message attributes, and deletes the
+// early copy. It reports whether the message was rescheduled.
+func (s *SQSRequest) RescheduleIfEarly(m *RecvMessageResponse) (bool, error) {
+	return rescheduleIfEarly(s, m)
+}
+
+func rescheduleIfEarly(c SQSClient, m *RecvMessageResponse) (bool, error) {
+	at, ok := m.NotBefore()
+	if !ok || !at.After(time.Now()) {
+		return false, nil
+	}
+
+	attrs := make(map[string]string, len(m.MessageAttributes))
+	for _, attr := range m.MessageAttributes {
+		if attr.Name != NotBeforeAttribute {
+			attrs[attr.Name] = attr.StringValue
+		}
+	}
+
+	if _, err := sendAt(c, []byte(m.MessageBody), at, &SendOptions{MessageAttributes: attrs}); err != nil {
+		return false, err
+	}
+
+	if _, err := c.DeleteSQSMessage(m.ReceiptHandle); err != nil {
+		return true, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,77 @@
+package sqs
+
+import (
+	"testing"
+	"time"
+)
+
+// sendRecorder records the options of the messages sent to a queue.
+type sendRecorder struct {
+	SQSClient
+
+	name string
+	sent []SendOptions
+}
+
+func (sr *sendRecorder) Name() string {
+	return sr.name
+}
+
+func (sr *sendRecorder) SendSQSMessageWithOptions(message []byte, opts *SendOptions) (*SendMessageResponse, error) {
+	sr.sent = append(sr.sent, *opts)
+	return &SendMessageResponse{}, nil
+}
+
+func TestSendAt(t *testing.T) {
+	tests := []struct {
+		name  string
+		queue string
+		in    time.Duration
+		group string
+
+		err       bool
+		delay     int
+		notBefore bool
+	}{
+		{name: "now", queue: "orders"},
+		{name: "past", queue: "orders", in: -time.Hour},
+		{name: "within the delay limit", queue: "orders", in: 90 * time.Second, delay: 90},
+		{name: "beyond the delay limit", queue: "orders", in: time.Hour, delay: maxDelaySeconds, notBefore: true},
+		{name: "fifo now", queue: "orders.fifo", group: "a"},
+		{name: "fifo delayed", queue: "orders.fifo", group: "a", in: 90 * time.Second, err: true},
+		{name: "fifo beyond the delay limit", queue: "orders.fifo", group: "a", in: time.Hour, err: true},
+		{name: "group on an unnamed queue", group: "a", in: time.Hour, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sr := &sendRecorder{name: tt.queue}
+			var opts *SendOptions
+			if tt.group != "" {
+				opts = &SendOptions{MessageGroupId: tt.group}
+			}
+
+			_, err := sendAt(sr, []byte("hello"), time.Now().Add(tt.in), opts)
+			if (err != nil) != tt.err {
+				t.Fatalf("sendAt = %v, want error %v", err, tt.err)
+			}
+			if tt.err {
+				if len(sr.sent) > 0 {
+					t.Errorf("sent %+v despite the error", sr.sent)
+				}
+				return
+			}
+
+			if len(sr.sent) != 1 {
+				t.Fatalf("sent %d messages, want 1", len(sr.sent))
+			}
+			o := sr.sent[0]
+			if o.DelaySeconds != tt.delay {
+				t.Errorf("DelaySeconds = %d, want %d", o.DelaySeconds, tt.delay)
+			}
+			if _, ok := o.MessageAttributes[NotBeforeAttribute]; ok != tt.notBefore {
+				t.Errorf("attributes = %v, want %s set %v", o.MessageAttributes, NotBeforeAttribute, tt.notBefore)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package sqs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Spool persists the messages a Producer has accepted until they are sent.
+type Spool interface {
+	// Append stores a message and returns the id to remove it by.
+	Append(body []byte, opts SendOptions) (string, error)
+
+	// Remove deletes a stored message.
+	Remove(id string) error
+
+	// Pending returns the stored messages, in the order they were
+	// appended.
+	Pending() ([]SpoolRecord, error)
+}
+
+// SpoolRecord is a message stored in a Spool.
+type SpoolRecord struct {
+	Id      string
+	Body    []byte
+	Options SendOptions
+}
+
+const spoolSuffix = ".msg"
+
+// FileSpool is a Spool keeping each message in a file of its own in a
+// directory. Files are written to a temporary name and renamed into place,
+// so a crash never leaves a partial message behind. A directory must not
+// be used by more than one FileSpool at a time.
+type FileSpool struct {
+	Dir string
+
+	// Sync makes Append flush every message to disk before returning, at
+	// the cost of much slower appends.
+	Sync bool
+
+	mu   sync.Mutex
+	next uint64
+}
+
+// NewFileSpool opens a spool in dir, creating the directory if needed.
+func NewFileSpool(dir string) (*FileSpool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	fs := &FileSpool{Dir: dir}
+
+	ids, err := fs.ids()
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) > 0 {
+		last, _ := strconv.ParseUint(ids[len(ids)-1], 10, 64)
+		fs.next = last + 1
+	}
+
+	return fs, nil
+}
+
+// ids returns the ids of the stored messages, in order. They are
+// zero-padded, so they sort as numbers.
+func (fs *FileSpool) ids() ([]string, error) {
+	entries, err := os.ReadDir(fs.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, spoolSuffix) {
+			continue
+		}
+
+		id := strings.TrimSuffix(name, spoolSuffix)
+		if _, err := strconv.ParseUint(id, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids, nil
+}
+
+func (fs *FileSpool) path(id string) string {
+	return filepath.Join(fs.Dir, id+spoolSuffix)
+}
+
+func (fs *FileSpool) Append(body []byte, opts SendOptions) (string, error) {
+	fs.mu.Lock()
+	id := fmt.Sprintf("%020d", fs.next)
+	fs.next++
+	fs.mu.Unlock()
+
+	data, err := json.Marshal(SpoolRecord{id, body, opts})
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.CreateTemp(fs.Dir, id+".tmp")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = f.Write(data)
+	if err == nil && fs.Sync {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), fs.path(id))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return id, nil
+}
+
+func (fs *FileSpool) Remove(id string) error {
+	err := os.Remove(fs.path(id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+func (fs *FileSpool) Pending() ([]SpoolRecord, error) {
+	ids, err := fs.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]SpoolRecord, 0, len(ids))
+	for _, id := range ids {
+		data, err := os.ReadFile(fs.path(id))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// A corrupt message is set aside rather than blocking the rest.
+		var r SpoolRecord
+		if json.Unmarshal(data, &r) != nil {
+			os.Rename(fs.path(id), fs.path(id)+".corrupt")
+			continue
+		}
+		r.Id = id
+		records = append(records, r)
+	}
+
+	return records, nil
+}
@@ -0,0 +1,178 @@
+package sqs
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func spooledBodies(t *testing.T, fs *FileSpool) []string {
+	t.Helper()
+
+	records, err := fs.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bodies []string
+	for _, r := range records {
+		bodies = append(bodies, string(r.Body))
+	}
+
+	return bodies
+}
+
+func TestFileSpool(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewFileSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for _, body := range []string{"a", "b", "c"} {
+		id, err := fs.Append([]byte(body), SendOptions{MessageGroupId: "g"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	if err = fs.Remove(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err = fs.Remove(ids[1]); err != nil {
+		t.Errorf("removing twice: %v", err)
+	}
+
+	// A reopened spool keeps numbering after the messages it finds.
+	fs, err = NewFileSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = fs.Append([]byte("d"), SendOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := fs.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := spooledBodies(t, fs); !reflect.DeepEqual(got, []string{"a", "c", "d"}) {
+		t.Errorf("spooled %v, want [a c d]", got)
+	}
+	if records[0].Id != ids[0] || records[0].Options.MessageGroupId != "g" {
+		t.Errorf("first record = %+v", records[0])
+	}
+}
+
+func TestProducerReplaysSpoolAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	fs, err := NewFileSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A previous process spooled a and b, left a temporary file behind
+	// and had a message corrupted, then crashed before sending anything.
+	for _, body := range []string{"a", "b"} {
+		if _, err = fs.Append([]byte(body), SendOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = os.WriteFile(filepath.Join(dir, "00000000000000000002.tmp123"), []byte(`{"Body":`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	corrupt := filepath.Join(dir, "00000000000000000003"+spoolSuffix)
+	if err = os.WriteFile(corrupt, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fs, err = NewFileSpool(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bq := &batchQueue{}
+	p := NewProducer(bq)
+	p.FlushInterval = time.Hour
+	p.Spool = fs
+	var errs []error
+	p.OnError = func(err error) { errs = append(errs, err) }
+
+	if err = p.Enqueue([]byte("c"), nil); err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+
+	if !reflect.DeepEqual(bq.sent, []string{"a", "b", "c"}) {
+		t.Errorf("sent %v, want the spooled a and b before c", bq.sent)
+	}
+	if got := spooledBodies(t, fs); len(got) != 0 {
+		t.Errorf("still spooled: %v", got)
+	}
+	if _, err = os.Stat(corrupt + ".corrupt"); err != nil {
+		t.Errorf("corrupt message was not set aside: %v", err)
+	}
+	if len(errs) != 0 {
+		t.Errorf("spool errors: %v", errs)
+	}
+}
+
+func TestProducerKeepsExhaustedMessagesSpooled(t *testing.T) {
+	fs, err := NewFileSpool(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outage := true
+	bq := &batchQueue{
+		fail: func(call int, body string) *BatchResultError {
+			if outage {
+				return &BatchResultError{Code: "InternalError"}
+			}
+			return nil
+		},
+	}
+	p := NewProducer(bq)
+	p.FlushInterval = time.Hour
+	p.MaxRetries = 1
+	p.Spool = fs
+
+	results := make(chan SendResult, 1)
+	if err = p.Enqueue([]byte("a"), func(res SendResult) { results <- res }); err != nil {
+		t.Fatal(err)
+	}
+	p.Flush()
+
+	// The callback hears of the failure, but the message stays spooled.
+	var be BatchError
+	if res := <-results; !errors.As(res.Err, &be) {
+		t.Errorf("result = %v, want a BatchError", res.Err)
+	}
+	if len(bq.batches) != 2 {
+		t.Errorf("batches = %v, want a first attempt and a retry", bq.batches)
+	}
+	if got := spooledBodies(t, fs); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("spooled %v, want [a]", got)
+	}
+
+	// Once SQS is back, a restarted producer sends it.
+	bq.mu.Lock()
+	outage = false
+	bq.mu.Unlock()
+
+	p.Close()
+	p = NewProducer(bq)
+	p.FlushInterval = time.Hour
+	p.Spool = fs
+	p.Close()
+
+	if !reflect.DeepEqual(bq.sent, []string{"a"}) {
+		t.Errorf("sent %v, want [a]", bq.sent)
+	}
+	if got := spooledBodies(t, fs); len(got) != 0 {
+		t.Errorf("still spooled: %v", got)
+	}
+}
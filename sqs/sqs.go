@@ -2,47 +2,130 @@ package sqs
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
-	"log"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type ErrorResponse struct {
-	Type    string `xml:"Error>Type"`
-	Code    string `xml:"Error>Code"`
-	Message string `xml:"Error>Message"`
+	Type      string `xml:"Error>Type"`
+	Code      string `xml:"Error>Code"`
+	Message   string `xml:"Error>Message"`
+	Detail    string `xml:"Error>Detail"`
+	RequestId string `xml:"RequestId"`
 }
 
 func (er *ErrorResponse) String() string {
 	return fmt.Sprintf("Type: %s, Code: %s, Message: %s", er.Type, er.Code, er.Message)
 }
 
+func (er *ErrorResponse) Error() string {
+	return er.String()
+}
+
 type SendMessageResponse struct {
-	MessageId  string `xml:"SendMessageResult>MessageId"`
-	MessageMD5 string `xml:"SendMessageResult>MD5OfMessageBody"`
+	MessageId     string `xml:"SendMessageResult>MessageId"`
+	MessageMD5    string `xml:"SendMessageResult>MD5OfMessageBody"`
+	AttributesMD5 string `xml:"SendMessageResult>MD5OfMessageAttributes"`
 	BasicResponse
 }
 
 type RecvMessageResponse struct {
-	MessageId     string `xml:"ReceiveMessageResult>Message>MessageId"`
-	MessageMD5    string `xml:"ReceiveMessageResult>Message>MD5OfBody"`
-	MessageBody   string `xml:"ReceiveMessageResult>Message>Body"`
-	ReceiptHandle string `xml:"ReceiveMessageResult>Message>ReceiptHandle"`
+	MessageId         string             `xml:"ReceiveMessageResult>Message>MessageId"`
+	MessageMD5        string             `xml:"ReceiveMessageResult>Message>MD5OfBody"`
+	MessageBody       string             `xml:"ReceiveMessageResult>Message>Body"`
+	ReceiptHandle     string             `xml:"ReceiveMessageResult>Message>ReceiptHandle"`
+	Attributes        []Attribute        `xml:"ReceiveMessageResult>Message>Attribute"`
+	MessageAttributes []MessageAttribute `xml:"ReceiveMessageResult>Message>MessageAttribute"`
+	AttributesMD5     string             `xml:"ReceiveMessageResult>Message>MD5OfMessageAttributes"`
 	BasicResponse
 }
 
+type MessageAttribute struct {
+	Name        string `xml:"Name"`
+	DataType    string `xml:"Value>DataType"`
+	StringValue string `xml:"Value>StringValue"`
+}
+
+// MessageAttribute returns the string value of the named user-defined
+// message attribute.
+func (rmr *RecvMessageResponse) MessageAttribute(name string) string {
+	for _, attr := range rmr.MessageAttributes {
+		if attr.Name == name {
+			return attr.StringValue
+		}
+	}
+
+	return ""
+}
+
+// Attribute returns the value of the named system attribute of the
+// message, such as ApproximateReceiveCount or SentTimestamp.
+func (rmr *RecvMessageResponse) Attribute(name string) string {
+	for _, attr := range rmr.Attributes {
+		if attr.Name == name {
+			return attr.Value
+		}
+	}
+
+	return ""
+}
+
+// ReceiveCount returns the number of times the message has been received,
+// including this time.
+func (rmr *RecvMessageResponse) ReceiveCount() int {
+	n, _ := strconv.Atoi(rmr.Attribute("ApproximateReceiveCount"))
+	return n
+}
+
+// MessageGroupId returns the group of a message received from a FIFO
+// queue.
+func (rmr *RecvMessageResponse) MessageGroupId() string {
+	return rmr.Attribute("MessageGroupId")
+}
+
+// SentTimestamp returns the time the message was sent to the queue.
+func (rmr *RecvMessageResponse) SentTimestamp() time.Time {
+	ms, _ := strconv.ParseInt(rmr.Attribute("SentTimestamp"), 10, 64)
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
 type QueueURLResponse struct {
-	QueueURL string `xml:"QueueUrl"`
+	QueueURL string
 	BasicResponse
 }
 
+// UnmarshalXML reads the queue URL out of both CreateQueue and GetQueueUrl
+// responses, which nest it under differently named result elements.
+func (qur *QueueURLResponse) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v struct {
+		Created string `xml:"CreateQueueResult>QueueUrl"`
+		Fetched string `xml:"GetQueueUrlResult>QueueUrl"`
+		BasicResponse
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+
+	qur.QueueURL = v.Created
+	if qur.QueueURL == "" {
+		qur.QueueURL = v.Fetched
+	}
+	qur.BasicResponse = v.BasicResponse
+
+	return nil
+}
+
 type QueueListResponse struct {
 	QueueURLs []string `xml:"ListQueuesResult>QueueUrl"`
+	NextToken string   `xml:"ListQueuesResult>NextToken"`
 	BasicResponse
 }
 
@@ -56,6 +139,103 @@ type SQSRequest struct {
 	QueueName    string
 	AWSAccessKey string
 	AWSSecret    string
+
+	// Protocol is the wire format of requests, ProtocolJSON by default.
+	// ProtocolQuery suits services that only speak the query protocol,
+	// such as older emulators.
+	Protocol Protocol
+
+	// DiagnoseSignatures turns SignatureDoesNotMatch failures into a
+	// *SignatureError carrying the string that was signed.
+	DiagnoseSignatures bool
+
+	Hooks *Hooks
+
+	// HTTPClient makes the requests of the client, defaulting to
+	// http.DefaultClient. Clients sharing one share its connections.
+	HTTPClient *http.Client
+
+	// Interceptors wrap every HTTP request the client makes, the first
+	// one outermost. See LoggingInterceptor.
+	Interceptors []Interceptor
+
+	// Metrics, if set, receives measurements of every request. See
+	// ExpvarMetrics and PrometheusMetrics.
+	Metrics MetricsCollector
+
+	// Tracer, if set, traces every call to SQS and carries the trace
+	// context of messages sent with a context to their consumers.
+	Tracer Tracer
+
+	// Limiter, if set, limits the rate and concurrency of requests. It
+	// may be shared by several clients.
+	Limiter *RateLimiter
+
+	// Retry, if set, retries requests that failed because of throttling,
+	// server errors or network errors.
+	Retry *RetryPolicy
+
+	// Naming, if set, normalizes and validates the names of queues created
+	// through CreateQueue and EnsureQueue.
+	Naming *QueueNaming
+
+	// ReadOnly restricts the client to actions that cannot change queues
+	// or messages; receives are only allowed through Peek. Anything else
+	// fails with a *ReadOnlyError before reaching SQS.
+	ReadOnly bool
+
+	// Endpoint, if set, is the base URL of the SQS service, replacing
+	// https://sqs.<region>.amazonaws.com; for example http://localhost:4566
+	// for a local emulator.
+	Endpoint string
+
+	// WaitSeconds is the long-poll wait of ReceiveSQSMessage, and of
+	// consumers that do not set their own. VisibilityTimeout, if
+	// positive, overrides the queue's visibility timeout for messages
+	// received by this client, except through Peek.
+	WaitSeconds       int
+	VisibilityTimeout int
+
+	// IdleBackoff, if set, delays the polls of Receive after consecutive
+	// empty receives, as Consumer.IdleBackoff does.
+	IdleBackoff *Backoff
+
+	// ExplicitQueueURL, if set, is the queue URL used for queue requests
+	// instead of one built from RegionId, UUID and QueueName. It allows
+	// operating on queues owned by other accounts; see WithQueueURL.
+	ExplicitQueueURL string
+
+	// SkipChecksums disables verification of the MD5 digests SQS returns
+	// for sent and received messages. Without it a mismatch fails the
+	// request with a *ChecksumError.
+	SkipChecksums bool
+
+	// BodyCodec converts payloads to and from message bodies. It defaults
+	// to RawBody; set Base64Body to send binary data, or QueryEscapedBody
+	// for queues written by older clients (see BodyCodec).
+	BodyCodec BodyCodec
+
+	// CompressAbove, if positive, gzips payloads larger than this many
+	// bytes when that makes them smaller. Compressed messages are marked
+	// with ContentEncodingAttribute and decompressed on receive.
+	CompressAbove int
+
+	// S3, if set, offloads message bodies too large for SQS to S3.
+	S3 *S3Offload
+
+	// Payloads, if set, keeps message bodies too large for SQS instead of
+	// S3. PayloadThreshold is the message size, body and attributes, above
+	// which it is used; it defaults to, and cannot exceed, 256 KiB.
+	Payloads         PayloadStore
+	PayloadThreshold int
+
+	// Codec marshals the values sent with SendTyped. It defaults to
+	// JSONCodec.
+	Codec Codec
+
+	// Propagation, if set, selects the attributes that messages sent with
+	// a context carry forward from the message being handled.
+	Propagation *PropagationPolicy
 }
 
 func (s *SQSRequest) makeSQSQueueRequest(params map[string]string) (io.ReadCloser, error) {
@@ -66,7 +246,108 @@ func (s *SQSRequest) makeSQSAdminRequest(params map[string]string) (io.ReadClose
 	return s.makeSQSRequest(params, false)
 }
 
+func (s *SQSRequest) makeSQSQueueRequestContext(ctx context.Context, params map[string]string) (io.ReadCloser, error) {
+	return s.makeSQSRequestContext(ctx, params, true)
+}
+
 func (s *SQSRequest) makeSQSRequest(params map[string]string, isQueueRequest bool) (io.ReadCloser, error) {
+	return s.makeSQSRequestContext(context.Background(), params, isQueueRequest)
+}
+
+func (s *SQSRequest) makeSQSRequestContext(ctx context.Context, params map[string]string, isQueueRequest bool) (io.ReadCloser, error) {
+	if s.ReadOnly && !allowedReadOnly(params) {
+		return nil, &ReadOnlyError{params["Action"]}
+	}
+
+	ctx, span := s.startSpan(ctx, params["Action"])
+
+	for attempt := 1; ; attempt++ {
+		reader, status, err := s.limitedSQSRequest(ctx, params, isQueueRequest)
+		if err == nil {
+			endSpan(span, nil)
+			return reader, nil
+		}
+
+		delay, retry := s.retryDelay(ctx, params["Action"], attempt, status, err)
+		if !retry {
+			endSpan(span, err)
+			return reader, err
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			endSpan(span, ctx.Err())
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// limitedSQSRequest makes a single attempt at a request once the Limiter
+// allows, and tells it about throttling.
+func (s *SQSRequest) limitedSQSRequest(ctx context.Context, params map[string]string, isQueueRequest bool) (io.ReadCloser, int, error) {
+	if s.Limiter == nil {
+		return s.doSQSRequest(ctx, params, isQueueRequest)
+	}
+
+	done, err := s.Limiter.Wait(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer done()
+
+	reader, status, err := s.doSQSRequest(ctx, params, isQueueRequest)
+	if class, ok := classifyRetry(status, err); ok && class == RetryThrottled {
+		s.Limiter.Throttled()
+	}
+
+	return reader, status, err
+}
+
+// doSQSRequest makes a single attempt at a request, returning the HTTP
+// status alongside any error.
+func (s *SQSRequest) doSQSRequest(ctx context.Context, params map[string]string, isQueueRequest bool) (io.ReadCloser, int, error) {
+	newRequest := s.newJSONRequest
+	if s.Protocol == ProtocolQuery {
+		newRequest = s.newQueryRequest
+	}
+
+	r, stringToSign, err := newRequest(ctx, params, isQueueRequest)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start := time.Now()
+	resp, err := s.roundTrip(r)
+	if s.Metrics != nil {
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		s.Metrics.ObserveRequest(params["Action"], status, time.Since(start))
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		reader, err := s.errorResponse(resp, stringToSign)
+		return reader, resp.StatusCode, err
+	}
+
+	if s.Protocol == ProtocolQuery {
+		return resp.Body, resp.StatusCode, nil
+	}
+
+	reader, err := queryResponse(params["Action"], resp)
+	return reader, resp.StatusCode, err
+}
+
+// newQueryRequest builds a query protocol request, and returns it along
+// with the string it signed.
+func (s *SQSRequest) newQueryRequest(ctx context.Context, params map[string]string, isQueueRequest bool) (*http.Request, string, error) {
 	sqsURI := s.generateSQSQueueURI()
 	if !isQueueRequest {
 		sqsURI = s.generateSQSURI()
@@ -85,36 +366,67 @@ func (s *SQSRequest) makeSQSRequest(params map[string]string, isQueueRequest boo
 		uv.Set(key, value)
 	}
 
+	stringToSign, _ := StringToSign(sqsURI, method, uv)
 	uv.Set("Signature", GenerateSignature(sqsURI, method, s.AWSSecret, uv))
 
-	r, err := http.NewRequest(method, sqsURI, bytes.NewBufferString(uv.Encode()))
+	r, err := http.NewRequestWithContext(ctx, method, sqsURI, bytes.NewBufferString(uv.Encode()))
 	if err != nil {
-		return nil, err
+		return nil, "", err
 	}
 
 	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
-	client := &http.Client{}
+	return r, stringToSign, nil
+}
 
-	resp, err := client.Do(r)
+// errorResponse decodes the SQS error document of a failed request. The
+// body is handed back unread alongside the error.
+func (s *SQSRequest) errorResponse(resp *http.Response, stringToSign string) (io.ReadCloser, error) {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
+	reader := io.NopCloser(bytes.NewReader(body))
 
-	if resp.StatusCode == http.StatusOK {
-		return resp.Body, nil
+	er := new(ErrorResponse)
+	if s.Protocol == ProtocolQuery {
+		if xml.Unmarshal(body, er) != nil {
+			er = nil
+		}
+	} else {
+		er = jsonError(resp, body)
+	}
+	if er == nil || er.Code == "" {
+		return reader, errors.New(resp.Status)
 	}
 
-	return resp.Body, errors.New(resp.Status)
+	if s.DiagnoseSignatures && er.Code == "SignatureDoesNotMatch" {
+		return reader, &SignatureError{stringToSign, er}
+	}
+
+	return reader, er
 }
 
 func (s *SQSRequest) generateSQSQueueURI() string {
+	if s.ExplicitQueueURL != "" {
+		return s.ExplicitQueueURL
+	}
+
 	var u = url.URL{
 		Scheme: "https",
 		Host:   fmt.Sprintf("sqs.%s.amazonaws.com", s.RegionId),
 		Path:   fmt.Sprintf("/%s/%s/", s.UUID, s.QueueName),
 	}
 
+	if s.Endpoint != "" {
+		if e, err := url.Parse(s.Endpoint); err == nil {
+			u.Scheme, u.Host = e.Scheme, e.Host
+			u.Path = strings.TrimSuffix(e.Path, "/") + u.Path
+		}
+	}
+
 	return u.String()
 }
 
@@ -127,15 +439,149 @@ func (s *SQSRequest) generateSQSURI() string {
 	return u.String()
 }
 
+// maxDelaySeconds is the longest delivery delay SQS supports.
+const maxDelaySeconds = 900
+
+// maxMessageAttributes is the most message attributes SQS accepts on a
+// message.
+const maxMessageAttributes = 10
+
+// SendOptions carries the optional parameters of a sent message.
+type SendOptions struct {
+	// DelaySeconds postpones delivery of the message, from 0 to 900
+	// seconds. Zero leaves the queue's default delay in effect.
+	DelaySeconds int
+
+	// MessageAttributes are sent as user-defined String attributes.
+	MessageAttributes map[string]string
+
+	// MessageGroupId is required on FIFO queues: messages of a group are
+	// delivered in the order they were sent. MessageDeduplicationId
+	// suppresses duplicates sent within five minutes; it is required on
+	// FIFO queues without content-based deduplication.
+	MessageGroupId         string
+	MessageDeduplicationId string
+}
+
+func (opts *SendOptions) validate() error {
+	if opts == nil {
+		return nil
+	}
+
+	if opts.DelaySeconds < 0 || opts.DelaySeconds > maxDelaySeconds {
+		return fmt.Errorf("DelaySeconds must be between 0 and %d, got %d.", maxDelaySeconds, opts.DelaySeconds)
+	}
+	if err := validateFifoId("MessageGroupId", opts.MessageGroupId); err != nil {
+		return err
+	}
+	if err := validateFifoId("MessageDeduplicationId", opts.MessageDeduplicationId); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateFifoId checks a group or deduplication id, which may be empty.
+func validateFifoId(name, id string) error {
+	if len(id) > 128 {
+		return fmt.Errorf("%s must be at most 128 characters long.", name)
+	}
+
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return fmt.Errorf("%s may only contain alphanumeric characters and punctuation.", name)
+		}
+	}
+
+	return nil
+}
+
+// withAttribute returns a copy of opts, which may be nil, with the named
+// message attribute set. opts itself is left alone.
+func (opts *SendOptions) withAttribute(name, value string) *SendOptions {
+	o := SendOptions{}
+	if opts != nil {
+		o = *opts
+	}
+
+	attrs := make(map[string]string, len(o.MessageAttributes)+1)
+	for name, value := range o.MessageAttributes {
+		attrs[name] = value
+	}
+	attrs[name] = value
+	o.MessageAttributes = attrs
+
+	return &o
+}
+
+func (opts *SendOptions) setParams(params map[string]string, prefix string) {
+	if opts == nil {
+		return
+	}
+
+	if opts.DelaySeconds > 0 {
+		params[prefix+"DelaySeconds"] = strconv.Itoa(opts.DelaySeconds)
+	}
+	if opts.MessageGroupId != "" {
+		params[prefix+"MessageGroupId"] = opts.MessageGroupId
+	}
+	if opts.MessageDeduplicationId != "" {
+		params[prefix+"MessageDeduplicationId"] = opts.MessageDeduplicationId
+	}
+
+	count := 1
+	for name, value := range opts.MessageAttributes {
+		attr := fmt.Sprintf("%sMessageAttribute.%d.", prefix, count)
+		params[attr+"Name"] = name
+		params[attr+"Value.DataType"] = "String"
+		params[attr+"Value.StringValue"] = value
+		count++
+	}
+}
+
 func (s *SQSRequest) SendSQSMessage(message []byte) (*SendMessageResponse, error) {
-	msg := url.QueryEscape(string(message))
+	return s.SendSQSMessageWithOptions(message, nil)
+}
+
+func (s *SQSRequest) SendSQSMessageWithOptions(message []byte, opts *SendOptions) (*SendMessageResponse, error) {
+	return s.sendSQSMessage(context.Background(), message, opts)
+}
+
+// SendSQSMessageContext sends a message, carrying attributes forward from
+// the message being handled in ctx according to the client's Propagation
+// policy, along with the trace context of ctx if the client has a Tracer.
+func (s *SQSRequest) SendSQSMessageContext(ctx context.Context, message []byte, opts *SendOptions) (*SendMessageResponse, error) {
+	opts = s.injectTrace(ctx, opts)
+	opts, err := s.Propagation.apply(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sendSQSMessage(ctx, message, opts)
+}
+
+func (s *SQSRequest) sendSQSMessage(ctx context.Context, message []byte, opts *SendOptions) (*SendMessageResponse, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	msg, opts, err := s.encodeBody(message, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, opts, err = s.offload(ctx, msg, opts)
+	if err != nil {
+		return nil, err
+	}
 
 	params := map[string]string{
 		"Action":      "SendMessage",
 		"MessageBody": msg,
 	}
+	opts.setParams(params, "")
 
-	reader, err := s.makeSQSQueueRequest(params)
+	reader, err := s.makeSQSQueueRequestContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
@@ -147,39 +593,141 @@ func (s *SQSRequest) SendSQSMessage(message []byte) (*SendMessageResponse, error
 		return nil, err
 	}
 
+	if err = s.verifyMessage(smr.MessageId, msg, smr.MessageMD5, sendAttributes(opts), smr.AttributesMD5); err != nil {
+		return nil, err
+	}
+
 	return smr, nil
 }
 
+var ErrNoMessage = errors.New("No message to dequeue.")
+
+type recvMessage struct {
+	MessageId         string             `xml:"MessageId"`
+	MessageMD5        string             `xml:"MD5OfBody"`
+	MessageBody       string             `xml:"Body"`
+	ReceiptHandle     string             `xml:"ReceiptHandle"`
+	Attributes        []Attribute        `xml:"Attribute"`
+	MessageAttributes []MessageAttribute `xml:"MessageAttribute"`
+	AttributesMD5     string             `xml:"MD5OfMessageAttributes"`
+}
+
+type recvMessagesResponse struct {
+	Messages []recvMessage `xml:"ReceiveMessageResult>Message"`
+	BasicResponse
+}
+
 func (s *SQSRequest) ReceiveSQSMessage() (*RecvMessageResponse, error) {
 	params := map[string]string{
-		"Action": "ReceiveMessage",
+		"Action":                 "ReceiveMessage",
+		"AttributeName.1":        AttrAll,
+		"MessageAttributeName.1": AttrAll,
+	}
+	if s.WaitSeconds > 0 {
+		params["WaitTimeSeconds"] = strconv.Itoa(s.WaitSeconds)
 	}
 
-	reader, err := s.makeSQSQueueRequest(params)
+	msgs, err := s.receiveSQSMessages(context.Background(), params)
 	if err != nil {
 		return nil, err
 	}
-	defer reader.Close()
 
-	rmr := new(RecvMessageResponse)
-	err = xml.NewDecoder(reader).Decode(rmr)
-	if err != nil {
-		return nil, err
+	if len(msgs) == 0 {
+		return nil, ErrNoMessage
 	}
 
-	if rmr.MessageBody == "" && rmr.MessageMD5 == "" {
-		return nil, errors.New("No message to dequeue.")
+	return msgs[0], nil
+}
+
+// ReceiveSQSMessages receives up to max messages, waiting up to
+// waitSeconds for at least one to arrive. An empty queue yields an empty
+// slice rather than ErrNoMessage.
+func (s *SQSRequest) ReceiveSQSMessages(max, waitSeconds int) ([]*RecvMessageResponse, error) {
+	return s.ReceiveSQSMessagesContext(context.Background(), max, waitSeconds)
+}
+
+func (s *SQSRequest) ReceiveSQSMessagesContext(ctx context.Context, max, waitSeconds int) ([]*RecvMessageResponse, error) {
+	params := map[string]string{
+		"Action":                 "ReceiveMessage",
+		"AttributeName.1":        AttrAll,
+		"MessageAttributeName.1": AttrAll,
+		"MaxNumberOfMessages":    strconv.Itoa(max),
+		"WaitTimeSeconds":        strconv.Itoa(waitSeconds),
 	}
 
-	rmr.MessageBody, err = url.QueryUnescape(rmr.MessageBody)
+	return s.receiveSQSMessages(ctx, params)
+}
+
+// Peek receives up to max messages without hiding them from other
+// consumers: their visibility timeout is zero, so they remain available
+// and their receipt handles are of no use.
+func (s *SQSRequest) Peek(max int) ([]*RecvMessageResponse, error) {
+	params := map[string]string{
+		"Action":                 "ReceiveMessage",
+		"AttributeName.1":        AttrAll,
+		"MessageAttributeName.1": AttrAll,
+		"MaxNumberOfMessages":    strconv.Itoa(max),
+		"VisibilityTimeout":      "0",
+	}
+
+	return s.receiveSQSMessages(context.Background(), params)
+}
+
+func (s *SQSRequest) receiveSQSMessages(ctx context.Context, params map[string]string) ([]*RecvMessageResponse, error) {
+	if _, ok := params["VisibilityTimeout"]; !ok && s.VisibilityTimeout > 0 {
+		params["VisibilityTimeout"] = strconv.Itoa(s.VisibilityTimeout)
+	}
+
+	reader, err := s.makeSQSQueueRequestContext(ctx, params)
 	if err != nil {
 		return nil, err
 	}
+	defer reader.Close()
+
+	rmr := new(recvMessagesResponse)
+	if err = xml.NewDecoder(reader).Decode(rmr); err != nil {
+		return nil, err
+	}
 
-	return rmr, nil
+	if s.Metrics != nil {
+		s.Metrics.ObserveReceive(len(rmr.Messages))
+	}
+
+	msgs := make([]*RecvMessageResponse, 0, len(rmr.Messages))
+	for _, m := range rmr.Messages {
+		if err = s.verifyMessage(m.MessageId, m.MessageBody, m.MessageMD5, m.MessageAttributes, m.AttributesMD5); err != nil {
+			return nil, err
+		}
+
+		if err = s.inflate(ctx, &m); err != nil {
+			return nil, err
+		}
+
+		body, err := s.decodeBody(m.MessageBody, m.MessageAttributes)
+		if err != nil {
+			return nil, err
+		}
+
+		msgs = append(msgs, &RecvMessageResponse{
+			MessageId:         m.MessageId,
+			MessageMD5:        m.MessageMD5,
+			MessageBody:       string(body),
+			ReceiptHandle:     m.ReceiptHandle,
+			Attributes:        m.Attributes,
+			MessageAttributes: m.MessageAttributes,
+			AttributesMD5:     m.AttributesMD5,
+			BasicResponse:     rmr.BasicResponse,
+		})
+	}
+
+	return msgs, nil
 }
 
+// DeleteSQSMessage deletes a message, along with its payload if that was
+// kept in a PayloadStore.
 func (s *SQSRequest) DeleteSQSMessage(handle string) (*BasicResponse, error) {
+	handle, payload := splitReceiptHandle(handle)
+
 	params := map[string]string{
 		"Action":        "DeleteMessage",
 		"ReceiptHandle": handle,
@@ -196,15 +744,32 @@ func (s *SQSRequest) DeleteSQSMessage(handle string) (*BasicResponse, error) {
 		return nil, err
 	}
 
+	if payload != "" {
+		if err = s.deletePayload(context.Background(), payload); err != nil {
+			return nil, err
+		}
+	}
+
 	return bmr, nil
 }
 
 func (s *SQSRequest) QueueURL() (*QueueURLResponse, error) {
+	return s.QueueURLForOwner("")
+}
+
+// QueueURLForOwner looks up the URL of the queue named QueueName in the
+// account ownerAccountId, which must have granted this client access. An
+// empty owner means the client's own account.
+func (s *SQSRequest) QueueURLForOwner(ownerAccountId string) (*QueueURLResponse, error) {
 	params := map[string]string{
-		"Action": "GetQueueUrl",
+		"Action":    "GetQueueUrl",
 		"QueueName": s.QueueName,
 	}
 
+	if ownerAccountId != "" {
+		params["QueueOwnerAWSAccountId"] = ownerAccountId
+	}
+
 	reader, err := s.makeSQSAdminRequest(params)
 	if err != nil {
 		return nil, err
@@ -219,12 +784,32 @@ func (s *SQSRequest) QueueURL() (*QueueURLResponse, error) {
 	return qur, nil
 }
 
+// ListQueues returns the queues whose names start with prefix. SQS returns
+// at most 1000 queues this way; use ListQueuesPages or ListAllQueues to see
+// all of them.
 func (s *SQSRequest) ListQueues(prefix string) (*QueueListResponse, error) {
+	return s.ListQueuesPage(prefix, "", 0)
+}
+
+// maxListResults is the largest page size the SQS list actions accept.
+const maxListResults = 1000
+
+// ListQueuesPage returns a single page of at most maxResults queues,
+// starting at nextToken. SQS only paginates, and sets NextToken in the
+// response, when maxResults is non-zero.
+func (s *SQSRequest) ListQueuesPage(prefix, nextToken string, maxResults int) (*QueueListResponse, error) {
 	params := map[string]string{
 		"Action":          "ListQueues",
 		"QueueNamePrefix": prefix,
 	}
 
+	if nextToken != "" {
+		params["NextToken"] = nextToken
+	}
+	if maxResults > 0 {
+		params["MaxResults"] = strconv.Itoa(maxResults)
+	}
+
 	reader, err := s.makeSQSAdminRequest(params)
 	if err != nil {
 		return nil, err
@@ -239,31 +824,246 @@ func (s *SQSRequest) ListQueues(prefix string) (*QueueListResponse, error) {
 	return qr, nil
 }
 
+// ListQueuesPages calls fn with each page of queues whose names start with
+// prefix, until fn returns false or there are no more pages.
+func (s *SQSRequest) ListQueuesPages(prefix string, fn func(page *QueueListResponse) bool) error {
+	token := ""
+	for {
+		qr, err := s.ListQueuesPage(prefix, token, maxListResults)
+		if err != nil {
+			return err
+		}
+
+		if !fn(qr) || qr.NextToken == "" {
+			return nil
+		}
+		token = qr.NextToken
+	}
+}
+
+// ListAllQueues returns the URLs of every queue whose name starts with
+// prefix, walking all pages.
+func (s *SQSRequest) ListAllQueues(prefix string) ([]string, error) {
+	var urls []string
+	err := s.ListQueuesPages(prefix, func(page *QueueListResponse) bool {
+		urls = append(urls, page.QueueURLs...)
+		return true
+	})
+
+	return urls, err
+}
+
+// CreateQueueOptions carries the attributes of a queue being created.
+type CreateQueueOptions struct {
+	// FifoQueue creates a FIFO queue. The queue name must then end in
+	// ".fifo"; when the client has a QueueNaming, the suffix is added.
+	FifoQueue bool
+
+	// DelaySeconds postpones the delivery of every message sent to the
+	// queue, from 0 to 900 seconds.
+	DelaySeconds int
+
+	// KmsMasterKeyId enables server-side encryption with the given KMS key
+	// id, ARN or alias. KmsDataKeyReusePeriodSeconds, from 60 to 86400,
+	// sets how long data keys are reused before KMS is called again.
+	KmsMasterKeyId               string
+	KmsDataKeyReusePeriodSeconds int
+
+	// SqsManagedSseEnabled enables server-side encryption with keys owned
+	// by SQS. It cannot be combined with KmsMasterKeyId.
+	SqsManagedSseEnabled bool
+
+	// Attributes holds any further queue attributes by name. Typed fields
+	// above take precedence over the same attribute given here.
+	Attributes map[string]string
+
+	// Tags are applied to the queue as it is created.
+	Tags map[string]string
+}
+
+// QueueAttributes returns the validated queue attributes described by opts.
+func (opts *CreateQueueOptions) QueueAttributes() (map[string]string, error) {
+	attrs := make(map[string]string)
+	if opts == nil {
+		return attrs, nil
+	}
+
+	for name, value := range opts.Attributes {
+		attrs[name] = value
+	}
+
+	if opts.DelaySeconds < 0 || opts.DelaySeconds > maxDelaySeconds {
+		return nil, fmt.Errorf("DelaySeconds must be between 0 and %d, got %d.", maxDelaySeconds, opts.DelaySeconds)
+	}
+	if opts.DelaySeconds > 0 {
+		attrs[AttrDelaySeconds] = strconv.Itoa(opts.DelaySeconds)
+	}
+	if opts.FifoQueue {
+		attrs[AttrFifoQueue] = "true"
+	}
+	if opts.KmsMasterKeyId != "" {
+		attrs[AttrKmsMasterKeyId] = opts.KmsMasterKeyId
+	}
+	if opts.KmsDataKeyReusePeriodSeconds > 0 {
+		attrs[AttrKmsDataKeyReusePeriodSeconds] = strconv.Itoa(opts.KmsDataKeyReusePeriodSeconds)
+	}
+	if opts.SqsManagedSseEnabled {
+		attrs[AttrSqsManagedSseEnabled] = "true"
+	}
+
+	if err := ValidateQueueAttributes(attrs); err != nil {
+		return nil, err
+	}
+
+	return attrs, nil
+}
+
 func (s *SQSRequest) CreateQueue(queueName string, options map[string]string) (*QueueURLResponse, error) {
+	return s.CreateQueueWithOptions(queueName, &CreateQueueOptions{Attributes: options})
+}
+
+func (s *SQSRequest) CreateQueueWithOptions(queueName string, opts *CreateQueueOptions) (*QueueURLResponse, error) {
+	attrs, err := opts.QueueAttributes()
+	if err != nil {
+		return nil, err
+	}
+
+	queueName, err = s.queueName(queueName, attrs[AttrFifoQueue] == "true")
+	if err != nil {
+		return nil, err
+	}
+
 	params := map[string]string{
-		"Action": "CreateQueue",
+		"Action":    "CreateQueue",
 		"QueueName": queueName,
 	}
 
 	count := 1
-	for name, value := range options {
+	for name, value := range attrs {
 		params[fmt.Sprintf("Attribute.%d.Name", count)] = name
 		params[fmt.Sprintf("Attribute.%d.Value", count)] = value
 		count++
 	}
 
+	if opts != nil {
+		setTagParams(params, opts.Tags)
+	}
+
 	reader, err := s.makeSQSAdminRequest(params)
 	if err != nil {
-		er := new(ErrorResponse)
-		xml.NewDecoder(reader).Decode(er)
-		log.Println(er)
 		return nil, err
 	}
+	defer reader.Close()
 
 	qur := new(QueueURLResponse)
 	if err = xml.NewDecoder(reader).Decode(qur); err != nil {
 		return nil, err
 	}
 
+	s.emitQueueEvent(QueueCreated, queueName, qur.QueueURL, attrs)
+
 	return qur, nil
 }
+
+func (s *SQSRequest) DeleteQueue() (*BasicResponse, error) {
+	params := map[string]string{
+		"Action": "DeleteQueue",
+	}
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	bmr := new(BasicResponse)
+	if err = xml.NewDecoder(reader).Decode(bmr); err != nil {
+		return nil, err
+	}
+
+	s.emitQueueEvent(QueueDeleted, s.QueueName, s.generateSQSQueueURI(), nil)
+
+	return bmr, nil
+}
+
+// PurgeQueue deletes every message in the queue. SQS acknowledges the
+// request immediately but may take up to a minute to finish; use
+// PurgeQueueAndWait to block until it has.
+func (s *SQSRequest) PurgeQueue() (*BasicResponse, error) {
+	params := map[string]string{
+		"Action": "PurgeQueue",
+	}
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	bmr := new(BasicResponse)
+	if err = xml.NewDecoder(reader).Decode(bmr); err != nil {
+		return nil, err
+	}
+
+	s.emitQueueEvent(QueuePurgeStarted, s.QueueName, s.generateSQSQueueURI(), nil)
+
+	return bmr, nil
+}
+
+// purgeDuration is how long SQS may take to complete a purge.
+const purgeDuration = 60 * time.Second
+
+// PurgeQueueAndWait purges the queue and returns once it reports no
+// messages, or once the purge window has elapsed.
+func (s *SQSRequest) PurgeQueueAndWait(ctx context.Context) (*BasicResponse, error) {
+	bmr, err := s.PurgeQueue()
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(purgeDuration)
+	for time.Now().Before(deadline) {
+		qar, err := s.GetQueueAttributes(AttrApproximateNumberOfMessages, AttrApproximateNumberOfMessagesNotVisible)
+		if err == nil && qar.Attributes.ApproximateNumberOfMessages == 0 && qar.Attributes.ApproximateNumberOfMessagesNotVisible == 0 {
+			break
+		}
+
+		t := time.NewTimer(5 * time.Second)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		case <-t.C:
+		}
+	}
+
+	s.emitQueueEvent(QueuePurgeFinished, s.QueueName, s.generateSQSQueueURI(), nil)
+
+	return bmr, nil
+}
+
+// WithQueueURL returns a copy of the client that targets the queue at
+// queueURL, which may belong to another account. RegionId, UUID and
+// QueueName are taken from the URL when it has the usual
+// https://sqs.<region>.amazonaws.com/<account>/<name> form.
+func (s *SQSRequest) WithQueueURL(queueURL string) (*SQSRequest, error) {
+	u, err := url.Parse(queueURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("Invalid queue URL %q.", queueURL)
+	}
+
+	q := *s
+	q.ExplicitQueueURL = queueURL
+
+	if parts := strings.Split(strings.Trim(u.Path, "/"), "/"); len(parts) == 2 {
+		q.UUID, q.QueueName = parts[0], parts[1]
+	}
+	if host := strings.Split(u.Host, "."); len(host) == 4 && host[0] == "sqs" {
+		q.RegionId = host[1]
+	}
+
+	return &q, nil
+}
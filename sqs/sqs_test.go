@@ -0,0 +1,26 @@
+package sqs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWithAttribute(t *testing.T) {
+	var none *SendOptions
+	if o := none.withAttribute("a", "1"); !reflect.DeepEqual(o.MessageAttributes, map[string]string{"a": "1"}) {
+		t.Errorf("from nil = %+v", o)
+	}
+
+	opts := &SendOptions{DelaySeconds: 5, MessageGroupId: "g", MessageAttributes: map[string]string{"a": "1"}}
+	o := opts.withAttribute("b", "2")
+
+	if o.DelaySeconds != 5 || o.MessageGroupId != "g" {
+		t.Errorf("copy lost options: %+v", o)
+	}
+	if !reflect.DeepEqual(o.MessageAttributes, map[string]string{"a": "1", "b": "2"}) {
+		t.Errorf("attributes = %v", o.MessageAttributes)
+	}
+	if !reflect.DeepEqual(opts.MessageAttributes, map[string]string{"a": "1"}) {
+		t.Errorf("original changed to %v", opts.MessageAttributes)
+	}
+}
@@ -0,0 +1,626 @@
+package sqstest
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+// Queue is a client of one queue of a Fake.
+type Queue struct {
+	fake *Fake
+	name string
+}
+
+var _ sqs.SQSClient = (*Queue)(nil)
+
+// Name returns the name of the queue.
+func (q *Queue) Name() string {
+	return q.name
+}
+
+func (q *Queue) SendSQSMessage(message []byte) (*sqs.SendMessageResponse, error) {
+	return q.SendSQSMessageWithOptions(message, nil)
+}
+
+func (q *Queue) SendSQSMessageWithOptions(message []byte, opts *sqs.SendOptions) (*sqs.SendMessageResponse, error) {
+	return q.SendSQSMessageContext(context.Background(), message, opts)
+}
+
+func (q *Queue) SendSQSMessageContext(ctx context.Context, message []byte, opts *sqs.SendOptions) (*sqs.SendMessageResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	smr, err := fq.send(q.fake.now(), string(message), opts)
+	if err != nil {
+		return nil, err
+	}
+	q.fake.notify()
+
+	return smr, nil
+}
+
+func (q *Queue) SendSQSMessageBatch(entries []sqs.BatchEntry) (*sqs.SendMessageBatchResponse, error) {
+	if err := checkBatch(len(entries), func(i int) string { return entries[i].Id }); err != nil {
+		return nil, err
+	}
+
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	smr := &sqs.SendMessageBatchResponse{BasicResponse: basicResponse()}
+	for _, entry := range entries {
+		opts := entry.SendOptions
+		r, err := fq.send(q.fake.now(), string(entry.Body), &opts)
+		if err != nil {
+			smr.Failed = append(smr.Failed, batchResultError(entry.Id, err))
+			continue
+		}
+
+		smr.Successful = append(smr.Successful, sqs.SendMessageBatchResultEntry{
+			Id:         entry.Id,
+			MessageId:  r.MessageId,
+			MessageMD5: r.MessageMD5,
+		})
+	}
+	q.fake.notify()
+
+	return smr, nil
+}
+
+var batchEntryId = regexp.MustCompile(`^[A-Za-z0-9_-]{1,80}$`)
+
+// checkBatch validates the number and the ids of the entries of a batch.
+func checkBatch(n int, id func(int) string) error {
+	if n == 0 {
+		return senderError("AWS.SimpleQueueService.EmptyBatchRequest", "There should be at least one SendMessageBatchRequestEntry in the request.")
+	}
+	if n > maxBatchEntries {
+		return senderError("AWS.SimpleQueueService.TooManyEntriesInBatchRequest", "Maximum number of entries per request are %d. You have sent %d.", maxBatchEntries, n)
+	}
+
+	seen := make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		if !batchEntryId.MatchString(id(i)) {
+			return senderError("AWS.SimpleQueueService.InvalidBatchEntryId", "A batch entry id can only contain alphanumeric characters, hyphens and underscores. It can be at most 80 letters long.")
+		}
+		if seen[id(i)] {
+			return senderError("AWS.SimpleQueueService.BatchEntryIdsNotDistinct", "Id %s repeated.", id(i))
+		}
+		seen[id(i)] = true
+	}
+
+	return nil
+}
+
+func batchResultError(id string, err error) sqs.BatchResultError {
+	bre := sqs.BatchResultError{Id: id, Message: err.Error(), SenderFault: true}
+	if er, ok := err.(*sqs.ErrorResponse); ok {
+		bre.Code = er.Code
+		bre.Message = er.Message
+	}
+
+	return bre
+}
+
+// ReceiveSQSMessage receives a message, long polling for as long as the
+// queue's ReceiveMessageWaitTimeSeconds says.
+func (q *Queue) ReceiveSQSMessage() (*sqs.RecvMessageResponse, error) {
+	q.fake.mu.Lock()
+	fq, err := q.fake.queue(q.name)
+	var wait int
+	if err == nil {
+		wait = fq.intAttr(sqs.AttrReceiveMessageWaitTimeSeconds, 0)
+	}
+	q.fake.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := q.receive(context.Background(), 1, wait, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, sqs.ErrNoMessage
+	}
+
+	return msgs[0], nil
+}
+
+func (q *Queue) ReceiveSQSMessages(max, waitSeconds int) ([]*sqs.RecvMessageResponse, error) {
+	return q.ReceiveSQSMessagesContext(context.Background(), max, waitSeconds)
+}
+
+func (q *Queue) ReceiveSQSMessagesContext(ctx context.Context, max, waitSeconds int) ([]*sqs.RecvMessageResponse, error) {
+	return q.receive(ctx, max, waitSeconds, -1)
+}
+
+// Peek receives up to max messages with a visibility timeout of zero.
+func (q *Queue) Peek(max int) ([]*sqs.RecvMessageResponse, error) {
+	return q.receive(context.Background(), max, 0, 0)
+}
+
+// receive long polls for up to waitSeconds for messages. A negative
+// visibility stands for the queue's visibility timeout.
+func (q *Queue) receive(ctx context.Context, max, waitSeconds, visibility int) ([]*sqs.RecvMessageResponse, error) {
+	if max < 1 || max > maxBatchEntries {
+		return nil, invalidParameter("Value %d for parameter MaxNumberOfMessages is invalid. Reason: Must be between 1 and %d, if provided.", max, maxBatchEntries)
+	}
+	if waitSeconds < 0 || waitSeconds > 20 {
+		return nil, invalidParameter("Value %d for parameter WaitTimeSeconds is invalid. Reason: Must be >= 0 and <= 20, if provided.", waitSeconds)
+	}
+
+	f := q.fake
+	f.mu.Lock()
+	deadline := f.now().Add(time.Duration(waitSeconds) * time.Second)
+	for {
+		if err := ctx.Err(); err != nil {
+			f.mu.Unlock()
+			return nil, err
+		}
+
+		fq, err := f.queue(q.name)
+		if err != nil {
+			f.mu.Unlock()
+			return nil, err
+		}
+
+		timeout := visibility
+		if timeout < 0 {
+			timeout = fq.intAttr(sqs.AttrVisibilityTimeout, defaultVisibilityTimeout)
+		}
+
+		now := f.now()
+		msgs, next := fq.receive(f, now, max, timeout)
+		if len(msgs) > 0 || !now.Before(deadline) {
+			f.mu.Unlock()
+			return msgs, nil
+		}
+
+		wait := deadline.Sub(now)
+		if !next.IsZero() && next.Sub(now) < wait {
+			wait = next.Sub(now)
+		}
+		changed := f.changed
+		f.mu.Unlock()
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+		case <-changed:
+		case <-t.C:
+		}
+		t.Stop()
+
+		f.mu.Lock()
+	}
+}
+
+func (q *Queue) DeleteSQSMessage(handle string) (*sqs.BasicResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = fq.delete(handle); err != nil {
+		return nil, err
+	}
+
+	br := basicResponse()
+	return &br, nil
+}
+
+// DeleteSQSMessageBatch deletes up to ten messages. As with SQSRequest,
+// the ids in the response are the indexes of the handles.
+func (q *Queue) DeleteSQSMessageBatch(handles []string) (*sqs.DeleteMessageBatchResponse, error) {
+	if err := checkBatch(len(handles), strconv.Itoa); err != nil {
+		return nil, err
+	}
+
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	dmr := &sqs.DeleteMessageBatchResponse{BasicResponse: basicResponse()}
+	for i, handle := range handles {
+		id := strconv.Itoa(i)
+		if err := fq.delete(handle); err != nil {
+			dmr.Failed = append(dmr.Failed, batchResultError(id, err))
+			continue
+		}
+		dmr.Successful = append(dmr.Successful, id)
+	}
+
+	return dmr, nil
+}
+
+// DeleteAll deletes the messages with the given receipt handles, ten at a
+// time. As with SQSRequest, rejected entries are reported through a
+// BatchError whose ids are the indexes of the handles.
+func (q *Queue) DeleteAll(ctx context.Context, handles []string) error {
+	var failed sqs.BatchError
+
+	for start := 0; start < len(handles); start += maxBatchEntries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := start + maxBatchEntries
+		if end > len(handles) {
+			end = len(handles)
+		}
+
+		dmr, err := q.DeleteSQSMessageBatch(handles[start:end])
+		if err != nil {
+			return err
+		}
+
+		for _, f := range dmr.Failed {
+			idx, _ := strconv.Atoi(f.Id)
+			f.Id = strconv.Itoa(start + idx)
+			failed = append(failed, f)
+		}
+	}
+
+	if len(failed) > 0 {
+		return failed
+	}
+
+	return nil
+}
+
+// ReleaseAll makes the given in-flight messages visible again. As with
+// SQSRequest, rejected entries are reported through a BatchError whose ids
+// are the indexes of the messages.
+func (q *Queue) ReleaseAll(ctx context.Context, msgs []*sqs.RecvMessageResponse) error {
+	var failed sqs.BatchError
+
+	for i, m := range msgs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		_, err := q.ChangeMessageVisibility(m.ReceiptHandle, 0)
+		if er, ok := err.(*sqs.ErrorResponse); ok && er.Code == "AWS.SimpleQueueService.NonExistentQueue" {
+			return err
+		}
+		if err != nil {
+			failed = append(failed, batchResultError(strconv.Itoa(i), err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return failed
+	}
+
+	return nil
+}
+
+func (q *Queue) ChangeMessageVisibility(handle string, timeout int) (*sqs.BasicResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = fq.changeVisibility(q.fake.now(), handle, timeout); err != nil {
+		return nil, err
+	}
+	q.fake.notify()
+
+	br := basicResponse()
+	return &br, nil
+}
+
+func (q *Queue) QueueURL() (*sqs.QueueURLResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	if _, err := q.fake.queue(q.name); err != nil {
+		return nil, err
+	}
+
+	return &sqs.QueueURLResponse{QueueURL: QueueURL(q.name), BasicResponse: basicResponse()}, nil
+}
+
+// QueueURLForOwner returns the URL of the queue if ownerAccountId is
+// empty or AccountId; the fake knows no other accounts.
+func (q *Queue) QueueURLForOwner(ownerAccountId string) (*sqs.QueueURLResponse, error) {
+	if ownerAccountId != "" && ownerAccountId != AccountId {
+		return nil, senderError("AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist.")
+	}
+
+	return q.QueueURL()
+}
+
+func (q *Queue) CreateQueue(queueName string, options map[string]string) (*sqs.QueueURLResponse, error) {
+	return q.CreateQueueWithOptions(queueName, &sqs.CreateQueueOptions{Attributes: options})
+}
+
+var validQueueName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,80}$`)
+
+// CreateQueueWithOptions creates the named queue, which need not be the
+// one of the client. Creating a queue that exists succeeds only if the
+// attributes are the same.
+func (q *Queue) CreateQueueWithOptions(name string, opts *sqs.CreateQueueOptions) (*sqs.QueueURLResponse, error) {
+	attrs, err := opts.QueueAttributes()
+	if err != nil {
+		return nil, err
+	}
+
+	fifo, _ := strconv.ParseBool(attrs[sqs.AttrFifoQueue])
+	if !validQueueName.MatchString(strings.TrimSuffix(name, ".fifo")) || fifo != strings.HasSuffix(name, ".fifo") {
+		return nil, invalidParameter("Can only include alphanumeric characters, hyphens, or underscores. 1 to 80 in length. FIFO queue names must end in .fifo.")
+	}
+
+	f := q.fake
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if fq, ok := f.queues[name]; ok {
+		for attr, value := range attrs {
+			if fq.attrs[attr] != value {
+				return nil, senderError("QueueAlreadyExists", "A queue already exists with the same name and a different value for attribute %s", attr)
+			}
+		}
+	} else {
+		now := f.now()
+		fq = &queue{
+			name:     name,
+			attrs:    attrs,
+			tags:     make(map[string]string),
+			created:  now,
+			modified: now,
+			dedup:    make(map[string]dedupEntry),
+		}
+		if opts != nil {
+			for key, value := range opts.Tags {
+				fq.tags[key] = value
+			}
+		}
+		f.queues[name] = fq
+	}
+
+	return &sqs.QueueURLResponse{QueueURL: QueueURL(name), BasicResponse: basicResponse()}, nil
+}
+
+func (q *Queue) DeleteQueue() (*sqs.BasicResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	if _, err := q.fake.queue(q.name); err != nil {
+		return nil, err
+	}
+	delete(q.fake.queues, q.name)
+	q.fake.notify()
+
+	br := basicResponse()
+	return &br, nil
+}
+
+// PurgeQueue deletes every message in the queue. Unlike in SQS, the purge
+// is complete when it returns.
+func (q *Queue) PurgeQueue() (*sqs.BasicResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+	fq.messages = nil
+
+	br := basicResponse()
+	return &br, nil
+}
+
+// ListQueues lists the URLs of up to 1000 queues whose names start with
+// prefix, in order.
+func (q *Queue) ListQueues(prefix string) (*sqs.QueueListResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	names := q.fake.queueNames(prefix)
+	if len(names) > 1000 {
+		names = names[:1000]
+	}
+
+	qlr := &sqs.QueueListResponse{BasicResponse: basicResponse()}
+	for _, name := range names {
+		qlr.QueueURLs = append(qlr.QueueURLs, QueueURL(name))
+	}
+
+	return qlr, nil
+}
+
+// ListQueuesPage lists the URLs of up to maxResults queues whose names
+// start with prefix, in order, starting after nextToken. Like SQS, it only
+// paginates when maxResults is non-zero.
+func (q *Queue) ListQueuesPage(prefix, nextToken string, maxResults int) (*sqs.QueueListResponse, error) {
+	if maxResults == 0 {
+		if nextToken != "" {
+			return nil, invalidParameter("MaxResults is a mandatory parameter when you provide a value for NextToken.")
+		}
+		return q.ListQueues(prefix)
+	}
+
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	names, token, err := page(q.fake.queueNames(prefix), nextToken, maxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	qlr := &sqs.QueueListResponse{NextToken: token, BasicResponse: basicResponse()}
+	for _, name := range names {
+		qlr.QueueURLs = append(qlr.QueueURLs, QueueURL(name))
+	}
+
+	return qlr, nil
+}
+
+// page returns up to max of the sorted names that come after token, along
+// with the token of the next page, if any. Tokens are queue names.
+func page(names []string, token string, max int) ([]string, string, error) {
+	if max < 1 || max > 1000 {
+		return nil, "", invalidParameter("Value %d for parameter MaxResults is invalid. Reason: Must be between 1 and 1000.", max)
+	}
+
+	start := sort.SearchStrings(names, token)
+	if start < len(names) && names[start] == token {
+		start++
+	}
+	names = names[start:]
+
+	if len(names) <= max {
+		return names, "", nil
+	}
+
+	return names[:max], names[max-1], nil
+}
+
+func (q *Queue) GetQueueAttributes(names ...string) (*sqs.QueueAttributesResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	all := fq.attributes(q.fake.now())
+	raw := all
+	if len(names) > 0 {
+		raw = make(map[string]string)
+	}
+	for _, name := range names {
+		if name == sqs.AttrAll {
+			raw = all
+			break
+		}
+
+		value, ok := all[name]
+		if !ok {
+			return nil, senderError("InvalidAttributeName", "Unknown Attribute %s.", name)
+		}
+		raw[name] = value
+	}
+
+	qa, err := sqs.ParseQueueAttributes(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqs.QueueAttributesResponse{Attributes: qa, BasicResponse: basicResponse()}, nil
+}
+
+func (q *Queue) SetQueueAttributes(attributes map[string]string) (*sqs.BasicResponse, error) {
+	if err := sqs.ValidateQueueAttributes(attributes); err != nil {
+		return nil, err
+	}
+
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	if value, ok := attributes[sqs.AttrFifoQueue]; ok && value != fq.attrs[sqs.AttrFifoQueue] {
+		return nil, senderError("InvalidAttributeName", "Unknown Attribute %s.", sqs.AttrFifoQueue)
+	}
+
+	for name, value := range attributes {
+		if value == "" {
+			delete(fq.attrs, name)
+			continue
+		}
+		fq.attrs[name] = value
+	}
+	fq.modified = q.fake.now()
+
+	br := basicResponse()
+	return &br, nil
+}
+
+func (q *Queue) TagQueue(tags map[string]string) (*sqs.BasicResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range tags {
+		fq.tags[key] = value
+	}
+
+	br := basicResponse()
+	return &br, nil
+}
+
+func (q *Queue) UntagQueue(keys ...string) (*sqs.BasicResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range keys {
+		delete(fq.tags, key)
+	}
+
+	br := basicResponse()
+	return &br, nil
+}
+
+func (q *Queue) ListQueueTags() (*sqs.QueueTagsResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(fq.tags))
+	for key, value := range fq.tags {
+		tags[key] = value
+	}
+
+	return &sqs.QueueTagsResponse{Tags: tags, BasicResponse: basicResponse()}, nil
+}
@@ -0,0 +1,176 @@
+// Package sqstest provides an in-memory fake of SQS for tests. It
+// implements sqs.SQSClient with the semantics tests usually depend on:
+// visibility timeouts, delivery delays, receive counts, redrive to and
+// from dead-letter queues and, on FIFO queues, ordering within message
+// groups and deduplication. Since the fake implements the interface, a
+// Consumer, Producer or Relay can run against it.
+package sqstest
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+const (
+	// Region and AccountId appear in the URLs and ARNs of fake queues.
+	Region    = "us-east-1"
+	AccountId = "000000000000"
+)
+
+// Defaults of the queue attributes the fake takes into account.
+const (
+	defaultVisibilityTimeout = 30
+	defaultMaximumSize       = 262144
+	defaultRetentionPeriod   = 345600
+	fifoDedupInterval        = 5 * time.Minute
+	maxBatchEntries          = 10
+	maxMessageAttributes     = 10
+)
+
+// Fake holds a set of in-memory queues. Its clock follows the wall clock
+// but can be moved forward with Advance, so tests need not wait for
+// visibility timeouts and delays to expire.
+type Fake struct {
+	mu      sync.Mutex
+	queues  map[string]*queue
+	offset  time.Duration
+	changed chan struct{}
+}
+
+func New() *Fake {
+	return &Fake{
+		queues:  make(map[string]*queue),
+		changed: make(chan struct{}),
+	}
+}
+
+// Now returns the current time of the fake's clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.now()
+}
+
+func (f *Fake) now() time.Time {
+	return time.Now().Add(f.offset)
+}
+
+// Advance moves the fake's clock forward by d. Long polls waiting for
+// messages that become visible as a result return them.
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.offset += d
+	f.notify()
+}
+
+// notify wakes up long polls so they look at the queues again.
+func (f *Fake) notify() {
+	close(f.changed)
+	f.changed = make(chan struct{})
+}
+
+// Queue returns a client for the named queue. The queue need not exist:
+// operations on a queue that does not fail like they do in SQS, and
+// CreateQueue may be called on any client to create it.
+func (f *Fake) Queue(name string) *Queue {
+	return &Queue{fake: f, name: name}
+}
+
+// QueueURL returns the URL of the named queue.
+func QueueURL(name string) string {
+	return fmt.Sprintf("https://sqs.%s.amazonaws.com/%s/%s", Region, AccountId, name)
+}
+
+// QueueARN returns the ARN of the named queue, as used in redrive
+// policies.
+func QueueARN(name string) string {
+	return fmt.Sprintf("arn:aws:sqs:%s:%s:%s", Region, AccountId, name)
+}
+
+func (f *Fake) queue(name string) (*queue, error) {
+	q, ok := f.queues[name]
+	if !ok {
+		return nil, senderError("AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist.")
+	}
+
+	return q, nil
+}
+
+// queueByARN returns the queue with the given ARN, or nil if there is no
+// such queue.
+func (f *Fake) queueByARN(arn string) *queue {
+	prefix := QueueARN("")
+	if !strings.HasPrefix(arn, prefix) {
+		return nil
+	}
+
+	return f.queues[strings.TrimPrefix(arn, prefix)]
+}
+
+func (f *Fake) queueNames(prefix string) []string {
+	var names []string
+	for name := range f.queues {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// senderError returns an error like those SQS reports for invalid
+// requests.
+func senderError(code, format string, args ...interface{}) error {
+	return &sqs.ErrorResponse{
+		Type:      "Sender",
+		Code:      code,
+		Message:   fmt.Sprintf(format, args...),
+		RequestId: newId(),
+	}
+}
+
+func invalidParameter(format string, args ...interface{}) error {
+	return senderError("InvalidParameterValue", format, args...)
+}
+
+func basicResponse() sqs.BasicResponse {
+	return sqs.BasicResponse{RequestId: newId()}
+}
+
+// newId returns a random id in the UUID format SQS uses for message and
+// request ids.
+func newId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	h := hex.EncodeToString(b)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+func newReceiptHandle() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return hex.EncodeToString(b)
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
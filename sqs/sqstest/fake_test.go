@@ -0,0 +1,404 @@
+package sqstest
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+func newQueue(t *testing.T, f *Fake, name string, attrs map[string]string) *Queue {
+	t.Helper()
+
+	q := f.Queue(name)
+	if _, err := q.CreateQueue(name, attrs); err != nil {
+		t.Fatal(err)
+	}
+
+	return q
+}
+
+func send(t *testing.T, q *Queue, body string, opts *sqs.SendOptions) {
+	t.Helper()
+
+	if _, err := q.SendSQSMessageWithOptions([]byte(body), opts); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// bodies receives up to max messages without waiting and returns their
+// bodies.
+func bodies(t *testing.T, q *Queue, max int) []string {
+	t.Helper()
+
+	msgs, err := q.ReceiveSQSMessages(max, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, m := range msgs {
+		got = append(got, m.MessageBody)
+	}
+
+	return got
+}
+
+func TestVisibilityTimeout(t *testing.T) {
+	f := New()
+	q := newQueue(t, f, "orders", map[string]string{sqs.AttrVisibilityTimeout: "30"})
+	send(t, q, "a", nil)
+
+	msgs, err := q.ReceiveSQSMessages(1, 0)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("receive = %v, %v", msgs, err)
+	}
+	if got := bodies(t, q, 1); len(got) != 0 {
+		t.Fatalf("in-flight message received again: %v", got)
+	}
+
+	f.Advance(31 * time.Second)
+	again, err := q.ReceiveSQSMessages(1, 0)
+	if err != nil || len(again) != 1 {
+		t.Fatalf("receive after timeout = %v, %v", again, err)
+	}
+	if again[0].ReceiveCount() != 2 {
+		t.Errorf("ReceiveCount = %d, want 2", again[0].ReceiveCount())
+	}
+
+	if _, err = q.DeleteSQSMessage(msgs[0].ReceiptHandle); err == nil {
+		t.Error("delete with a stale receipt handle succeeded")
+	}
+
+	if _, err = q.ChangeMessageVisibility(again[0].ReceiptHandle, 0); err != nil {
+		t.Fatal(err)
+	}
+	released, err := q.ReceiveSQSMessages(1, 0)
+	if err != nil || len(released) != 1 {
+		t.Fatalf("receive after release = %v, %v", released, err)
+	}
+
+	if _, err = q.DeleteSQSMessage(released[0].ReceiptHandle); err != nil {
+		t.Fatal(err)
+	}
+	f.Advance(time.Hour)
+	if got := bodies(t, q, 1); len(got) != 0 {
+		t.Errorf("deleted message received: %v", got)
+	}
+}
+
+func TestReleaseAndDeleteAll(t *testing.T) {
+	f := New()
+	q := newQueue(t, f, "orders", nil)
+	for _, body := range []string{"a", "b", "c"} {
+		send(t, q, body, nil)
+	}
+
+	msgs, err := q.ReceiveSQSMessages(10, 0)
+	if err != nil || len(msgs) != 3 {
+		t.Fatalf("receive = %v, %v", msgs, err)
+	}
+
+	stale := &sqs.RecvMessageResponse{ReceiptHandle: "stale"}
+	err = q.ReleaseAll(context.Background(), append(msgs[:2:2], stale))
+	if be, ok := err.(sqs.BatchError); !ok || len(be) != 1 || be[0].Id != "2" || be[0].Code != "ReceiptHandleIsInvalid" {
+		t.Fatalf("ReleaseAll error = %v, want entry 2 to fail", err)
+	}
+
+	released, err := q.ReceiveSQSMessages(10, 0)
+	if err != nil || len(released) != 2 {
+		t.Fatalf("receive after release = %v, %v", released, err)
+	}
+
+	handles := []string{released[0].ReceiptHandle, released[1].ReceiptHandle, msgs[2].ReceiptHandle}
+	if err = q.DeleteAll(context.Background(), handles); err != nil {
+		t.Fatal(err)
+	}
+
+	attrs, err := q.GetQueueAttributes(sqs.AttrAll)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := attrs.Attributes.ApproximateNumberOfMessages + attrs.Attributes.ApproximateNumberOfMessagesNotVisible; n != 0 {
+		t.Errorf("%d messages left after DeleteAll", n)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	f := New()
+	q := newQueue(t, f, "orders", map[string]string{sqs.AttrDelaySeconds: "60", sqs.AttrVisibilityTimeout: "3600"})
+
+	send(t, q, "queue delay", nil)
+	send(t, q, "message delay", &sqs.SendOptions{DelaySeconds: 10})
+
+	attrs, err := q.GetQueueAttributes(sqs.AttrApproximateNumberOfMessagesDelayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs.Attributes.ApproximateNumberOfMessagesDelayed != 2 {
+		t.Errorf("delayed = %d, want 2", attrs.Attributes.ApproximateNumberOfMessagesDelayed)
+	}
+
+	if got := bodies(t, q, 10); len(got) != 0 {
+		t.Fatalf("delayed messages received: %v", got)
+	}
+
+	f.Advance(10 * time.Second)
+	if got := bodies(t, q, 10); !reflect.DeepEqual(got, []string{"message delay"}) {
+		t.Errorf("after 10s got %v, want the message delay to override the queue's", got)
+	}
+
+	f.Advance(50 * time.Second)
+	if got := bodies(t, q, 10); !reflect.DeepEqual(got, []string{"queue delay"}) {
+		t.Errorf("after 60s got %v", got)
+	}
+}
+
+func TestLongPollWakesOnSend(t *testing.T) {
+	f := New()
+	q := newQueue(t, f, "orders", nil)
+
+	done := make(chan []*sqs.RecvMessageResponse)
+	go func() {
+		msgs, _ := q.ReceiveSQSMessages(1, 20)
+		done <- msgs
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	send(t, q, "a", nil)
+
+	select {
+	case msgs := <-done:
+		if len(msgs) != 1 {
+			t.Errorf("long poll returned %d messages", len(msgs))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("long poll did not return after a send")
+	}
+}
+
+func TestFIFO(t *testing.T) {
+	f := New()
+	q := newQueue(t, f, "orders.fifo", map[string]string{
+		sqs.AttrFifoQueue:                 "true",
+		sqs.AttrContentBasedDeduplication: "true",
+		sqs.AttrVisibilityTimeout:         "3600",
+	})
+
+	send(t, q, "a1", &sqs.SendOptions{MessageGroupId: "a"})
+	send(t, q, "b1", &sqs.SendOptions{MessageGroupId: "b"})
+	send(t, q, "a2", &sqs.SendOptions{MessageGroupId: "a"})
+	send(t, q, "a1", &sqs.SendOptions{MessageGroupId: "a"}) // deduplicated
+
+	if _, err := q.SendSQSMessage([]byte("x")); err == nil {
+		t.Error("send without MessageGroupId succeeded")
+	}
+	if _, err := q.SendSQSMessageWithOptions([]byte("x"), &sqs.SendOptions{MessageGroupId: "a", DelaySeconds: 5}); err == nil {
+		t.Error("send with a per-message delay succeeded")
+	}
+
+	first, err := q.ReceiveSQSMessages(1, 0)
+	if err != nil || len(first) != 1 || first[0].MessageBody != "a1" {
+		t.Fatalf("first receive = %v, %v", first, err)
+	}
+	if first[0].MessageGroupId() != "a" {
+		t.Errorf("MessageGroupId = %q", first[0].MessageGroupId())
+	}
+
+	// a2 is held back while a1 is in flight.
+	if got := bodies(t, q, 10); !reflect.DeepEqual(got, []string{"b1"}) {
+		t.Errorf("while a1 is in flight got %v, want [b1]", got)
+	}
+
+	if _, err = q.DeleteSQSMessage(first[0].ReceiptHandle); err != nil {
+		t.Fatal(err)
+	}
+	if got := bodies(t, q, 10); !reflect.DeepEqual(got, []string{"a2"}) {
+		t.Errorf("after deleting a1 got %v, want [a2]", got)
+	}
+
+	f.Advance(fifoDedupInterval)
+	send(t, q, "a1", &sqs.SendOptions{MessageGroupId: "c"})
+	if got := bodies(t, q, 10); !reflect.DeepEqual(got, []string{"a1"}) {
+		t.Errorf("after the deduplication interval got %v, want [a1]", got)
+	}
+}
+
+func TestRedrive(t *testing.T) {
+	f := New()
+	dlq := newQueue(t, f, "orders-dlq", nil)
+	q := newQueue(t, f, "orders", map[string]string{
+		sqs.AttrVisibilityTimeout: "0",
+		sqs.AttrRedrivePolicy:     (&sqs.RedrivePolicy{DeadLetterTargetArn: QueueARN("orders-dlq"), MaxReceiveCount: 2}).String(),
+	})
+	send(t, q, "poison", nil)
+
+	for i := 1; i <= 2; i++ {
+		if got := bodies(t, q, 1); len(got) != 1 {
+			t.Fatalf("receive %d got %v", i, got)
+		}
+	}
+	if got := bodies(t, q, 1); len(got) != 0 {
+		t.Fatalf("third receive got %v, want the message redriven", got)
+	}
+
+	msgs, err := dlq.Peek(1)
+	if err != nil || len(msgs) != 1 || msgs[0].MessageBody != "poison" {
+		t.Fatalf("dead-letter queue holds %v, %v", msgs, err)
+	}
+
+	sources, err := dlq.ListDeadLetterSourceQueues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(sources.QueueURLs, []string{QueueURL("orders")}) {
+		t.Errorf("source queues = %v", sources.QueueURLs)
+	}
+}
+
+func TestMessageMoveTask(t *testing.T) {
+	f := New()
+	dlq := newQueue(t, f, "orders-dlq", nil)
+	q := newQueue(t, f, "orders", map[string]string{
+		sqs.AttrVisibilityTimeout: "0",
+		sqs.AttrRedrivePolicy:     (&sqs.RedrivePolicy{DeadLetterTargetArn: QueueARN("orders-dlq"), MaxReceiveCount: 1}).String(),
+	})
+	other := newQueue(t, f, "audit", nil)
+
+	if _, err := q.StartMessageMoveTask(nil, 0); err == nil {
+		t.Error("move task on a queue that is no dead-letter queue started")
+	}
+	if _, err := dlq.StartMessageMoveTask(nil, 501); err == nil {
+		t.Error("move task faster than 500 messages per second started")
+	}
+
+	send(t, q, "a", nil)
+	bodies(t, q, 1)
+	bodies(t, q, 1) // redrives a
+
+	smr, err := dlq.StartMessageMoveTask(nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := bodies(t, q, 1); !reflect.DeepEqual(got, []string{"a"}) {
+		t.Fatalf("source queue got %v after the move", got)
+	}
+
+	send(t, dlq, "b", nil)
+	if _, err = dlq.StartMessageMoveTask(other, 10); err != nil {
+		t.Fatal(err)
+	}
+	if got := bodies(t, other, 1); !reflect.DeepEqual(got, []string{"b"}) {
+		t.Errorf("destination got %v", got)
+	}
+
+	send(t, dlq, "c", nil)
+	if _, err = dlq.StartMessageMoveTask(nil, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	lmr, err := dlq.ListMessageMoveTasks(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lmr.Tasks) != 3 {
+		t.Fatalf("%d tasks listed, want 3", len(lmr.Tasks))
+	}
+
+	newest, middle, oldest := lmr.Tasks[0], lmr.Tasks[1], lmr.Tasks[2]
+	if newest.Status != sqs.MoveTaskFailed || newest.ApproximateNumberOfMessagesMoved != 0 {
+		t.Errorf("task without an origin = %+v, want it failed", newest)
+	}
+	if middle.Status != sqs.MoveTaskCompleted || middle.DestinationArn != QueueARN("audit") || middle.MaxNumberOfMessagesPerSecond != 10 {
+		t.Errorf("task to audit = %+v", middle)
+	}
+	if oldest.TaskHandle != smr.TaskHandle || oldest.ApproximateNumberOfMessagesMoved != 1 {
+		t.Errorf("first task = %+v", oldest)
+	}
+
+	if _, err = dlq.CancelMessageMoveTask(smr.TaskHandle); err == nil {
+		t.Error("cancelling a completed task succeeded")
+	}
+}
+
+func TestListQueuesPage(t *testing.T) {
+	f := New()
+	for _, name := range []string{"a", "b", "c", "other"} {
+		newQueue(t, f, name, nil)
+	}
+	q := f.Queue("a")
+
+	var urls []string
+	token := ""
+	for {
+		page, err := q.ListQueuesPage("", token, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		urls = append(urls, page.QueueURLs...)
+		if token = page.NextToken; token == "" {
+			break
+		}
+	}
+
+	want := []string{QueueURL("a"), QueueURL("b"), QueueURL("c"), QueueURL("other")}
+	if !reflect.DeepEqual(urls, want) {
+		t.Errorf("pages = %v, want %v", urls, want)
+	}
+
+	if _, err := q.QueueURLForOwner("111111111111"); err == nil {
+		t.Error("QueueURLForOwner found a queue in another account")
+	}
+}
+
+func TestPermissions(t *testing.T) {
+	f := New()
+	q := newQueue(t, f, "orders", nil)
+
+	if _, err := q.AddPermission("producers", []string{"111111111111"}, []string{"SendMessage"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.AddPermission("producers", []string{"222222222222"}, []string{"SendMessage"}); err == nil {
+		t.Error("AddPermission with a duplicate label succeeded")
+	}
+
+	attrs, err := q.GetQueueAttributes(sqs.AttrPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	policy := attrs.Attributes.Policy
+	if !strings.Contains(policy, `"Sid":"producers"`) || !strings.Contains(policy, "arn:aws:iam::111111111111:root") || !strings.Contains(policy, "SQS:SendMessage") {
+		t.Fatalf("Policy after AddPermission = %s", policy)
+	}
+
+	if _, err = q.RemovePermission("producers"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = q.RemovePermission("producers"); err == nil {
+		t.Error("RemovePermission of a missing label succeeded")
+	}
+
+	if attrs, err = q.GetQueueAttributes(sqs.AttrAll); err != nil {
+		t.Fatal(err)
+	}
+	if attrs.Attributes.Policy != "" {
+		t.Errorf("Policy = %q after RemovePermission", attrs.Attributes.Policy)
+	}
+}
+
+func TestMessageAttributeLimit(t *testing.T) {
+	q := newQueue(t, New(), "orders", nil)
+
+	attrs := make(map[string]string)
+	for i := 0; i < 11; i++ {
+		attrs[fmt.Sprint("a", i)] = "x"
+		_, err := q.SendSQSMessageWithOptions([]byte("hello"), &sqs.SendOptions{MessageAttributes: attrs})
+		if fail := len(attrs) > 10; fail != (err != nil) {
+			t.Errorf("sending %d attributes: %v", len(attrs), err)
+		}
+	}
+}
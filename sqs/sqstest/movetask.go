@@ -0,0 +1,192 @@
+package sqstest
+
+import (
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+// sourceQueues returns the sorted names of the queues whose redrive policy
+// targets the named queue.
+func (f *Fake) sourceQueues(name string) []string {
+	var names []string
+	for _, source := range f.queueNames("") {
+		if rp := f.queues[source].redrivePolicy(); rp != nil && rp.DeadLetterTargetArn == QueueARN(name) {
+			names = append(names, source)
+		}
+	}
+
+	return names
+}
+
+func (q *Queue) ListDeadLetterSourceQueues() (*sqs.DeadLetterSourceQueuesResponse, error) {
+	return q.ListDeadLetterSourceQueuesPage("", 0)
+}
+
+// ListDeadLetterSourceQueuesPage lists the URLs of the queues whose
+// redrive policy targets this queue, paginated as ListQueuesPage.
+func (q *Queue) ListDeadLetterSourceQueuesPage(nextToken string, maxResults int) (*sqs.DeadLetterSourceQueuesResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	if _, err := q.fake.queue(q.name); err != nil {
+		return nil, err
+	}
+
+	names := q.fake.sourceQueues(q.name)
+	token := ""
+	if maxResults != 0 {
+		var err error
+		if names, token, err = page(names, nextToken, maxResults); err != nil {
+			return nil, err
+		}
+	} else if len(names) > 1000 {
+		names = names[:1000]
+	}
+
+	dsr := &sqs.DeadLetterSourceQueuesResponse{NextToken: token, BasicResponse: basicResponse()}
+	for _, name := range names {
+		dsr.QueueURLs = append(dsr.QueueURLs, QueueURL(name))
+	}
+
+	return dsr, nil
+}
+
+// StartMessageMoveTask moves the messages of this dead-letter queue back
+// to the queues they came from, or all of them to dest if it is not nil.
+// Unlike in SQS, the task runs to completion before the call returns and
+// maxPerSecond is only recorded. In-flight messages are left alone; if the
+// origin of some of the others is unknown the task fails, having moved the
+// rest.
+func (q *Queue) StartMessageMoveTask(dest sqs.SQSClient, maxPerSecond int) (*sqs.StartMessageMoveTaskResponse, error) {
+	if maxPerSecond < 0 || maxPerSecond > 500 {
+		return nil, invalidParameter("Value %d for parameter MaxNumberOfMessagesPerSecond is invalid. Reason: Must be between 1 and 500.", maxPerSecond)
+	}
+
+	if d, ok := dest.(*sqs.SQSRequest); ok && d == nil {
+		dest = nil
+	}
+
+	var destArn string
+	if dest != nil {
+		qar, err := dest.GetQueueAttributes(sqs.AttrQueueArn)
+		if err != nil {
+			return nil, err
+		}
+		destArn = qar.Attributes.QueueArn
+	}
+
+	f := q.fake
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fq, err := f.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.sourceQueues(q.name)) == 0 {
+		return nil, invalidParameter("Source queue must be configured as a Dead Letter Queue.")
+	}
+
+	var target *queue
+	if destArn != "" {
+		if target = f.queueByARN(destArn); target == nil {
+			return nil, senderError("ResourceNotFoundException", "The resource that you specified for the DestinationArn parameter doesn't exist.")
+		}
+	}
+
+	now := f.now()
+	task := &sqs.MessageMoveTask{
+		TaskHandle:                   newId(),
+		Status:                       sqs.MoveTaskCompleted,
+		SourceArn:                    QueueARN(q.name),
+		DestinationArn:               destArn,
+		MaxNumberOfMessagesPerSecond: maxPerSecond,
+		StartedTimestamp:             now.UnixNano() / int64(time.Millisecond),
+	}
+
+	fq.expire(now)
+	kept := fq.messages[:0]
+	for _, m := range fq.messages {
+		if m.inFlight(now) {
+			kept = append(kept, m)
+			continue
+		}
+		task.ApproximateNumberOfMessagesToMove++
+
+		to := target
+		if to == nil {
+			to = f.queues[m.source]
+		}
+		if to == nil {
+			kept = append(kept, m)
+			continue
+		}
+
+		m.receives = 0
+		m.firstReceive = time.Time{}
+		m.handle = ""
+		m.source = ""
+		m.visibleAt = now
+		to.messages = append(to.messages, m)
+		task.ApproximateNumberOfMessagesMoved++
+	}
+	fq.messages = kept
+	f.notify()
+
+	if task.ApproximateNumberOfMessagesMoved < task.ApproximateNumberOfMessagesToMove {
+		task.Status = sqs.MoveTaskFailed
+		task.FailureReason = "The original source of some messages could not be found."
+	}
+	fq.tasks = append(fq.tasks, task)
+
+	return &sqs.StartMessageMoveTaskResponse{TaskHandle: task.TaskHandle, BasicResponse: basicResponse()}, nil
+}
+
+// CancelMessageMoveTask cancels a running task. Since the fake's tasks
+// complete when they start, it only ever reports that there is no such
+// task.
+func (q *Queue) CancelMessageMoveTask(taskHandle string) (*sqs.CancelMessageMoveTaskResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	for _, fq := range q.fake.queues {
+		for _, task := range fq.tasks {
+			if task.TaskHandle == taskHandle && task.Status == sqs.MoveTaskRunning {
+				task.Status = sqs.MoveTaskCancelled
+				return &sqs.CancelMessageMoveTaskResponse{
+					ApproximateNumberOfMessagesMoved: task.ApproximateNumberOfMessagesMoved,
+					BasicResponse:                    basicResponse(),
+				}, nil
+			}
+		}
+	}
+
+	return nil, senderError("ResourceNotFoundException", "Task does not exist or is not running.")
+}
+
+// ListMessageMoveTasks returns up to maxResults of the queue's move tasks,
+// newest first. As in SQS, maxResults defaults to 1.
+func (q *Queue) ListMessageMoveTasks(maxResults int) (*sqs.ListMessageMoveTasksResponse, error) {
+	if maxResults == 0 {
+		maxResults = 1
+	}
+	if maxResults < 1 || maxResults > 10 {
+		return nil, invalidParameter("Value %d for parameter MaxResults is invalid. Reason: Must be between 1 and 10.", maxResults)
+	}
+
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	lmr := &sqs.ListMessageMoveTasksResponse{BasicResponse: basicResponse()}
+	for i := len(fq.tasks) - 1; i >= 0 && len(lmr.Tasks) < maxResults; i-- {
+		lmr.Tasks = append(lmr.Tasks, *fq.tasks[i])
+	}
+
+	return lmr, nil
+}
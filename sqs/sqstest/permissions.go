@@ -0,0 +1,123 @@
+package sqstest
+
+import (
+	"encoding/json"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+// policy is the access policy of a queue, as kept in its Policy
+// attribute. Statements are kept raw so that those set through
+// SetQueueAttributes survive AddPermission and RemovePermission.
+type policy struct {
+	Version   string
+	Id        string `json:",omitempty"`
+	Statement []json.RawMessage
+}
+
+type policyStatement struct {
+	Sid       string
+	Effect    string
+	Principal map[string][]string
+	Action    []string
+	Resource  string
+}
+
+func (q *queue) policy() *policy {
+	p := &policy{Version: "2012-10-17", Id: QueueARN(q.name) + "/SQSDefaultPolicy"}
+	if raw := q.attrs[sqs.AttrPolicy]; raw != "" {
+		json.Unmarshal([]byte(raw), p)
+	}
+
+	return p
+}
+
+func (q *queue) setPolicy(p *policy) {
+	if len(p.Statement) == 0 {
+		delete(q.attrs, sqs.AttrPolicy)
+		return
+	}
+
+	b, _ := json.Marshal(p)
+	q.attrs[sqs.AttrPolicy] = string(b)
+}
+
+// statement returns the index of the statement labelled sid, or -1.
+func (p *policy) statement(sid string) int {
+	for i, raw := range p.Statement {
+		var s struct{ Sid string }
+		if json.Unmarshal(raw, &s) == nil && s.Sid == sid {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// AddPermission adds a statement to the queue's Policy attribute, as SQS
+// does. The fake does not enforce it.
+func (q *Queue) AddPermission(label string, accountIds, actions []string) (*sqs.BasicResponse, error) {
+	if len(accountIds) == 0 {
+		return nil, senderError("MissingParameter", "The request must contain the parameter AWSAccountIds.")
+	}
+	if len(actions) == 0 {
+		return nil, senderError("MissingParameter", "The request must contain the parameter Actions.")
+	}
+
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	p := fq.policy()
+	if p.statement(label) >= 0 {
+		return nil, invalidParameter("Value %s for parameter Label is invalid. Reason: Already exists.", label)
+	}
+
+	s := policyStatement{
+		Sid:       label,
+		Effect:    "Allow",
+		Principal: map[string][]string{"AWS": nil},
+		Resource:  QueueARN(q.name),
+	}
+	for _, id := range accountIds {
+		s.Principal["AWS"] = append(s.Principal["AWS"], "arn:aws:iam::"+id+":root")
+	}
+	for _, action := range actions {
+		s.Action = append(s.Action, "SQS:"+action)
+	}
+
+	raw, _ := json.Marshal(s)
+	p.Statement = append(p.Statement, raw)
+	fq.setPolicy(p)
+	fq.modified = q.fake.now()
+
+	br := basicResponse()
+	return &br, nil
+}
+
+func (q *Queue) RemovePermission(label string) (*sqs.BasicResponse, error) {
+	q.fake.mu.Lock()
+	defer q.fake.mu.Unlock()
+
+	fq, err := q.fake.queue(q.name)
+	if err != nil {
+		return nil, err
+	}
+
+	p := fq.policy()
+	i := p.statement(label)
+	if i < 0 {
+		return nil, invalidParameter("Value %s for parameter Label is invalid. Reason: can't find label.", label)
+	}
+
+	p.Statement = append(p.Statement[:i], p.Statement[i+1:]...)
+	fq.setPolicy(p)
+	fq.modified = q.fake.now()
+
+	br := basicResponse()
+	return &br, nil
+}
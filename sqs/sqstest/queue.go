@@ -0,0 +1,351 @@
+package sqstest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+type queue struct {
+	name     string
+	attrs    map[string]string
+	tags     map[string]string
+	created  time.Time
+	modified time.Time
+
+	// messages are kept in the order they were sent.
+	messages []*message
+
+	// dedup maps the deduplication ids of messages sent to a FIFO queue to
+	// their message ids, for the deduplication interval.
+	dedup    map[string]dedupEntry
+	sequence int64
+
+	// tasks are the message move tasks started on the queue, oldest
+	// first.
+	tasks []*sqs.MessageMoveTask
+}
+
+type dedupEntry struct {
+	messageId string
+	at        time.Time
+}
+
+type message struct {
+	id           string
+	body         string
+	attrs        map[string]string
+	group        string
+	dedupId      string
+	sequence     int64
+	sent         time.Time
+	visibleAt    time.Time
+	firstReceive time.Time
+	receives     int
+	handle       string
+
+	// source is the name of the queue that dead-lettered the message.
+	source string
+}
+
+// inFlight reports whether the message was received and is still hidden.
+func (m *message) inFlight(now time.Time) bool {
+	return m.receives > 0 && m.visibleAt.After(now)
+}
+
+func (q *queue) intAttr(name string, def int) int {
+	if n, err := strconv.Atoi(q.attrs[name]); err == nil {
+		return n
+	}
+
+	return def
+}
+
+func (q *queue) boolAttr(name string) bool {
+	b, _ := strconv.ParseBool(q.attrs[name])
+	return b
+}
+
+func (q *queue) fifo() bool {
+	return q.boolAttr(sqs.AttrFifoQueue)
+}
+
+// expire drops the messages older than the retention period.
+func (q *queue) expire(now time.Time) {
+	retention := time.Duration(q.intAttr(sqs.AttrMessageRetentionPeriod, defaultRetentionPeriod)) * time.Second
+
+	kept := q.messages[:0]
+	for _, m := range q.messages {
+		if now.Sub(m.sent) < retention {
+			kept = append(kept, m)
+		}
+	}
+	q.messages = kept
+}
+
+func (q *queue) send(now time.Time, body string, opts *sqs.SendOptions) (*sqs.SendMessageResponse, error) {
+	if opts == nil {
+		opts = &sqs.SendOptions{}
+	}
+
+	if body == "" {
+		return nil, senderError("MissingParameter", "The request must contain the parameter MessageBody.")
+	}
+	if max := q.intAttr(sqs.AttrMaximumMessageSize, defaultMaximumSize); len(body) > max {
+		return nil, invalidParameter("One or more parameters are invalid. Reason: Message must be shorter than %d bytes.", max)
+	}
+	if opts.DelaySeconds < 0 || opts.DelaySeconds > 900 {
+		return nil, invalidParameter("Value %d for parameter DelaySeconds is invalid. Reason: must be between 0 and 900, if provided.", opts.DelaySeconds)
+	}
+	if n := len(opts.MessageAttributes); n > maxMessageAttributes {
+		return nil, invalidParameter("Number of message attributes [%d] exceeds the allowed maximum [%d].", n, maxMessageAttributes)
+	}
+
+	delay := q.intAttr(sqs.AttrDelaySeconds, 0)
+	m := &message{
+		id:    newId(),
+		body:  body,
+		attrs: make(map[string]string, len(opts.MessageAttributes)),
+		sent:  now,
+	}
+	for name, value := range opts.MessageAttributes {
+		m.attrs[name] = value
+	}
+
+	if q.fifo() {
+		if opts.DelaySeconds > 0 {
+			return nil, invalidParameter("Value %d for parameter DelaySeconds is invalid. Reason: The request include parameter that is not valid for this queue type.", opts.DelaySeconds)
+		}
+		if opts.MessageGroupId == "" {
+			return nil, senderError("MissingParameter", "The request must contain the parameter MessageGroupId.")
+		}
+
+		dedupId := opts.MessageDeduplicationId
+		if dedupId == "" {
+			if !q.boolAttr(sqs.AttrContentBasedDeduplication) {
+				return nil, invalidParameter("The queue should either have ContentBasedDeduplication enabled or MessageDeduplicationId provided explicitly")
+			}
+
+			sum := sha256.Sum256([]byte(body))
+			dedupId = hex.EncodeToString(sum[:])
+		}
+
+		q.sequence++
+		m.group = opts.MessageGroupId
+		m.dedupId = dedupId
+		m.sequence = q.sequence
+
+		if e, ok := q.dedup[dedupId]; ok && now.Sub(e.at) < fifoDedupInterval {
+			return &sqs.SendMessageResponse{MessageId: e.messageId, MessageMD5: md5Hex(body), BasicResponse: basicResponse()}, nil
+		}
+		q.dedup[dedupId] = dedupEntry{m.id, now}
+	} else {
+		if opts.MessageDeduplicationId != "" {
+			return nil, invalidParameter("The request include parameter MessageDeduplicationId that is not valid for this queue type")
+		}
+		if opts.DelaySeconds > 0 {
+			delay = opts.DelaySeconds
+		}
+	}
+
+	m.visibleAt = now.Add(time.Duration(delay) * time.Second)
+	q.messages = append(q.messages, m)
+
+	return &sqs.SendMessageResponse{MessageId: m.id, MessageMD5: md5Hex(body), BasicResponse: basicResponse()}, nil
+}
+
+// receive hides and returns up to max visible messages for visibility
+// seconds. Messages received more often than the redrive policy allows are
+// moved to the dead-letter queue instead, if it exists. It also returns
+// the time at which the next hidden message becomes visible, if any.
+func (q *queue) receive(f *Fake, now time.Time, max, visibility int) ([]*sqs.RecvMessageResponse, time.Time) {
+	q.expire(now)
+
+	redrive := q.redrivePolicy()
+
+	var (
+		msgs    []*sqs.RecvMessageResponse
+		next    time.Time
+		blocked = make(map[string]bool)
+		kept    = q.messages[:0]
+	)
+	for _, m := range q.messages {
+		// On FIFO queues, a message hidden for whatever reason holds back
+		// the rest of its group.
+		visible := !m.visibleAt.After(now) && !(q.fifo() && blocked[m.group])
+		if m.visibleAt.After(now) && (next.IsZero() || m.visibleAt.Before(next)) {
+			next = m.visibleAt
+		}
+		if !visible || len(msgs) == max {
+			if q.fifo() {
+				blocked[m.group] = true
+			}
+			kept = append(kept, m)
+			continue
+		}
+
+		if redrive != nil && m.receives >= redrive.MaxReceiveCount {
+			if dlq := f.queueByARN(redrive.DeadLetterTargetArn); dlq != nil && dlq != q {
+				m.visibleAt = now
+				m.handle = ""
+				m.source = q.name
+				dlq.messages = append(dlq.messages, m)
+				continue
+			}
+		}
+
+		m.receives++
+		if m.firstReceive.IsZero() {
+			m.firstReceive = now
+		}
+		m.handle = newReceiptHandle()
+		m.visibleAt = now.Add(time.Duration(visibility) * time.Second)
+
+		msgs = append(msgs, m.response(now))
+		kept = append(kept, m)
+	}
+	q.messages = kept
+
+	return msgs, next
+}
+
+// redrivePolicy returns the queue's redrive policy, or nil if it has
+// none.
+func (q *queue) redrivePolicy() *sqs.RedrivePolicy {
+	raw := q.attrs[sqs.AttrRedrivePolicy]
+	if raw == "" {
+		return nil
+	}
+
+	rp := new(sqs.RedrivePolicy)
+	if json.Unmarshal([]byte(raw), rp) != nil {
+		return nil
+	}
+
+	return rp
+}
+
+func (m *message) response(now time.Time) *sqs.RecvMessageResponse {
+	ms := func(t time.Time) string {
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	}
+
+	names := make([]string, 0, len(m.attrs))
+	for name := range m.attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	attrs := make([]sqs.MessageAttribute, len(names))
+	for i, name := range names {
+		attrs[i] = sqs.MessageAttribute{Name: name, DataType: "String", StringValue: m.attrs[name]}
+	}
+
+	system := []sqs.Attribute{
+		{Name: "ApproximateReceiveCount", Value: strconv.Itoa(m.receives)},
+		{Name: "ApproximateFirstReceiveTimestamp", Value: ms(m.firstReceive)},
+		{Name: "SentTimestamp", Value: ms(m.sent)},
+		{Name: "SenderId", Value: AccountId},
+	}
+	if m.group != "" {
+		system = append(system,
+			sqs.Attribute{Name: "MessageGroupId", Value: m.group},
+			sqs.Attribute{Name: "MessageDeduplicationId", Value: m.dedupId},
+			sqs.Attribute{Name: "SequenceNumber", Value: strconv.FormatInt(m.sequence, 10)},
+		)
+	}
+
+	return &sqs.RecvMessageResponse{
+		MessageId:         m.id,
+		MessageMD5:        md5Hex(m.body),
+		MessageBody:       m.body,
+		ReceiptHandle:     m.handle,
+		Attributes:        system,
+		MessageAttributes: attrs,
+		BasicResponse:     basicResponse(),
+	}
+}
+
+// find returns the index of the message with the given receipt handle.
+func (q *queue) find(handle string) (int, error) {
+	if handle != "" {
+		for i, m := range q.messages {
+			if m.handle == handle {
+				return i, nil
+			}
+		}
+	}
+
+	return -1, senderError("ReceiptHandleIsInvalid", "The input receipt handle %q is not a valid receipt handle.", handle)
+}
+
+func (q *queue) delete(handle string) error {
+	i, err := q.find(handle)
+	if err != nil {
+		return err
+	}
+
+	q.messages = append(q.messages[:i], q.messages[i+1:]...)
+	return nil
+}
+
+func (q *queue) changeVisibility(now time.Time, handle string, timeout int) error {
+	if timeout < 0 || timeout > 43200 {
+		return invalidParameter("Value %d for parameter VisibilityTimeout is invalid. Reason: Must be between 0 and 43200.", timeout)
+	}
+
+	i, err := q.find(handle)
+	if err != nil {
+		return err
+	}
+
+	m := q.messages[i]
+	if !m.inFlight(now) {
+		return senderError("AWS.SimpleQueueService.MessageNotInflight", "Message does not exist or is not available for visibility timeout change.")
+	}
+
+	m.visibleAt = now.Add(time.Duration(timeout) * time.Second)
+	return nil
+}
+
+// attributes returns the raw values of all of the queue's attributes.
+func (q *queue) attributes(now time.Time) map[string]string {
+	q.expire(now)
+
+	raw := map[string]string{
+		sqs.AttrDelaySeconds:                  "0",
+		sqs.AttrMaximumMessageSize:            strconv.Itoa(defaultMaximumSize),
+		sqs.AttrMessageRetentionPeriod:        strconv.Itoa(defaultRetentionPeriod),
+		sqs.AttrReceiveMessageWaitTimeSeconds: "0",
+		sqs.AttrVisibilityTimeout:             strconv.Itoa(defaultVisibilityTimeout),
+	}
+	for name, value := range q.attrs {
+		raw[name] = value
+	}
+
+	var visible, inFlight, delayed int
+	for _, m := range q.messages {
+		switch {
+		case !m.visibleAt.After(now):
+			visible++
+		case m.receives > 0:
+			inFlight++
+		default:
+			delayed++
+		}
+	}
+
+	raw[sqs.AttrApproximateNumberOfMessages] = strconv.Itoa(visible)
+	raw[sqs.AttrApproximateNumberOfMessagesNotVisible] = strconv.Itoa(inFlight)
+	raw[sqs.AttrApproximateNumberOfMessagesDelayed] = strconv.Itoa(delayed)
+	raw[sqs.AttrCreatedTimestamp] = strconv.FormatInt(q.created.Unix(), 10)
+	raw[sqs.AttrLastModifiedTimestamp] = strconv.FormatInt(q.modified.Unix(), 10)
+	raw[sqs.AttrQueueArn] = QueueARN(q.name)
+
+	return raw
+}
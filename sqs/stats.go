@@ -0,0 +1,38 @@
+package sqs
+
+import (
+	"time"
+)
+
+// QueueStats is a snapshot of a queue's approximate depth.
+type QueueStats struct {
+	Time     time.Time
+	Visible  int
+	InFlight int
+	Delayed  int
+}
+
+// Total returns the number of messages on the queue in any state.
+func (qs *QueueStats) Total() int {
+	return qs.Visible + qs.InFlight + qs.Delayed
+}
+
+// Stats returns the queue's current message counts. SQS computes them
+// approximately and they may lag behind by a minute or so.
+func (s *SQSRequest) Stats() (*QueueStats, error) {
+	qar, err := s.GetQueueAttributes(
+		AttrApproximateNumberOfMessages,
+		AttrApproximateNumberOfMessagesNotVisible,
+		AttrApproximateNumberOfMessagesDelayed,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueueStats{
+		Time:     time.Now(),
+		Visible:  qar.Attributes.ApproximateNumberOfMessages,
+		InFlight: qar.Attributes.ApproximateNumberOfMessagesNotVisible,
+		Delayed:  qar.Attributes.ApproximateNumberOfMessagesDelayed,
+	}, nil
+}
@@ -0,0 +1,93 @@
+package sqs
+
+import (
+	"context"
+	"time"
+)
+
+// Receive long-polls the queue in the background and delivers messages on
+// the returned channel until ctx is cancelled, at which point both
+// channels are closed. Each poll waits up to WaitSeconds, or 20 seconds
+// if that is zero, and polls after consecutive empty receives are delayed
+// by IdleBackoff, if set. Polling pauses while the message channel is
+// full, so a slow reader never holds more than one batch beyond the
+// channel's capacity. On cancellation, messages received but not yet
+// read, whether still buffered in the channel or not yet sent on it, are
+// released back to the queue.
+//
+// Receive errors are sent on the error channel and polling resumes after a
+// short pause. Errors are dropped if nobody is reading them.
+func (s *SQSRequest) Receive(ctx context.Context) (<-chan *RecvMessageResponse, <-chan error) {
+	msgc := make(chan *RecvMessageResponse, maxBatchEntries)
+	errc := make(chan error, 1)
+
+	wait := s.WaitSeconds
+	if wait == 0 {
+		wait = 20
+	}
+
+	// release takes back the messages the reader has not read yet, and
+	// returns them to the queue along with unsent.
+	release := func(unsent []*RecvMessageResponse) {
+		for len(msgc) > 0 {
+			select {
+			case m := <-msgc:
+				unsent = append(unsent, m)
+			default:
+			}
+		}
+		if len(unsent) == 0 {
+			return
+		}
+
+		rctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		s.ReleaseAll(rctx, unsent)
+	}
+
+	go func() {
+		defer close(msgc)
+		defer close(errc)
+
+		empty := 0
+		for ctx.Err() == nil {
+			msgs, err := s.ReceiveSQSMessagesContext(ctx, maxBatchEntries, wait)
+			if err != nil {
+				if ctx.Err() != nil {
+					break
+				}
+
+				select {
+				case errc <- err:
+				default:
+				}
+
+				sleep(ctx, time.Second)
+				continue
+			}
+
+			if len(msgs) == 0 {
+				empty++
+				if s.IdleBackoff != nil {
+					sleep(ctx, s.IdleBackoff.Delay(empty))
+				}
+				continue
+			}
+			empty = 0
+
+			for i, m := range msgs {
+				select {
+				case msgc <- m:
+				case <-ctx.Done():
+					release(msgs[i:])
+					return
+				}
+			}
+		}
+
+		release(nil)
+	}()
+
+	return msgc, errc
+}
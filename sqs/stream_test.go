@@ -0,0 +1,142 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// streamServer hands out n messages on the first JSON protocol receive and
+// none after, recording the wait of each receive and the handles released.
+type streamServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	waits    []float64
+	released []string
+}
+
+func newStreamServer(t *testing.T, n int) *streamServer {
+	ss := &streamServer{}
+	ss.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			WaitTimeSeconds float64
+			Entries         []struct{ Id, ReceiptHandle string }
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+
+		ss.mu.Lock()
+		defer ss.mu.Unlock()
+
+		switch action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), jsonTargetPrefix); action {
+		case "ReceiveMessage":
+			var msgs []map[string]string
+			if len(ss.waits) == 0 {
+				for i := 1; i <= n; i++ {
+					msgs = append(msgs, map[string]string{"MessageId": fmt.Sprint("m", i), "ReceiptHandle": fmt.Sprint("h", i), "Body": "x"})
+				}
+			}
+			ss.waits = append(ss.waits, req.WaitTimeSeconds)
+			json.NewEncoder(w).Encode(map[string]interface{}{"Messages": msgs})
+		case "ChangeMessageVisibilityBatch":
+			var ok []map[string]string
+			for _, e := range req.Entries {
+				ss.released = append(ss.released, e.ReceiptHandle)
+				ok = append(ok, map[string]string{"Id": e.Id})
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"Successful": ok})
+		default:
+			t.Errorf("unexpected %s request", action)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(ss.Close)
+
+	return ss
+}
+
+func (ss *streamServer) state() ([]float64, []string) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	return append([]float64(nil), ss.waits...), append([]string(nil), ss.released...)
+}
+
+// waitFor polls cond until it holds, failing the test after a while.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+func TestReceiveReleasesUnreadOnCancel(t *testing.T) {
+	tests := []struct {
+		waitSeconds int
+		wantWait    float64
+	}{
+		{0, 20},
+		{3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("WaitSeconds=%d", tt.waitSeconds), func(t *testing.T) {
+			ss := newStreamServer(t, 3)
+			s := &SQSRequest{
+				RegionId:      "us-east-1",
+				UUID:          "123456789012",
+				QueueName:     "orders",
+				AWSAccessKey:  "key",
+				AWSSecret:     "secret",
+				Endpoint:      ss.URL,
+				SkipChecksums: true,
+				WaitSeconds:   tt.waitSeconds,
+				IdleBackoff:   &Backoff{Initial: time.Hour},
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			msgc, _ := s.Receive(ctx)
+
+			if m := <-msgc; m.ReceiptHandle != "h1" {
+				t.Fatalf("first message = %+v", m)
+			}
+
+			// The second receive is empty, and the backoff holds off a
+			// third.
+			waitFor(t, "the second receive", func() bool {
+				waits, _ := ss.state()
+				return len(waits) == 2
+			})
+
+			cancel()
+			waitFor(t, "messages to be released", func() bool {
+				_, released := ss.state()
+				return len(released) > 0
+			})
+			for m := range msgc {
+				t.Errorf("read %s after it was released", m.ReceiptHandle)
+			}
+
+			waits, released := ss.state()
+			if !reflect.DeepEqual(waits, []float64{tt.wantWait, tt.wantWait}) {
+				t.Errorf("receive waits = %v, want two of %v", waits, tt.wantWait)
+			}
+			if !reflect.DeepEqual(released, []string{"h2", "h3"}) {
+				t.Errorf("released %v, want the unread [h2 h3]", released)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package sqs
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+type QueueTagsResponse struct {
+	Tags map[string]string
+	BasicResponse
+}
+
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type listQueueTagsResponse struct {
+	Tags []tag `xml:"ListQueueTagsResult>Tag"`
+	BasicResponse
+}
+
+func setTagParams(params map[string]string, tags map[string]string) {
+	count := 1
+	for key, value := range tags {
+		params[fmt.Sprintf("Tag.%d.Key", count)] = key
+		params[fmt.Sprintf("Tag.%d.Value", count)] = value
+		count++
+	}
+}
+
+// TagQueue adds the given tags to the queue, overwriting the values of
+// tags that already exist.
+func (s *SQSRequest) TagQueue(tags map[string]string) (*BasicResponse, error) {
+	params := map[string]string{
+		"Action": "TagQueue",
+	}
+	setTagParams(params, tags)
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	bmr := new(BasicResponse)
+	if err = xml.NewDecoder(reader).Decode(bmr); err != nil {
+		return nil, err
+	}
+
+	return bmr, nil
+}
+
+func (s *SQSRequest) UntagQueue(keys ...string) (*BasicResponse, error) {
+	params := map[string]string{
+		"Action": "UntagQueue",
+	}
+
+	for i, key := range keys {
+		params[fmt.Sprintf("TagKey.%d", i+1)] = key
+	}
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	bmr := new(BasicResponse)
+	if err = xml.NewDecoder(reader).Decode(bmr); err != nil {
+		return nil, err
+	}
+
+	return bmr, nil
+}
+
+func (s *SQSRequest) ListQueueTags() (*QueueTagsResponse, error) {
+	params := map[string]string{
+		"Action": "ListQueueTags",
+	}
+
+	reader, err := s.makeSQSQueueRequest(params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	ltr := new(listQueueTagsResponse)
+	if err = xml.NewDecoder(reader).Decode(ltr); err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(ltr.Tags))
+	for _, t := range ltr.Tags {
+		tags[t.Key] = t.Value
+	}
+
+	return &QueueTagsResponse{tags, ltr.BasicResponse}, nil
+}
@@ -0,0 +1,202 @@
+package sqs
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+)
+
+// TenantPlaceholder marks where the tenant id goes in a TenantQueues
+// naming template.
+const TenantPlaceholder = "{tenant}"
+
+// tenantMarker stands in for the tenant id when working out how the
+// client's QueueNaming transforms a template.
+const tenantMarker = "tenant0marker"
+
+// TenantQueues creates and resolves one queue per tenant on demand. Queue
+// names come from Template, with TenantPlaceholder replaced by the tenant
+// id and the client's QueueNaming, if any, applied on top.
+type TenantQueues struct {
+	// Base supplies the region, account and credentials of every tenant
+	// queue. Its QueueName is ignored.
+	Base     *SQSRequest
+	Template string
+
+	// Options are applied to every tenant queue that gets created.
+	Options *CreateQueueOptions
+
+	// DeadLetterTemplate, if set, names a dead-letter queue created
+	// alongside each tenant queue; messages move there after
+	// MaxReceiveCount receives.
+	DeadLetterTemplate string
+	MaxReceiveCount    int
+
+	mu      sync.Mutex
+	queues  map[string]*SQSRequest
+	pending map[string]*tenantLookup
+}
+
+// tenantLookup is a resolution of a tenant queue in progress, which other
+// callers asking for the same tenant wait for.
+type tenantLookup struct {
+	done chan struct{}
+	q    *SQSRequest
+	err  error
+}
+
+func NewTenantQueues(base *SQSRequest, template string) *TenantQueues {
+	return &TenantQueues{
+		Base:     base,
+		Template: template,
+	}
+}
+
+func (tq *TenantQueues) target(template, tenant string) *SQSRequest {
+	q := *tq.Base
+	q.QueueName = strings.Replace(template, TenantPlaceholder, tenant, -1)
+	q.ExplicitQueueURL = ""
+
+	return &q
+}
+
+// Queue returns the queue of the given tenant, creating it (and its
+// dead-letter queue) if it does not exist yet. Resolved queues are cached.
+// Concurrent calls for one tenant share a lookup, and do not hold up
+// calls for other tenants.
+func (tq *TenantQueues) Queue(tenant string) (*SQSRequest, error) {
+	if tenant == "" || strings.IndexFunc(tenant, func(r rune) bool { return !isNameRune(r) }) >= 0 {
+		return nil, fmt.Errorf("Invalid tenant id %q.", tenant)
+	}
+	if !strings.Contains(tq.Template, TenantPlaceholder) {
+		return nil, errors.New("Tenant queue template has no " + TenantPlaceholder + " placeholder.")
+	}
+
+	tq.mu.Lock()
+	if q, ok := tq.queues[tenant]; ok {
+		tq.mu.Unlock()
+		return q, nil
+	}
+	if l, ok := tq.pending[tenant]; ok {
+		tq.mu.Unlock()
+		<-l.done
+		return l.q, l.err
+	}
+
+	l := &tenantLookup{done: make(chan struct{})}
+	if tq.pending == nil {
+		tq.pending = make(map[string]*tenantLookup)
+		tq.queues = make(map[string]*SQSRequest)
+	}
+	tq.pending[tenant] = l
+	tq.mu.Unlock()
+
+	l.q, l.err = tq.resolve(tenant)
+
+	tq.mu.Lock()
+	delete(tq.pending, tenant)
+	if l.err == nil {
+		tq.queues[tenant] = l.q
+	}
+	tq.mu.Unlock()
+	close(l.done)
+
+	return l.q, l.err
+}
+
+// resolve looks up the queue of a tenant, creating it if needed. The
+// redrive policy is only set on queues that have none, so that one
+// changed by hand is left alone.
+func (tq *TenantQueues) resolve(tenant string) (*SQSRequest, error) {
+	q := tq.target(tq.Template, tenant)
+	qur, created, err := q.ensureQueue(tq.Options)
+	if err != nil {
+		return nil, err
+	}
+	q.ExplicitQueueURL = qur.QueueURL
+
+	if tq.DeadLetterTemplate == "" {
+		return q, nil
+	}
+
+	if !created {
+		// A queue created by an earlier call that failed halfway may
+		// still lack its policy.
+		qar, err := q.GetQueueAttributes(AttrRedrivePolicy)
+		if err != nil {
+			return nil, err
+		}
+		if qar.Attributes.RedrivePolicy != nil {
+			return q, nil
+		}
+	}
+
+	dlq := tq.target(tq.DeadLetterTemplate, tenant)
+	if _, err = dlq.EnsureQueue(tq.Options); err != nil {
+		return nil, err
+	}
+	if _, err = q.ConfigureDeadLetterQueue(dlq, tq.MaxReceiveCount); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// affixes returns the parts of a normalized queue name before and after
+// the tenant id.
+func (tq *TenantQueues) affixes(template string) (string, string, error) {
+	fifo := tq.Options != nil && tq.Options.FifoQueue
+
+	q := tq.target(template, tenantMarker)
+	name, err := q.queueName(q.QueueName, fifo)
+	if err != nil {
+		return "", "", err
+	}
+
+	i := strings.Index(name, tenantMarker)
+	if i < 0 {
+		return "", "", errors.New("Tenant queue naming removed the tenant id.")
+	}
+
+	return name[:i], name[i+len(tenantMarker):], nil
+}
+
+// Tenants lists the ids of all tenants that have a queue, by listing the
+// queues whose names match the template.
+func (tq *TenantQueues) Tenants() ([]string, error) {
+	prefix, suffix, err := tq.affixes(tq.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	var dlqPrefix, dlqSuffix string
+	if tq.DeadLetterTemplate != "" {
+		if dlqPrefix, dlqSuffix, err = tq.affixes(tq.DeadLetterTemplate); err != nil {
+			return nil, err
+		}
+	}
+
+	urls, err := tq.Base.ListAllQueues(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenants []string
+	for _, u := range urls {
+		name := path.Base(u)
+		if tq.DeadLetterTemplate != "" && isTenantQueue(name, dlqPrefix, dlqSuffix) {
+			continue
+		}
+		if isTenantQueue(name, prefix, suffix) {
+			tenants = append(tenants, name[len(prefix):len(name)-len(suffix)])
+		}
+	}
+
+	return tenants, nil
+}
+
+func isTenantQueue(name, prefix, suffix string) bool {
+	return len(name) > len(prefix)+len(suffix) && strings.HasPrefix(name, prefix) && strings.HasSuffix(name, suffix)
+}
@@ -0,0 +1,291 @@
+package sqs
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// queueServer serves the queue management actions of the query protocol
+// from a map of queue attributes, by queue name, and records the actions
+// it changes queues with.
+type queueServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	queues  map[string]map[string]string
+	changes []string
+}
+
+func newQueueServer(t *testing.T, names ...string) *queueServer {
+	qs := &queueServer{queues: make(map[string]map[string]string)}
+	for _, name := range names {
+		qs.queues[name] = make(map[string]string)
+	}
+	qs.Server = httptest.NewServer(http.HandlerFunc(qs.serve))
+	t.Cleanup(qs.Close)
+
+	return qs
+}
+
+func (qs *queueServer) serve(w http.ResponseWriter, r *http.Request) {
+	qs.mu.Lock()
+	defer qs.mu.Unlock()
+
+	r.ParseForm()
+	action := r.PostForm.Get("Action")
+	name := r.PostForm.Get("QueueName")
+	if name == "" {
+		name = path.Base(r.URL.Path)
+	}
+	url := qs.URL + "/123456789012/" + name
+
+	switch action {
+	case "GetQueueUrl":
+		if qs.queues[name] == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<ErrorResponse><Error><Type>Sender</Type><Code>AWS.SimpleQueueService.NonExistentQueue</Code><Message>no queue</Message></Error></ErrorResponse>`))
+			return
+		}
+		fmt.Fprintf(w, `<GetQueueUrlResponse><GetQueueUrlResult><QueueUrl>%s</QueueUrl></GetQueueUrlResult></GetQueueUrlResponse>`, url)
+	case "CreateQueue":
+		qs.changes = append(qs.changes, action+" "+name)
+		qs.queues[name] = make(map[string]string)
+		fmt.Fprintf(w, `<CreateQueueResponse><CreateQueueResult><QueueUrl>%s</QueueUrl></CreateQueueResult></CreateQueueResponse>`, url)
+	case "GetQueueAttributes":
+		attrs := qs.queues[name]
+		attrs[AttrQueueArn] = "arn:aws:sqs:us-east-1:123456789012:" + name
+		fmt.Fprint(w, `<GetQueueAttributesResponse><GetQueueAttributesResult>`)
+		for k, v := range attrs {
+			fmt.Fprintf(w, `<Attribute><Name>%s</Name><Value>%s</Value></Attribute>`, k, strings.Replace(v, `"`, "&quot;", -1))
+		}
+		fmt.Fprint(w, `</GetQueueAttributesResult></GetQueueAttributesResponse>`)
+	case "SetQueueAttributes":
+		qs.changes = append(qs.changes, action+" "+name)
+		for i := 1; r.PostForm.Get(fmt.Sprintf("Attribute.%d.Name", i)) != ""; i++ {
+			qs.queues[name][r.PostForm.Get(fmt.Sprintf("Attribute.%d.Name", i))] = r.PostForm.Get(fmt.Sprintf("Attribute.%d.Value", i))
+		}
+		w.Write([]byte(`<SetQueueAttributesResponse></SetQueueAttributesResponse>`))
+	case "ListQueues":
+		var names []string
+		for n := range qs.queues {
+			if strings.HasPrefix(n, r.PostForm.Get("QueueNamePrefix")) {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+		fmt.Fprint(w, `<ListQueuesResponse><ListQueuesResult>`)
+		for _, n := range names {
+			fmt.Fprintf(w, `<QueueUrl>%s/123456789012/%s</QueueUrl>`, qs.URL, n)
+		}
+		fmt.Fprint(w, `</ListQueuesResult></ListQueuesResponse>`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`<ErrorResponse><Error><Code>InvalidAction</Code></Error></ErrorResponse>`))
+	}
+}
+
+func (qs *queueServer) base() *SQSRequest {
+	return &SQSRequest{RegionId: "us-east-1", UUID: "123456789012", Endpoint: qs.URL, Protocol: ProtocolQuery}
+}
+
+func TestTenantQueue(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		template string
+		dlq      string
+		naming   *QueueNaming
+		tenants  []string
+
+		err     bool
+		queue   string // of the last tenant
+		changes []string
+	}{
+		{
+			name:     "created once",
+			template: "orders-{tenant}",
+			tenants:  []string{"acme", "acme", "globex"},
+			queue:    "orders-globex",
+			changes:  []string{"CreateQueue orders-acme", "CreateQueue orders-globex"},
+		},
+		{
+			name:     "existing queue",
+			existing: []string{"orders-acme"},
+			template: "orders-{tenant}",
+			tenants:  []string{"acme"},
+			queue:    "orders-acme",
+		},
+		{
+			name:     "naming convention",
+			template: "orders-{tenant}",
+			naming:   &QueueNaming{Prefix: "prod-"},
+			tenants:  []string{"acme"},
+			queue:    "prod-orders-acme",
+			changes:  []string{"CreateQueue prod-orders-acme"},
+		},
+		{
+			name:     "dead-letter queue",
+			template: "orders-{tenant}",
+			dlq:      "orders-{tenant}-dlq",
+			tenants:  []string{"acme"},
+			queue:    "orders-acme",
+			changes:  []string{"CreateQueue orders-acme", "CreateQueue orders-acme-dlq", "SetQueueAttributes orders-acme"},
+		},
+		{
+			// An earlier call created the queue but failed to set its
+			// redrive policy.
+			name:     "dead-letter queue of an existing queue",
+			existing: []string{"orders-acme"},
+			template: "orders-{tenant}",
+			dlq:      "orders-{tenant}-dlq",
+			tenants:  []string{"acme"},
+			queue:    "orders-acme",
+			changes:  []string{"CreateQueue orders-acme-dlq", "SetQueueAttributes orders-acme"},
+		},
+		{name: "empty tenant", template: "orders-{tenant}", tenants: []string{""}, err: true},
+		{name: "tenant with a slash", template: "orders-{tenant}", tenants: []string{"acme/../x"}, err: true},
+		{name: "tenant with a dot", template: "orders-{tenant}", tenants: []string{"acme.fifo"}, err: true},
+		{name: "template without placeholder", template: "orders", tenants: []string{"acme"}, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs := newQueueServer(t, tt.existing...)
+			base := qs.base()
+			base.Naming = tt.naming
+			tq := NewTenantQueues(base, tt.template)
+			tq.DeadLetterTemplate = tt.dlq
+			tq.MaxReceiveCount = 5
+
+			var q *SQSRequest
+			var err error
+			for _, tenant := range tt.tenants {
+				if q, err = tq.Queue(tenant); err != nil {
+					break
+				}
+			}
+
+			if (err != nil) != tt.err {
+				t.Fatalf("Queue = %v, want error %v", err, tt.err)
+			}
+			if tt.err {
+				if len(qs.changes) > 0 {
+					t.Errorf("changes = %q, want none", qs.changes)
+				}
+				return
+			}
+
+			if q.QueueName != tt.queue || q.ExplicitQueueURL != qs.URL+"/123456789012/"+tt.queue {
+				t.Errorf("queue = %q at %q, want %q", q.QueueName, q.ExplicitQueueURL, tt.queue)
+			}
+			if !reflect.DeepEqual(qs.changes, tt.changes) {
+				t.Errorf("changes = %q, want %q", qs.changes, tt.changes)
+			}
+
+			// The redrive policy, once set, is left alone.
+			if tt.dlq != "" {
+				rp := qs.queues[tt.queue][AttrRedrivePolicy]
+				if !strings.Contains(rp, tt.queue+"-dlq") {
+					t.Errorf("redrive policy = %s", rp)
+				}
+
+				qs.changes = nil
+				again := NewTenantQueues(base, tt.template)
+				again.DeadLetterTemplate = tt.dlq
+				again.MaxReceiveCount = 5
+				if _, err = again.Queue(tt.tenants[0]); err != nil {
+					t.Fatal(err)
+				}
+				if len(qs.changes) > 0 {
+					t.Errorf("resolving again made changes %q", qs.changes)
+				}
+			}
+		})
+	}
+}
+
+func TestTenantQueueShared(t *testing.T) {
+	qs := newQueueServer(t)
+	tq := NewTenantQueues(qs.base(), "orders-{tenant}")
+
+	var wg sync.WaitGroup
+	got := make([]*SQSRequest, 8)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			q, err := tq.Queue("acme")
+			if err != nil {
+				t.Error(err)
+			}
+			got[i] = q
+		}(i)
+	}
+	wg.Wait()
+
+	for _, q := range got {
+		if q != got[0] {
+			t.Fatal("concurrent lookups returned different queues")
+		}
+	}
+	if !reflect.DeepEqual(qs.changes, []string{"CreateQueue orders-acme"}) {
+		t.Errorf("changes = %q, want one CreateQueue", qs.changes)
+	}
+}
+
+func TestTenants(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		dlq      string
+		naming   *QueueNaming
+		want     []string
+	}{
+		{
+			name:     "matching queues",
+			existing: []string{"orders-acme", "orders-globex", "orders-", "invoices-acme"},
+			want:     []string{"acme", "globex"},
+		},
+		{
+			name:     "dead-letter queues left out",
+			existing: []string{"orders-acme", "orders-acme-dlq", "orders-globex"},
+			dlq:      "orders-{tenant}-dlq",
+			want:     []string{"acme", "globex"},
+		},
+		{
+			name:     "naming convention",
+			existing: []string{"prod-orders-acme", "orders-globex"},
+			naming:   &QueueNaming{Prefix: "prod-"},
+			want:     []string{"acme"},
+		},
+		{
+			name:     "none",
+			existing: []string{"invoices-acme"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qs := newQueueServer(t, tt.existing...)
+			base := qs.base()
+			base.Naming = tt.naming
+			tq := NewTenantQueues(base, "orders-{tenant}")
+			tq.DeadLetterTemplate = tt.dlq
+
+			got, err := tq.Tenants()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tenants = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
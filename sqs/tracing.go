@@ -0,0 +1,78 @@
+package sqs
+
+import (
+	"context"
+)
+
+// TraceParentAttribute carries the W3C trace context of the producer of a
+// message, so that its processing can be linked to the producer's trace.
+const TraceParentAttribute = "traceparent"
+
+// Tracer creates spans and carries their context across messages. It is
+// small enough to adapt any tracing library to; with OpenTelemetry, Start
+// wraps trace.Tracer.Start, and Inject and Extract use the TraceContext
+// propagator with a carrier holding the single "traceparent" key.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx, if any, and
+	// returns a context holding the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+
+	// Inject returns the traceparent of the span in ctx, or "" if there
+	// is none.
+	Inject(ctx context.Context) string
+
+	// Extract returns ctx with the remote span described by traceparent
+	// as parent. An empty or invalid traceparent leaves ctx unchanged.
+	Extract(ctx context.Context, traceparent string) context.Context
+}
+
+// Span is a unit of traced work.
+type Span interface {
+	SetAttribute(key, value string)
+	RecordError(err error)
+	End()
+}
+
+// startSpan starts a span for a call to action. Without a Tracer, it
+// returns a nil Span, which endSpan ignores.
+func (s *SQSRequest) startSpan(ctx context.Context, action string) (context.Context, Span) {
+	if s.Tracer == nil {
+		return ctx, nil
+	}
+
+	ctx, span := s.Tracer.Start(ctx, "SQS "+action)
+	span.SetAttribute("rpc.system", "aws-api")
+	span.SetAttribute("rpc.service", "SQS")
+	span.SetAttribute("rpc.method", action)
+	if s.QueueName != "" {
+		span.SetAttribute("messaging.destination.name", s.QueueName)
+	}
+
+	return ctx, span
+}
+
+func endSpan(span Span, err error) {
+	if span == nil {
+		return
+	}
+
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
+
+// injectTrace returns opts with the trace context of ctx added as
+// TraceParentAttribute, unless that is already set. opts is not modified.
+func (s *SQSRequest) injectTrace(ctx context.Context, opts *SendOptions) *SendOptions {
+	if s.Tracer == nil {
+		return opts
+	}
+
+	traceparent := s.Tracer.Inject(ctx)
+	if traceparent == "" || opts != nil && opts.MessageAttributes[TraceParentAttribute] != "" {
+		return opts
+	}
+
+	return opts.withAttribute(TraceParentAttribute, traceparent)
+}
@@ -0,0 +1,135 @@
+package sqs
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxBatchEntries is the largest number of entries SQS accepts in a single
+// batch request.
+const maxBatchEntries = 10
+
+type BatchResultError struct {
+	Id          string `xml:"Id"`
+	Code        string `xml:"Code"`
+	Message     string `xml:"Message"`
+	SenderFault bool   `xml:"SenderFault"`
+}
+
+// BatchError collects the entries of a batch request that SQS rejected.
+type BatchError []BatchResultError
+
+func (be BatchError) Error() string {
+	msgs := make([]string, len(be))
+	for i, e := range be {
+		msgs[i] = fmt.Sprintf("%s: %s (%s)", e.Id, e.Message, e.Code)
+	}
+
+	return fmt.Sprintf("%d batch entries failed: %s", len(be), strings.Join(msgs, "; "))
+}
+
+// ChangeMessageVisibility sets the visibility timeout of an in-flight
+// message to timeout seconds from now. A timeout of 0 makes the message
+// immediately visible again.
+func (s *SQSRequest) ChangeMessageVisibility(handle string, timeout int) (*BasicResponse, error) {
+	return s.changeMessageVisibility(context.Background(), handle, timeout)
+}
+
+func (s *SQSRequest) changeMessageVisibility(ctx context.Context, handle string, timeout int) (*BasicResponse, error) {
+	params := map[string]string{
+		"Action":            "ChangeMessageVisibility",
+		"ReceiptHandle":     stripReceiptHandle(handle),
+		"VisibilityTimeout": strconv.Itoa(timeout),
+	}
+
+	reader, err := s.makeSQSQueueRequestContext(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	bmr := new(BasicResponse)
+	if err = xml.NewDecoder(reader).Decode(bmr); err != nil {
+		return nil, err
+	}
+
+	return bmr, nil
+}
+
+type changeVisibilityBatchResponse struct {
+	Successful []string           `xml:"ChangeMessageVisibilityBatchResult>ChangeMessageVisibilityBatchResultEntry>Id"`
+	Failed     []BatchResultError `xml:"ChangeMessageVisibilityBatchResult>BatchResultErrorEntry"`
+	BasicResponse
+}
+
+func (s *SQSRequest) changeVisibilityBatch(ctx context.Context, handles []string, timeout int) (*changeVisibilityBatchResponse, error) {
+	params := map[string]string{
+		"Action": "ChangeMessageVisibilityBatch",
+	}
+
+	for i, handle := range handles {
+		prefix := fmt.Sprintf("ChangeMessageVisibilityBatchRequestEntry.%d.", i+1)
+		params[prefix+"Id"] = strconv.Itoa(i)
+		params[prefix+"ReceiptHandle"] = stripReceiptHandle(handle)
+		params[prefix+"VisibilityTimeout"] = strconv.Itoa(timeout)
+	}
+
+	s.observeBatch("ChangeMessageVisibilityBatch", len(handles))
+
+	reader, err := s.makeSQSQueueRequestContext(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	cvr := new(changeVisibilityBatchResponse)
+	if err = xml.NewDecoder(reader).Decode(cvr); err != nil {
+		return nil, err
+	}
+
+	return cvr, nil
+}
+
+// ReleaseAll makes the given in-flight messages immediately visible to
+// other consumers again, batching the calls to ChangeMessageVisibility.
+// Entries rejected by SQS are reported through a BatchError whose ids are
+// the indexes of the corresponding messages in msgs.
+func (s *SQSRequest) ReleaseAll(ctx context.Context, msgs []*RecvMessageResponse) error {
+	var failed BatchError
+
+	for start := 0; start < len(msgs); start += maxBatchEntries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		end := start + maxBatchEntries
+		if end > len(msgs) {
+			end = len(msgs)
+		}
+
+		handles := make([]string, 0, end-start)
+		for _, m := range msgs[start:end] {
+			handles = append(handles, m.ReceiptHandle)
+		}
+
+		cvr, err := s.changeVisibilityBatch(ctx, handles, 0)
+		if err != nil {
+			return err
+		}
+
+		for _, f := range cvr.Failed {
+			idx, _ := strconv.Atoi(f.Id)
+			f.Id = strconv.Itoa(start + idx)
+			failed = append(failed, f)
+		}
+	}
+
+	if len(failed) > 0 {
+		return failed
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package sqs
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+)
+
+// WarmUp prepares the client for latency-sensitive use: it checks that
+// credentials are configured, then resolves the queue URL, which verifies
+// that the queue exists and leaves a connection to SQS open for reuse by
+// the requests that follow. The URL is kept in ExplicitQueueURL, so that
+// requests no longer need the account id, and returned. WarmUp changes
+// the client and must not run concurrently with its other requests;
+// see DialDSN.
+func (s *SQSRequest) WarmUp(ctx context.Context) (string, error) {
+	if s.AWSAccessKey == "" || s.AWSSecret == "" {
+		return "", errors.New("No AWS credentials configured.")
+	}
+
+	params := map[string]string{
+		"Action":    "GetQueueUrl",
+		"QueueName": s.QueueName,
+	}
+	if s.UUID != "" {
+		params["QueueOwnerAWSAccountId"] = s.UUID
+	}
+
+	reader, err := s.makeSQSRequestContext(ctx, params, false)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	qur := new(QueueURLResponse)
+	if err = xml.NewDecoder(reader).Decode(qur); err != nil {
+		return "", err
+	}
+
+	// The connection only goes back to the pool once the body is read.
+	if _, err = io.Copy(io.Discard, reader); err != nil {
+		return "", err
+	}
+
+	q, err := s.WithQueueURL(qur.QueueURL)
+	if err != nil {
+		return "", err
+	}
+	s.ExplicitQueueURL = q.ExplicitQueueURL
+	if s.UUID == "" {
+		s.UUID = q.UUID
+	}
+
+	return qur.QueueURL, nil
+}
+
+// DialDSN returns a client for the queue described by dsn, as ParseDSN
+// does, once it has been warmed up with WarmUp, so that its first request
+// does not pay for a cold start.
+func DialDSN(ctx context.Context, dsn string) (*SQSRequest, error) {
+	s, err := ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = s.WarmUp(ctx); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,221 @@
+package sqs
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// WatermarkStore persists the watermark of each queue, keyed by queue URL.
+// LoadWatermark returns the zero time for a queue without one.
+type WatermarkStore interface {
+	SaveWatermark(queue string, at time.Time) error
+	LoadWatermark(queue string) (time.Time, error)
+}
+
+// WatermarkReporter tracks, per queue, the SentTimestamp of the newest
+// message that was processed successfully, and periodically saves it to
+// Store. SQS delivers out of order, so a watermark tells when the newest
+// processed message was sent, not that every older message was processed.
+//
+// A watermark never moves backwards: the first time a queue is flushed,
+// its stored watermark is loaded, and kept if it is later than the one
+// observed since the reporter started.
+type WatermarkReporter struct {
+	Store    WatermarkStore
+	Interval time.Duration // defaults to 10 seconds
+
+	mu     sync.Mutex
+	marks  map[string]time.Time
+	saved  map[string]time.Time
+	loaded map[string]bool
+
+	flushMu sync.Mutex // serializes Flush, which saves without holding mu
+}
+
+func NewWatermarkReporter(store WatermarkStore) *WatermarkReporter {
+	return &WatermarkReporter{Store: store}
+}
+
+// Observe records a message of queue sent at sent as processed.
+func (wr *WatermarkReporter) Observe(queue string, sent time.Time) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	if wr.marks == nil {
+		wr.marks = make(map[string]time.Time)
+		wr.saved = make(map[string]time.Time)
+		wr.loaded = make(map[string]bool)
+	}
+	if sent.After(wr.marks[queue]) {
+		wr.marks[queue] = sent
+	}
+}
+
+// Watermark returns the current, possibly unsaved, watermark of queue.
+func (wr *WatermarkReporter) Watermark(queue string) time.Time {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	return wr.marks[queue]
+}
+
+// Flush saves the watermarks that moved since the last save. Observe is
+// not held up while they are saved.
+func (wr *WatermarkReporter) Flush() error {
+	wr.flushMu.Lock()
+	defer wr.flushMu.Unlock()
+
+	wr.mu.Lock()
+	moved := make(map[string]time.Time)
+	for queue, at := range wr.marks {
+		if at.After(wr.saved[queue]) {
+			moved[queue] = at
+		}
+	}
+	wr.mu.Unlock()
+
+	for queue, at := range moved {
+		stored, err := wr.load(queue)
+		if err != nil {
+			return err
+		}
+		if !at.After(stored) {
+			continue
+		}
+
+		if err = wr.Store.SaveWatermark(queue, at); err != nil {
+			return err
+		}
+
+		wr.mu.Lock()
+		wr.saved[queue] = at
+		wr.mu.Unlock()
+	}
+
+	return nil
+}
+
+// load returns the saved watermark of queue, asking Store for it the
+// first time. A later stored watermark replaces the observed one.
+func (wr *WatermarkReporter) load(queue string) (time.Time, error) {
+	wr.mu.Lock()
+	loaded := wr.loaded[queue]
+	wr.mu.Unlock()
+	if !loaded {
+		stored, err := wr.Store.LoadWatermark(queue)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		wr.mu.Lock()
+		wr.loaded[queue] = true
+		if stored.After(wr.saved[queue]) {
+			wr.saved[queue] = stored
+		}
+		if stored.After(wr.marks[queue]) {
+			wr.marks[queue] = stored
+		}
+		wr.mu.Unlock()
+	}
+
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+
+	return wr.saved[queue], nil
+}
+
+// Run flushes every Interval until ctx is cancelled, then flushes once
+// more and returns the result. Failed periodic flushes are retried on the
+// next tick.
+func (wr *WatermarkReporter) Run(ctx context.Context) error {
+	interval := wr.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return wr.Flush()
+		case <-t.C:
+			wr.Flush()
+		}
+	}
+}
+
+// FileWatermarkStore keeps watermarks in a JSON file mapping queue URLs to
+// timestamps. The file is replaced atomically on every save.
+type FileWatermarkStore struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+func (fws *FileWatermarkStore) load() (map[string]time.Time, error) {
+	marks := make(map[string]time.Time)
+
+	data, err := os.ReadFile(fws.Path)
+	if os.IsNotExist(err) {
+		return marks, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(data, &marks); err != nil {
+		return nil, err
+	}
+
+	return marks, nil
+}
+
+func (fws *FileWatermarkStore) SaveWatermark(queue string, at time.Time) error {
+	fws.mu.Lock()
+	defer fws.mu.Unlock()
+
+	marks, err := fws.load()
+	if err != nil {
+		return err
+	}
+	marks[queue] = at
+
+	data, err := json.MarshalIndent(marks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fws.Path), filepath.Base(fws.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fws.Path)
+}
+
+func (fws *FileWatermarkStore) LoadWatermark(queue string) (time.Time, error) {
+	fws.mu.Lock()
+	defer fws.mu.Unlock()
+
+	marks, err := fws.load()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return marks[queue], nil
+}
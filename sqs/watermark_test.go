@@ -0,0 +1,85 @@
+package sqs
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatermarkSurvivesRestart(t *testing.T) {
+	store := &FileWatermarkStore{Path: filepath.Join(t.TempDir(), "watermarks.json")}
+	t0 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		observed time.Time // after the restart
+		want     time.Time
+	}{
+		{"older", t0.Add(-time.Minute), t0},
+		{"same", t0, t0},
+		{"newer", t0.Add(time.Minute), t0.Add(time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.SaveWatermark("orders", t0); err != nil {
+				t.Fatal(err)
+			}
+
+			wr := NewWatermarkReporter(store)
+			wr.Observe("orders", tt.observed)
+			if err := wr.Flush(); err != nil {
+				t.Fatal(err)
+			}
+
+			at, err := store.LoadWatermark("orders")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !at.Equal(tt.want) {
+				t.Errorf("stored watermark = %v, want %v", at, tt.want)
+			}
+			if got := wr.Watermark("orders"); !got.Equal(tt.want) {
+				t.Errorf("Watermark = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// countingStore counts the loads and saves of the store it wraps.
+type countingStore struct {
+	WatermarkStore
+	loads, saves int
+}
+
+func (cs *countingStore) SaveWatermark(queue string, at time.Time) error {
+	cs.saves++
+	return cs.WatermarkStore.SaveWatermark(queue, at)
+}
+
+func (cs *countingStore) LoadWatermark(queue string) (time.Time, error) {
+	cs.loads++
+	return cs.WatermarkStore.LoadWatermark(queue)
+}
+
+func TestWatermarkLoadsOnce(t *testing.T) {
+	store := &countingStore{WatermarkStore: &FileWatermarkStore{Path: filepath.Join(t.TempDir(), "watermarks.json")}}
+	t0 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	wr := NewWatermarkReporter(store)
+	for i := 0; i < 3; i++ {
+		wr.Observe("orders", t0.Add(time.Duration(i)*time.Second))
+		wr.Observe("orders", t0)
+		if err := wr.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Nothing moved, so nothing is saved.
+	if err := wr.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if store.loads != 1 || store.saves != 3 {
+		t.Errorf("%d loads and %d saves, want 1 and 3", store.loads, store.saves)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/neurodrone/aws-sqs/sqs"
+)
+
+func statsCommand(args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	cf := addClientFlags(fs)
+	warnDepth := fs.Int("warn-depth", 0, "Warn when more messages than this are visible")
+	warnAge := fs.Duration("warn-age", 0, "Warn when the oldest message is older than this, per the ApproximateAgeOfOldestMessage CloudWatch metric (needs cloudwatch:GetMetricStatistics; lags by minutes)")
+	watch := fs.Duration("watch", 0, "Print stats at this interval until interrupted")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s stats [flags] <queue>\n\n", os.Args[0])
+		fmt.Fprintln(os.Stderr, "Exits with status 1 if a threshold is breached, unless watching.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+
+	if len(args) != 1 {
+		return usageError(fs, "Need exactly one queue.")
+	}
+
+	s, err := cf.queue(args[0])
+	if err != nil {
+		return err
+	}
+	s.ReadOnly = true
+
+	for {
+		breached, err := printStats(s, *warnDepth, *warnAge)
+
+		if *watch <= 0 {
+			if err != nil {
+				return err
+			}
+			if breached {
+				return fmt.Errorf("Thresholds breached for %s.", s.QueueName)
+			}
+			return nil
+		}
+
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		time.Sleep(*watch)
+		fmt.Println()
+	}
+}
+
+// printStats prints a stats snapshot and reports whether it breaches the
+// thresholds. Zero thresholds are not checked.
+func printStats(s *sqs.Queue, warnDepth int, warnAge time.Duration) (bool, error) {
+	qs, err := s.Stats()
+	if err != nil {
+		return false, err
+	}
+
+	fmt.Printf("%s  %s\n", qs.Time.Format(time.RFC3339), s.QueueName)
+	fmt.Printf("  Visible:    %d\n", qs.Visible)
+	fmt.Printf("  In flight:  %d\n", qs.InFlight)
+	fmt.Printf("  Delayed:    %d\n", qs.Delayed)
+
+	breached := false
+	if warnDepth > 0 && qs.Visible > warnDepth {
+		fmt.Fprintf(os.Stderr, "WARNING: %d visible messages, more than %d.\n", qs.Visible, warnDepth)
+		breached = true
+	}
+
+	if warnAge > 0 {
+		// Peeking would count as a receive and push messages towards the
+		// dead-letter queue, so the age comes from CloudWatch instead.
+		age, err := s.OldestMessageAge(context.Background())
+		if err != nil {
+			return false, err
+		}
+
+		fmt.Printf("  Oldest:     %s\n", age.Round(time.Second))
+		if age > warnAge {
+			fmt.Fprintf(os.Stderr, "WARNING: oldest message is %s old, older than %s.\n", age.Round(time.Second), warnAge)
+			breached = true
+		}
+	}
+
+	return breached, nil
+}